@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+)
+
+// envHelp summarises the key environment variables in the root command's help
+const envHelp = `Key environment variables:
+  PD_API_TOKEN (required)        PagerDuty REST API token (or PD_OAUTH_CLIENT_ID/SECRET)
+  PD_SCHEDULE_ID                 PagerDuty schedule to monitor
+  PD_ESCALATION_POLICY_ID        escalation policy to monitor instead of a schedule
+  PD_TEAM_ID                     team whose schedules are all monitored
+  PD_USER_ID or PD_USERS         PagerDuty user (or users) expected to be on call (default: token owner)
+  NOTIFICATION_BACKEND           webhook | ntfy | pushover
+  CHECK_INTERVAL                 poll interval in seconds (default 300)
+  ADVANCE_NOTIFICATION_TIME      duration(s) before shift for advance alerts, e.g. 24h,2h
+  SHIFT_END_NOTIFICATIONS_ENABLED enable/disable shift end alerts (default true)
+  MESSAGE_LOCALE                 language for notification messages (default en)
+  HTTP_LISTEN_ADDR               address for the HTTP control API (disabled if unset)
+  STATE_FILE_PATH                path for persisted state (default /data/state.json)
+
+See README.md for full configuration details.`
+
+// onceHelp describes the --once flag shared by the root and run commands
+const onceHelp = "Check each user once, send any due notifications, save state, and exit (for cron jobs and timers)"
+
+// newRootCommand builds the notifier command tree. Without a subcommand the notifier runs,
+// as run does, so existing deployments keep working.
+func newRootCommand() *cobra.Command {
+	var configFile, profile string
+	var once bool
+	settingFlags := map[string]*string{}
+	root := &cobra.Command{
+		Use:     "notifier",
+		Short:   "PagerDuty On-Call Notifier",
+		Long:    "PagerDuty On-Call Notifier\n\nMonitors PagerDuty on-call rotations and notifies you when your shifts start and end.\n\n" + envHelp,
+		Args:    cobra.NoArgs,
+		Version: version.Get().String(),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Flags and arguments are valid by now, so later errors are not usage errors
+			cmd.SilenceUsage = true
+			values := map[string]string{}
+			for setting, value := range settingFlags {
+				if cmd.Flags().Changed(config.FlagName(setting)) {
+					values[setting] = *value
+				}
+			}
+			config.SetFlagValues(values)
+			config.SelectProfile(profile)
+			if configFile != "" {
+				return os.Setenv("CONFIG_FILE", configFile)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runNotifier(once)
+		},
+		SilenceErrors:     true,
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+	}
+	root.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file to read settings from (overrides CONFIG_FILE)")
+	root.PersistentFlags().StringVar(&profile, "profile", "", "CONFIG_FILE profile to use (default: every profile for run and validate)")
+	// Every setting can be given as a flag, taking precedence over CONFIG_FILE and the environment
+	for _, setting := range config.Settings {
+		settingFlags[setting] = root.PersistentFlags().String(config.FlagName(setting), "", "Overrides "+setting)
+	}
+	root.Flags().BoolVar(&once, "once", false, onceHelp)
+	root.SetVersionTemplate("notifier {{.Version}}\n")
+
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Monitor the configured users and send notifications",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runNotifier(once)
+		},
+	}
+	run.Flags().BoolVar(&once, "once", false, onceHelp)
+
+	root.AddCommand(
+		run,
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print the version, commit, build date, and Go version",
+			Args:  cobra.NoArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				fmt.Println("notifier " + version.Get().String())
+			},
+		},
+		newValidateCommand(),
+		newConfigCommand(),
+		newTestNotifyCommand(),
+		newListShiftsCommand(),
+		newHistoryCommand(),
+		newStateCommand(),
+		newMuteCommand(),
+		newUnmuteCommand(),
+		newPauseCommand(),
+		newResumeCommand(),
+	)
+	return root
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+)
+
+func newConfigCommand() *cobra.Command {
+	var format string
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective settings and where each came from, with secrets masked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(format)
+		},
+	}
+	show.Flags().StringVar(&format, "format", "table", "Output format (table or json)")
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	cmd.AddCommand(show)
+	return cmd
+}
+
+// runConfigShow prints every setting that is set, after flags, CONFIG_FILE, and the environment
+// are merged, so it is clear which value won. It does not check the values; validate does.
+func runConfigShow(format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format %q (valid formats: table, json)", format)
+	}
+
+	settings, err := config.Effective()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		if settings == nil {
+			settings = []config.EffectiveSetting{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	}
+
+	if len(settings) == 0 {
+		fmt.Println("No settings are set")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, setting := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Name, setting.Value, setting.Source)
+	}
+	return w.Flush()
+}
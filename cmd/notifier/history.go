@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+// listedNotification is a sent notification as printed by history
+type listedNotification struct {
+	UserID string `json:"user_id"`
+	state.NotificationRecord
+}
+
+func newHistoryCommand() *cobra.Command {
+	var limit int
+	var format string
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Print the notifications recently sent to the configured user(s)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory(limit, format)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, fmt.Sprintf("Maximum number of notifications to list (1-%d)", state.MaxNotificationHistory))
+	cmd.Flags().StringVar(&format, "format", "table", "Output format (table or json)")
+	return cmd
+}
+
+// runHistory prints the notifications recently sent to each monitored user, newest first,
+// and returns
+func runHistory(limit int, format string) error {
+	if limit < 1 || limit > state.MaxNotificationHistory {
+		return fmt.Errorf("--limit must be between 1 and %d", state.MaxNotificationHistory)
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format %q (valid formats: table, json)", format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.StateBackend == config.StateBackendMemory {
+		// The state only exists inside the running notifier
+		return fmt.Errorf("history is not supported with STATE_BACKEND=memory")
+	}
+
+	newStateManager, err := openStateBackend(cfg)
+	if err != nil {
+		return err
+	}
+
+	notifications := []listedNotification{}
+	for _, route := range cfg.Users {
+		currentState, err := newStateManager(route).Load()
+		if err != nil {
+			return fmt.Errorf("user %s: %w", route.UserID, err)
+		}
+		for _, record := range currentState.NotificationHistory {
+			notifications = append(notifications, listedNotification{UserID: route.UserID, NotificationRecord: record})
+		}
+	}
+	slices.SortStableFunc(notifications, func(a, b listedNotification) int {
+		return b.SentAt.Compare(a.SentAt)
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(notifications)
+	}
+
+	if len(notifications) == 0 {
+		fmt.Println("No notifications have been sent yet")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SENT\tUSER\tEVENT\tSHIFT START\tBACKEND")
+	for _, n := range notifications {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			n.SentAt.Local().Format("Mon 2006-01-02 15:04 MST"),
+			n.UserID,
+			n.Event,
+			n.ShiftStartTime.Local().Format("Mon 2006-01-02 15:04 MST"),
+			n.Backend)
+	}
+	return w.Flush()
+}
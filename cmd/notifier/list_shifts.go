@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// listedShift is a shift as printed by list-shifts
+type listedShift struct {
+	UserID     string    `json:"user_id"`
+	ScheduleID string    `json:"schedule_id,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+func newListShiftsCommand() *cobra.Command {
+	var days int
+	var format string
+	cmd := &cobra.Command{
+		Use:   "list-shifts",
+		Short: "Print the upcoming shifts of the configured user(s)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runListShifts(days, format)
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", 30, fmt.Sprintf("Number of days ahead to list (1-%d)", pagerduty.MaxShiftListingDays))
+	cmd.Flags().StringVar(&format, "format", "table", "Output format (table or json)")
+	return cmd
+}
+
+// runListShifts prints the upcoming shifts of each monitored user and returns
+func runListShifts(days int, format string) error {
+	if days < 1 || days > pagerduty.MaxShiftListingDays {
+		return fmt.Errorf("--days must be between 1 and %d", pagerduty.MaxShiftListingDays)
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format %q (valid formats: table, json)", format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ctx := context.Background()
+	since := time.Now().UTC()
+	until := since.AddDate(0, 0, days)
+	shifts := []listedShift{}
+	for _, route := range cfg.Users {
+		pdClient, err := newPagerDutyClient(cfg, route)
+		if err != nil {
+			return err
+		}
+		userID := route.UserID
+		if userID == "" {
+			user, err := pdClient.ResolveCurrentUser(ctx)
+			if err != nil {
+				return err
+			}
+			userID = user.ID
+		}
+		upcoming, err := pdClient.ListShifts(ctx, since, until)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", userID, err)
+		}
+		for _, shift := range upcoming {
+			shifts = append(shifts, listedShift{
+				UserID:     userID,
+				ScheduleID: shift.ScheduleID,
+				Start:      shift.StartTime,
+				End:        shift.EndTime,
+			})
+		}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(shifts)
+	}
+
+	if len(shifts) == 0 {
+		fmt.Printf("No shifts in the next %d day(s)\n", days)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tSCHEDULE\tSTART\tEND\tDURATION")
+	for _, shift := range shifts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			shift.UserID,
+			shift.ScheduleID,
+			shift.Start.Local().Format("Mon 2006-01-02 15:04 MST"),
+			shift.End.Local().Format("Mon 2006-01-02 15:04 MST"),
+			shift.End.Sub(shift.Start).Round(time.Minute))
+	}
+	return w.Flush()
+}
@@ -2,91 +2,167 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"os"
 	"os/signal"
+	"path"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/audit"
 	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/control"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/errorreport"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/grafana"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/heartbeat"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/logging"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/metrics"
 	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
-	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/pagerduty"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/server"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/slack"
 	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
 )
 
+// errorReporter sends panics and persistent errors to SENTRY_DSN once the configuration is
+// loaded; it is nil, and reports nothing, until then or if SENTRY_DSN is not set
+var errorReporter *errorreport.Reporter
+
+// auditLog records on-call transitions and notification decisions to AUDIT_LOG_PATH; it is nil
+// if AUDIT_LOG_PATH is not set
+var auditLog *audit.File
+
 func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "PagerDuty On-Call Notifier\n\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  notifier [flags]\n\nFlags:\n")
-		flag.PrintDefaults()
-		fmt.Fprintln(flag.CommandLine.Output(), "\nKey environment variables:")
-		fmt.Fprintln(flag.CommandLine.Output(), "  PD_API_TOKEN (required)        PagerDuty REST API token")
-		fmt.Fprintln(flag.CommandLine.Output(), "  PD_SCHEDULE_ID (required)      PagerDuty schedule to monitor")
-		fmt.Fprintln(flag.CommandLine.Output(), "  PD_USER_ID (required)          PagerDuty user expected to be on call")
-		fmt.Fprintln(flag.CommandLine.Output(), "  NOTIFICATION_BACKEND           webhook | ntfy | pushover")
-		fmt.Fprintln(flag.CommandLine.Output(), "  CHECK_INTERVAL                 poll interval in seconds (default 300)")
-		fmt.Fprintln(flag.CommandLine.Output(), "  ADVANCE_NOTIFICATION_TIME      duration before shift for advance alerts")
-		fmt.Fprintln(flag.CommandLine.Output(), "  SHIFT_END_NOTIFICATIONS_ENABLED enable/disable shift end alerts (default true)")
-		fmt.Fprintln(flag.CommandLine.Output(), "  STATE_FILE_PATH                path for persisted state (default /data/state.json)")
-		fmt.Fprintln(flag.CommandLine.Output(), "\nSee README.md for full configuration details.")
-	}
-
-	help := flag.Bool("help", false, "Show help and exit")
-	shortHelp := flag.Bool("h", false, "Show help and exit")
-	flag.Parse()
-
-	if *help || *shortHelp {
-		flag.Usage()
-		return
+	cmd, err := newRootCommand().ExecuteC()
+	if err != nil {
+		fatal("Command failed", "command", cmd.CommandPath(), "error", err)
+	}
+}
+
+// fatal logs and reports msg with its attributes at error level and exits, as log.Fatal does
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	if err := errorReporter.Report(context.Background(), msg, args...); err != nil {
+		slog.Warn("Failed to report error", "error", err)
 	}
+	os.Exit(1)
+}
 
+// runNotifier monitors the configured users of every profile until it is interrupted. With once,
+// it checks each user a single time and exits, for cron jobs and timers.
+func runNotifier(once bool) {
 	// Load configuration
-	cfg, err := config.Load()
+	cfgs, err := config.LoadAll()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", "error", err)
 	}
+	// Logging and auditing are process-wide, so with several profiles the first one's settings apply
+	if path := cfgs[0].AuditLogPath; path != "" {
+		auditLog, err = audit.Open(path, cfgs[0].AuditLogMaxSize, cfgs[0].AuditLogMaxBackups)
+		if err != nil {
+			fatal("Failed to open audit log", "error", err)
+		}
+		defer auditLog.Close()
+	}
+	setupLogging(cfgs[0])
+	if dsn := cfgs[0].SentryDSN; dsn != "" {
+		// The DSN was checked when the configuration was loaded
+		errorReporter, _ = errorreport.New(dsn, cfgs[0].SentryEnvironment)
+	}
+	defer errorReporter.Recover()
 
-	log.Println("PagerDuty On-Call Notifier starting...")
-	log.Printf("Schedule ID: %s", cfg.PagerDutyScheduleID)
-	log.Printf("User ID: %s", cfg.PagerDutyUserID)
-	log.Printf("Check interval: %v", cfg.CheckInterval)
-	log.Printf("Notification backend: %s", cfg.NotificationBackend)
-	log.Printf("Shift end notifications enabled: %v", cfg.ShiftEndNotificationsEnabled)
-
-	// Initialize components
-	pdClient := pagerduty.NewClient(
-		cfg.PagerDutyAPIToken,
-		cfg.PagerDutyScheduleID,
-		cfg.PagerDutyUserID,
-	)
+	slog.Info("PagerDuty On-Call Notifier starting...", "version", version.Version)
+	if errorReporter != nil {
+		slog.Info("Reporting panics and persistent errors to Sentry")
+	}
+	var stateLocks []*state.FileLock
+	defer func() {
+		for _, lock := range stateLocks {
+			lock.Close()
+		}
+	}()
+	var monitors []*monitor
+	for _, cfg := range cfgs {
+		if cfg.Profile != "" {
+			slog.Info("Loading profile", "profile", cfg.Profile)
+		}
+		logConfig(cfg)
 
-	stateManager := state.NewManager(cfg.StateFilePath)
+		// Refuse to share state files with another running notifier
+		locks, err := lockStateFiles(cfg)
+		stateLocks = append(stateLocks, locks...)
+		if err != nil {
+			fatal("Failed to lock state", "error", err)
+		}
 
-	// Create notifier based on backend selection
-	notifierInstance, err := createNotifier(cfg)
-	if err != nil {
-		log.Fatalf("Failed to create notifier: %v", err)
+		// Initialize components for each monitored user
+		profileMonitors, err := newMonitors(cfg)
+		if err != nil {
+			if cfg.Profile != "" {
+				fatal("Failed to create notifier", "profile", cfg.Profile, "error", err)
+			}
+			fatal("Failed to create notifier", "error", err)
+		}
+		monitors = append(monitors, profileMonitors...)
 	}
 
-	// Send birth message for ntfy notifier
-	if ntfyNotifier, ok := notifierInstance.(*notifier.NtfyNotifier); ok {
-		log.Println("Sending birth message...")
-		if err := ntfyNotifier.SendBirthMessage(); err != nil {
-			log.Printf("Failed to send birth message: %v", err)
-			// Don't fail startup if birth message fails
-		} else {
-			log.Println("Birth message sent successfully")
+	for _, m := range monitors {
+		cfg, pdClient, n := m.current()
+		logger := monitorLogger(cfg, m.route)
+
+		// Send birth message for ntfy notifier; a single check is not worth announcing
+		if ntfyNotifier, ok := n.(*notifier.NtfyNotifier); ok && !once {
+			logger.Info("Sending birth message...")
+			if err := ntfyNotifier.SendBirthMessage(context.Background()); err != nil {
+				logger.Warn("Failed to send birth message", "error", err)
+				// Don't fail startup if birth message fails
+			} else {
+				logger.Info("Birth message sent successfully")
+			}
+		}
+
+		// Load initial state
+		currentState, err := m.stateManager.Load()
+		if err != nil {
+			fatal("Failed to load state", "user_id", m.route.UserID, "error", err)
+		}
+		logger.Info("Initial state loaded", "was_on_call", currentState.WasOnCall)
+
+		if err := reconcileMissedShifts(context.Background(), logger, pdClient, m.stateManager, n, cfg); err != nil {
+			logger.Warn("Failed to reconcile shifts missed while the notifier was not running", "error", err)
 		}
 	}
 
-	// Load initial state
-	currentState, err := stateManager.Load()
-	if err != nil {
-		log.Fatalf("Failed to load state: %v", err)
+	if once {
+		err := runOnce(monitors)
+		// There is nothing to scrape after exiting, so push the results instead if configured
+		if gatewayURL := cfgs[0].PushgatewayURL; gatewayURL != "" {
+			if err := metrics.Push(context.Background(), gatewayURL, cfgs[0].PushgatewayJob, metricsTargets(monitors)); err != nil {
+				slog.Warn("Failed to push metrics", "error", err)
+			} else {
+				slog.Info("Metrics pushed to Pushgateway", "job", cfgs[0].PushgatewayJob)
+			}
+		}
+		if err != nil {
+			fatal("Check failed", "error", err)
+		}
+		slog.Info("Check complete")
+		return
 	}
-	log.Printf("Initial state: was_on_call=%v", currentState.WasOnCall)
+
+	// The HTTP API and remote commands are only available with a single monitored user
+	cfg := cfgs[0]
+	stateManager := monitors[0].stateManager
+	notifierInstance := monitors[0].notifier
 
 	// Set up graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -95,163 +171,1486 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Start polling loop in a goroutine
-	done := make(chan error, 1)
+	// Reload the configuration on SIGHUP, or when a file it is read from changes
+	reload := make(chan struct{}, 1)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 	go func() {
-		done <- runPollingLoop(ctx, pdClient, stateManager, notifierInstance, cfg.CheckInterval, cfg)
+		for range hupChan {
+			slog.Info("Received SIGHUP")
+			select {
+			case reload <- struct{}{}:
+			default: // A reload is already pending
+			}
+		}
 	}()
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	if files := configFiles(cfgs); len(files) > 0 {
+		slog.Info("Watching configuration files for changes", "files", files)
+		go watchFiles(watchCtx, files, reload)
+	}
 
-	// Wait for signal or error
-	select {
-	case sig := <-sigChan:
-		log.Printf("Received signal: %v, shutting down...", sig)
-		// Send will message for ntfy notifier before shutdown
-		if ntfyNotifier, ok := notifierInstance.(*notifier.NtfyNotifier); ok {
-			log.Println("Sending will message...")
-			if err := ntfyNotifier.SendWillMessage(); err != nil {
-				log.Printf("Failed to send will message: %v", err)
-			} else {
-				log.Println("Will message sent successfully")
+	// Start HTTP API if configured
+	// PagerDuty webhook events, resync requests, and gRPC ForceCheck calls trigger an immediate
+	// check of the (single) monitored user; the reason is logged with the check
+	checkNow := make(chan string, 1)
+	if cfg.HTTPListenAddr != "" {
+		apiServer := server.New(cfg.HTTPListenAddr, cfg.HTTPAPIToken, stateManager, monitors[0].health)
+		apiServer.AcceptActionToken(cfg.HTTPActionToken)
+		if cfg.PagerDutyWebhookSecret != "" {
+			slog.Info("Accepting PagerDuty webhooks on /webhooks/pagerduty", "addr", cfg.HTTPListenAddr)
+			apiServer.HandlePagerDutyWebhooks(cfg.PagerDutyWebhookSecret, func(eventType string) {
+				if !strings.HasPrefix(eventType, "incident.") {
+					return
+				}
+				select {
+				case checkNow <- "PagerDuty webhook event":
+				default: // A check is already pending
+				}
+			})
+		}
+		// Shifts are listed with the current PagerDuty client, which is replaced on reload
+		m := monitors[0]
+		if cfg.CalendarToken != "" {
+			apiServer.HandleCalendar(cfg.CalendarToken, m.route.UserID, cfg.CalendarWeeks, func(ctx context.Context, since, until time.Time) ([]pagerduty.UpcomingShift, error) {
+				_, pdClient, _ := m.current()
+				return pdClient.ListShifts(ctx, since, until)
+			})
+		}
+		apiServer.HandleTestNotifications(func(ctx context.Context, event notifier.NotificationEvent) error {
+			cfg, _, n := m.current()
+			return sendTestNotification(ctx, cfg, n, event)
+		})
+		resync := func(reason string) func() {
+			return func() {
+				_, pdClient, _ := m.current()
+				pdClient.ResetCache()
+				select {
+				case checkNow <- reason:
+				default: // A check is already pending and will see the reset cache
+				}
+			}
+		}
+		apiServer.HandleResync(resync("resync request"))
+		apiServer.HandleGRPC(resync("gRPC check request"), func(ctx context.Context) (notifier.NotificationEvent, error) {
+			_, _, n := m.current()
+			return control.Resend(ctx, stateManager, n)
+		})
+		slog.Info("HTTP API listening", "addr", cfg.HTTPListenAddr)
+		go func() {
+			if err := apiServer.Run(ctx); err != nil {
+				slog.Error("HTTP API error", "error", err)
+			}
+		}()
+	}
+
+	// Serve pprof profiles if configured; like logging, this is process-wide
+	if addr := cfgs[0].DebugListenAddr; addr != "" {
+		debugServer := server.NewDebug(addr)
+		slog.Info("Debug endpoints listening on /debug/pprof/", "addr", addr)
+		go func() {
+			if err := debugServer.Run(ctx); err != nil {
+				slog.Error("Debug server error", "error", err)
+			}
+		}()
+	}
+
+	// Export every monitored user's on-call status to Prometheus if configured
+	if addr := cfgs[0].MetricsListenAddr; addr != "" {
+		metricsServer := server.NewMetrics(addr, metricsTargets(monitors))
+		slog.Info("Metrics listening on /metrics", "addr", addr)
+		go func() {
+			if err := metricsServer.Run(ctx); err != nil {
+				slog.Error("Metrics server error", "error", err)
+			}
+		}()
+	}
+
+	// Accept remote commands from the ntfy control topic if configured
+	if startupNtfy, ok := notifierInstance.(*notifier.NtfyNotifier); ok && cfg.NtfyControlTopic != "" {
+		// The notifier is resolved for every command and reconnect, so reloads and rotated
+		// credentials apply to replies, resends, and the subscription as well
+		m := monitors[0]
+		currentNotifier := func() notifier.Notifier {
+			_, _, n := m.current()
+			return n
+		}
+		currentNtfy := func() *notifier.NtfyNotifier {
+			if n, ok := currentNotifier().(*notifier.NtfyNotifier); ok {
+				return n
+			}
+			return startupNtfy
+		}
+		controller := control.New(stateManager, currentNotifier, cfg.NtfyControlSecret)
+		slog.Info("Listening for remote commands on ntfy", "topic", cfg.NtfyControlTopic)
+		go func() {
+			defer errorReporter.Recover()
+			notifier.SubscribeNtfy(ctx, currentNtfy, cfg.NtfyControlTopic, func(message string) {
+				reply, err := controller.Execute(ctx, message)
+				if errors.Is(err, control.ErrUnauthenticated) {
+					// Not answered, so the topic cannot be used to probe or spam the notifier
+					slog.Warn("Ignoring remote command without the control secret")
+					return
+				}
+				if err != nil {
+					reply = fmt.Sprintf("Error: %v", err)
+				}
+				// The command itself is not logged, as it starts with the secret
+				slog.Info("Remote command received", "reply", reply)
+				if err := currentNtfy().SendReply(ctx, "PagerDuty Notifier", reply); err != nil {
+					slog.Warn("Failed to send command reply", "error", err)
+				}
+			})
+		}()
+	}
+
+	// Start a polling loop per monitored user
+	done := make(chan error, len(monitors))
+	for _, m := range monitors {
+		go func() {
+			defer errorReporter.Recover("user_id", m.route.UserID)
+			done <- runPollingLoop(ctx, m, monitors, checkNow)
+		}()
+	}
+
+	// Wait for signal or error, reloading the configuration when asked
+	for running := true; running; {
+		select {
+		case <-reload:
+			newCfgs, err := reloadMonitors(cfgs, monitors)
+			if err != nil {
+				slog.Error("Configuration reload failed, keeping the current configuration", "error", err)
+				continue
+			}
+			// The reloaded configuration may be read from different files
+			if !slices.Equal(configFiles(cfgs), configFiles(newCfgs)) {
+				stopWatching()
+				watchCtx, stopWatching = context.WithCancel(ctx)
+				go watchFiles(watchCtx, configFiles(newCfgs), reload)
+			}
+			cfgs = newCfgs
+			setupLogging(cfgs[0])
+			for _, cfg := range cfgs {
+				if cfg.Profile != "" {
+					slog.Info("Configuration reloaded", "profile", cfg.Profile, "check_interval", cfg.CheckInterval, "backend", cfg.NotificationBackend)
+				} else {
+					slog.Info("Configuration reloaded", "check_interval", cfg.CheckInterval, "backend", cfg.NotificationBackend)
+				}
+			}
+		case sig := <-sigChan:
+			slog.Info("Received signal, shutting down...", "signal", sig.String())
+			// Send will message for ntfy notifier before shutdown
+			sendWillMessages(ctx, monitors)
+			cancel()
+			for range monitors {
+				<-done
+			}
+			running = false
+		case err := <-done:
+			if err != nil {
+				fatal("Polling loop error", "error", err)
+			}
+			// Send will message for ntfy notifier on graceful shutdown
+			sendWillMessages(ctx, monitors)
+			running = false
+		}
+	}
+	stopWatching()
+
+	slog.Info("Shutdown complete")
+}
+
+// setupLogging applies the logging settings of cfg, also recording decisions to the audit log
+// if one is open
+func setupLogging(cfg *config.Config) {
+	logging.Setup(cfg.LogLevel, cfg.LogFormat)
+	if auditLog != nil {
+		slog.SetDefault(slog.New(audit.NewHandler(slog.Default().Handler(), auditLog)))
+	}
+}
+
+// logConfig logs the main settings of a configuration at startup
+func logConfig(cfg *config.Config) {
+	switch {
+	case cfg.PagerDutyEscalationPolicyID != "":
+		slog.Info("Monitoring escalation policy", "escalation_policy_id", cfg.PagerDutyEscalationPolicyID)
+	case cfg.PagerDutyTeamID != "":
+		slog.Info("Monitoring team", "team_id", cfg.PagerDutyTeamID, "refresh_interval", pagerduty.TeamScheduleRefreshInterval)
+	case cfg.PagerDutyScheduleID != "":
+		slog.Info("Monitoring schedule", "schedule_id", cfg.PagerDutyScheduleID)
+	}
+	for _, route := range cfg.Users {
+		if route.Account != "" {
+			slog.Info("Monitoring account", "account", route.Account, "schedule_id", route.ScheduleID)
+		}
+		if route.Target != "" {
+			slog.Info("Monitoring user", "user_id", route.UserID, "target", route.Target)
+		} else if route.UserID != "" {
+			slog.Info("Monitoring user", "user_id", route.UserID)
+		} else {
+			slog.Info("Monitoring user resolved from API token")
+		}
+	}
+	if cfg.PagerDutyAPIURL != "" {
+		slog.Info("Using PagerDuty API URL", "url", cfg.PagerDutyAPIURL)
+	}
+	if cfg.CheckIntervalJitter > 0 {
+		slog.Info("Check interval", "interval", cfg.CheckInterval, "jitter", cfg.CheckIntervalJitter)
+	} else {
+		slog.Info("Check interval", "interval", cfg.CheckInterval)
+	}
+	if cfg.WebhookPollInterval > 0 {
+		slog.Info("Polling less often while PagerDuty webhooks trigger checks", "interval", cfg.WebhookPollInterval)
+	}
+	slog.Info("Notification backend", "backend", cfg.NotificationBackend)
+	slog.Info("Shift end notifications", "enabled", cfg.ShiftEndNotificationsEnabled)
+	if cfg.DailySummaryTime != nil {
+		slog.Info("Daily summary", "at", cfg.DailySummaryTime.String(), "timezone", cfg.ClockLocation().String())
+	}
+	if cfg.AdvanceNotificationWindow != nil {
+		slog.Info("Advance notification window", "window", cfg.AdvanceNotificationWindow.String(), "timezone", cfg.ClockLocation().String())
+	}
+	slog.Info("Message locale", "locale", cfg.MessageLocale)
+}
+
+// configFiles returns the files the configurations of all profiles are read from
+func configFiles(cfgs []*config.Config) []string {
+	var files []string
+	for _, cfg := range cfgs {
+		for _, path := range cfg.Files() {
+			if !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+	return files
+}
+
+// monitor holds the components that watch a single user's on-call status. The configuration,
+// PagerDuty client, and notifier are replaced when the configuration is reloaded, and are read
+// by the polling loop under mu.
+type monitor struct {
+	route        config.UserRoute
+	stateManager *state.Manager
+	health       *health.Tracker
+
+	mu       sync.Mutex
+	cfg      *config.Config
+	pdClient *pagerduty.Client
+	notifier notifier.Notifier
+}
+
+// monitorLogger returns a logger whose records carry monitorAttrs
+func monitorLogger(cfg *config.Config, route config.UserRoute) *slog.Logger {
+	return slog.With(monitorAttrs(cfg, route)...)
+}
+
+// monitorAttrs returns the log attributes naming a monitored user, their schedule and account
+// or profile if any, and the notification backend
+func monitorAttrs(cfg *config.Config, route config.UserRoute) []any {
+	args := []any{"user_id", route.UserID}
+	if route.ScheduleID != "" {
+		args = append(args, "schedule_id", route.ScheduleID)
+	}
+	if route.Account != "" {
+		args = append(args, "account", route.Account)
+	}
+	if cfg.Profile != "" {
+		args = append(args, "profile", cfg.Profile)
+	}
+	return append(args, "backend", string(cfg.NotificationBackend))
+}
+
+// newMonitors creates a PagerDuty client, state manager, and notifier for each monitored user
+func newMonitors(cfg *config.Config) ([]*monitor, error) {
+	newStateManager, err := openStateBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var monitors []*monitor
+	for _, route := range cfg.Users {
+		route, pdClient, n, err := newMonitorComponents(cfg, route)
+		if err != nil {
+			return nil, err
+		}
+		monitors = append(monitors, &monitor{
+			route:        route,
+			stateManager: newStateManager(route),
+			health:       health.NewTracker(health.DefaultUnhealthyThreshold),
+			cfg:          cfg,
+			pdClient:     pdClient,
+			notifier:     n,
+		})
+	}
+	return monitors, nil
+}
+
+// newMonitorComponents creates the PagerDuty client and notifier for a monitored user,
+// resolving the user from the API token if the route does not name one
+func newMonitorComponents(cfg *config.Config, route config.UserRoute) (config.UserRoute, *pagerduty.Client, notifier.Notifier, error) {
+	pdClient, err := newPagerDutyClient(cfg, route)
+	if err != nil {
+		return route, nil, nil, err
+	}
+	if route.UserID == "" {
+		user, err := pdClient.ResolveCurrentUser(context.Background())
+		if err != nil {
+			if route.Account != "" {
+				return route, nil, nil, fmt.Errorf("%s: %w", route.Label(), err)
+			}
+			return route, nil, nil, err
+		}
+		slog.Info("Resolved user from API token", "user_id", user.ID, "user_name", user.Name)
+		route.UserID = user.ID
+	}
+	if err := validatePagerDuty(cfg, pdClient); err != nil {
+		return route, nil, nil, fmt.Errorf("%s: %w", route.Label(), err)
+	}
+	n, err := createNotifier(cfg, pdClient, route)
+	if err != nil {
+		return route, nil, nil, fmt.Errorf("%s: %w", route.Label(), err)
+	}
+	return route, pdClient, n, nil
+}
+
+// lockStateFiles locks each monitored user's state file when state is kept on disk. The
+// locks are held until the process exits; other subcommands such as mute do not take them,
+// but every update of the state is serialized by the store's own lock (see state.LockingStore).
+func lockStateFiles(cfg *config.Config) ([]*state.FileLock, error) {
+	if cfg.StateBackend != config.StateBackendFile {
+		return nil, nil
+	}
+	var locks []*state.FileLock
+	for _, route := range cfg.Users {
+		lock, err := state.LockFile(route.StateFilePath)
+		if err != nil {
+			for _, l := range locks {
+				l.Close()
 			}
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// openStateBackend connects to the configured state backend and returns a function that
+// creates the state manager for a monitored user. A database connection is kept open for
+// the lifetime of the process.
+func openStateBackend(cfg *config.Config) (func(route config.UserRoute) *state.Manager, error) {
+	var newStore func(route config.UserRoute) state.Store
+	switch cfg.StateBackend {
+	case config.StateBackendPostgres:
+		db, err := state.OpenPostgres(context.Background(), cfg.StatePostgresURL)
+		if err != nil {
+			return nil, err
 		}
-		cancel()
-		<-done
-	case err := <-done:
+		slog.Info("Persisting state in PostgreSQL")
+		newStore = func(route config.UserRoute) state.Store {
+			return state.NewPostgresStore(db, route.StateKey)
+		}
+	case config.StateBackendS3:
+		client, err := state.OpenS3(context.Background(), state.S3Options{
+			Bucket:    cfg.StateS3Bucket,
+			Region:    cfg.StateS3Region,
+			Endpoint:  cfg.StateS3Endpoint,
+			PathStyle: cfg.StateS3PathStyle,
+		})
 		if err != nil {
-			log.Fatalf("Polling loop error: %v", err)
+			return nil, err
+		}
+		slog.Info("Persisting state in S3", "bucket", cfg.StateS3Bucket)
+		newStore = func(route config.UserRoute) state.Store {
+			return state.NewS3Store(client, cfg.StateS3Bucket, s3StateKey(cfg, route))
+		}
+	case config.StateBackendMemory:
+		slog.Info("Keeping state in memory; it is lost on restart")
+		newStore = func(route config.UserRoute) state.Store {
+			return &state.MemoryStore{}
+		}
+	default:
+		newStore = func(route config.UserRoute) state.Store {
+			return &state.FileStore{Path: route.StateFilePath}
+		}
+	}
+
+	if cfg.StateEncryptionKey == nil {
+		return func(route config.UserRoute) *state.Manager {
+			return state.NewManagerWithStore(newStore(route))
+		}, nil
+	}
+	aead, err := state.NewStateCipher(cfg.StateEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Encrypting persisted state")
+	return func(route config.UserRoute) *state.Manager {
+		return state.NewManagerWithStore(state.NewEncryptedStore(newStore(route), aead))
+	}, nil
+}
+
+// s3StateKey returns the object a user's state is kept in, suffixed like the state files
+// when several users are monitored or profiles are used, e.g. state-PXXXXXX.json
+func s3StateKey(cfg *config.Config, route config.UserRoute) string {
+	if len(cfg.Users) <= 1 && cfg.Profile == "" {
+		return cfg.StateS3Key
+	}
+	ext := path.Ext(cfg.StateS3Key)
+	return strings.TrimSuffix(cfg.StateS3Key, ext) + "-" + route.StateKey + ext
+}
+
+// validatePagerDuty checks the configured PagerDuty IDs as PD_STARTUP_VALIDATION asks,
+// so a typo fails startup instead of polling a nonexistent schedule forever
+func validatePagerDuty(cfg *config.Config, pdClient *pagerduty.Client) error {
+	if cfg.PagerDutyStartupValidation == config.StartupValidationOff {
+		return nil
+	}
+	err := pdClient.Validate(context.Background())
+	if err == nil || cfg.PagerDutyStartupValidation == config.StartupValidationFail {
+		return err
+	}
+	slog.Warn("PagerDuty settings look wrong, continuing anyway", "error", err)
+	return nil
+}
+
+// resolveNames looks up the names of the user's schedule and the user, and the schedule's
+// timezone, once for use in notifications. They are informational, so they are left empty if
+// PagerDuty is unreachable. Escalation policy and team modes have no single schedule to report.
+func resolveNames(route config.UserRoute, pdClient *pagerduty.Client) (scheduleName, timeZone, userName string) {
+	if route.ScheduleID != "" {
+		if schedule, err := pdClient.GetSchedule(context.Background()); err != nil {
+			slog.Warn("Failed to resolve schedule name, notifications will omit it", "schedule_id", route.ScheduleID, "error", err)
+		} else {
+			scheduleName = schedule.Name
+			timeZone = schedule.TimeZone
 		}
-		// Send will message for ntfy notifier on graceful shutdown
-		if ntfyNotifier, ok := notifierInstance.(*notifier.NtfyNotifier); ok {
-			log.Println("Sending will message...")
-			if err := ntfyNotifier.SendWillMessage(); err != nil {
-				log.Printf("Failed to send will message: %v", err)
+	}
+	if user, err := pdClient.GetUser(context.Background()); err != nil {
+		slog.Warn("Failed to resolve user name, notifications will omit it", "user_id", route.UserID, "error", err)
+	} else {
+		userName = user.Name
+	}
+	return scheduleName, timeZone, userName
+}
+
+// messageLocation returns the timezone notifications show times in: MESSAGE_TIMEZONE if set,
+// otherwise the schedule's timezone, or nil for UTC if neither is known
+func messageLocation(cfg *config.Config, scheduleTimeZone string) *time.Location {
+	if cfg.MessageTimezone != nil {
+		return cfg.MessageTimezone
+	}
+	if scheduleTimeZone == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(scheduleTimeZone)
+	if err != nil {
+		slog.Warn("Unknown schedule timezone, showing times in UTC", "timezone", scheduleTimeZone, "error", err)
+		return nil
+	}
+	return location
+}
+
+// newPagerDutyClient creates a PagerDuty client for the user within the configured scope,
+// using the token and schedule of the user's account
+func newPagerDutyClient(cfg *config.Config, route config.UserRoute) (*pagerduty.Client, error) {
+	tlsConfig, err := httpclient.LoadTLSConfig(cfg.PagerDutyTLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure PagerDuty TLS: %w", err)
+	}
+	scope := pagerduty.Scope{
+		ScheduleID:         route.ScheduleID,
+		EscalationPolicyID: cfg.PagerDutyEscalationPolicyID,
+		TeamID:             cfg.PagerDutyTeamID,
+	}
+	return pagerduty.NewClient(route.APIToken, scope, route.UserID, pagerduty.ClientOptions{
+		APIURL:             cfg.PagerDutyAPIURL,
+		MaxPages:           cfg.PagerDutyMaxPages,
+		RetryMaxElapsed:    cfg.PagerDutyRetryMaxElapsed,
+		ShiftCacheTTL:      cfg.PagerDutyShiftCacheTTL,
+		MaxEscalationLevel: cfg.PagerDutyMaxEscalationLevel,
+		ScheduleSource:     cfg.PagerDutyScheduleSource,
+		ScheduleLayers:     cfg.PagerDutyScheduleLayers,
+		OAuth:              cfg.PagerDutyOAuth,
+		APITokenFile:       cfg.PagerDutyAPITokenFile,
+		ProxyURL:           cfg.PagerDutyProxyURL,
+		TLSConfig:          tlsConfig,
+		UserAgent:          version.UserAgent(),
+	}), nil
+}
+
+// sendWillMessages sends the ntfy will message for each monitored user
+func sendWillMessages(ctx context.Context, monitors []*monitor) {
+	for _, m := range monitors {
+		cfg, _, n := m.current()
+		if ntfyNotifier, ok := n.(*notifier.NtfyNotifier); ok {
+			logger := monitorLogger(cfg, m.route)
+			logger.Info("Sending will message...")
+			if err := ntfyNotifier.SendWillMessage(ctx); err != nil {
+				logger.Warn("Failed to send will message", "error", err)
 			} else {
-				log.Println("Will message sent successfully")
+				logger.Info("Will message sent successfully")
 			}
 		}
 	}
-
-	log.Println("Shutdown complete")
 }
 
-// createNotifier creates the appropriate notifier based on the configuration
-func createNotifier(cfg *config.Config) (notifier.Notifier, error) {
+// createNotifier creates the appropriate notifier for a monitored user based on the configuration
+func createNotifier(cfg *config.Config, pdClient *pagerduty.Client, route config.UserRoute) (notifier.Notifier, error) {
+	messages, err := notifier.NewMessages(cfg.MessageLocale)
+	if err != nil {
+		return nil, err
+	}
+	scheduleName, timeZone, userName := resolveNames(route, pdClient)
+	messages = messages.WithNames(scheduleName, userName).WithLocation(messageLocation(cfg, timeZone))
+
 	switch cfg.NotificationBackend {
 	case config.BackendWebhook:
-		log.Printf("Using webhook notifier: %s", cfg.NotificationWebhookURL)
-		return notifier.NewWebhookNotifier(cfg.NotificationWebhookURL), nil
+		webhookURL, webhookURLs := cfg.NotificationWebhookURL, cfg.WebhookURLs
+		if route.Target != "" {
+			// The user's own target receives every event, so WEBHOOK_URLS cannot send their
+			// notifications to someone else's endpoint
+			webhookURL, webhookURLs = route.Target, nil
+		}
+		slog.Info("Using webhook notifier", "method", cfg.WebhookMethod, "url", webhookURL, "format", cfg.WebhookFormat, "encoding", cfg.WebhookEncoding)
+		for event, webhookURL := range webhookURLs {
+			slog.Info("Webhook URL override", "event", event, "url", webhookURL)
+		}
+		opts := notifier.WebhookOptions{
+			Format:     cfg.WebhookFormat,
+			URLs:       webhookURLs,
+			Method:     cfg.WebhookMethod,
+			Encoding:   cfg.WebhookEncoding,
+			ScheduleID: route.ScheduleID,
+			UserID:     route.UserID,
+			Timeout:    cfg.WebhookTimeout,
+		}
+		tlsConfig, err := httpclient.LoadTLSConfig(cfg.WebhookTLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure webhook TLS: %w", err)
+		}
+		if tlsConfig != nil {
+			slog.Info("Webhook TLS configured", "client_certificate", cfg.WebhookTLS.CertFile != "", "custom_ca", cfg.WebhookTLS.CAFile != "")
+			opts.TLSConfig = tlsConfig
+		}
+		if cfg.WebhookTemplate != "" {
+			tmpl, err := notifier.ParseWebhookTemplate(cfg.WebhookTemplate)
+			if err != nil {
+				return nil, err
+			}
+			slog.Info("Using custom webhook template")
+			opts.Template = tmpl
+		}
+		opts.ScheduleName, opts.UserName = scheduleName, userName
+		return notifier.NewWebhookNotifier(webhookURL, messages, opts), nil
 	case config.BackendNtfy:
-		log.Printf("Using ntfy notifier: %s/%s", cfg.NtfyServerURL, cfg.NtfyTopic)
+		topic := cfg.NtfyTopic
+		if route.Target != "" {
+			topic = route.Target
+		}
+		slog.Info("Using ntfy notifier", "server", cfg.NtfyServerURL, "topic", topic)
 		if cfg.NtfyAPIKey != "" {
-			log.Println("Ntfy authentication enabled")
+			slog.Info("Ntfy authentication enabled")
+		}
+		opts := notifier.NtfyOptions{
+			Priorities:   cfg.NtfyPriorities,
+			ActionsURL:   cfg.HTTPPublicURL,
+			ActionsToken: cfg.HTTPActionToken,
+			Markdown:     cfg.NtfyMarkdown,
+			Emails:       cfg.NtfyEmails,
+			Tags:         cfg.NtfyTags,
+			Timeout:      cfg.NtfyTimeout,
+		}
+		if opts.ActionsURL != "" {
+			slog.Info("Ntfy action buttons enabled", "url", opts.ActionsURL)
 		}
-		return notifier.NewNtfyNotifier(cfg.NtfyServerURL, cfg.NtfyTopic, cfg.NtfyAPIKey), nil
+		return notifier.NewNtfyNotifier(cfg.NtfyServerURL, topic, cfg.NtfyAPIKey, messages, opts), nil
 	case config.BackendPushover:
-		log.Println("Using Pushover notifier")
-		if cfg.PushoverDevice != "" {
-			log.Printf("Pushover device targeting enabled: %s", cfg.PushoverDevice)
+		userKeys := cfg.PushoverUserKeys
+		if route.Target != "" {
+			userKeys = route.Recipients()
 		}
-		if cfg.PushoverSound != "" {
-			log.Printf("Pushover sound override: %s", cfg.PushoverSound)
+		slog.Info("Using Pushover notifier", "recipients", len(userKeys))
+		opts := notifier.PushoverOptions{
+			Device:     cfg.PushoverDevice,
+			Sound:      cfg.PushoverSound,
+			Sounds:     cfg.PushoverSounds,
+			TTLs:       cfg.PushoverTTLs,
+			Priorities: cfg.PushoverPriorities,
+			HTML:       cfg.PushoverHTML,
+			URL:        cfg.PushoverURL,
+			Timeout:    cfg.PushoverTimeout,
 		}
-		return notifier.NewPushoverNotifier(cfg.PushoverAppToken, cfg.PushoverUserKey, cfg.PushoverDevice, cfg.PushoverSound), nil
+		if opts.Device != "" {
+			slog.Info("Pushover device targeting enabled", "device", opts.Device)
+		}
+		if opts.Sound != "" {
+			slog.Info("Pushover sound override", "sound", opts.Sound)
+		}
+		for event, sound := range opts.Sounds {
+			slog.Info("Pushover sound override", "event", event, "sound", sound)
+		}
+		if opts.URL == "" {
+			// Link notifications to the schedule so the rota is one tap away
+			scheduleURL, err := pdClient.GetScheduleURL(context.Background())
+			if err != nil {
+				slog.Warn("Failed to resolve schedule URL, notifications will not include a link", "error", err)
+			}
+			opts.URL = scheduleURL
+		}
+		return notifier.NewPushoverNotifier(cfg.PushoverAppToken, userKeys, messages, opts), nil
 	default:
 		return nil, fmt.Errorf("unsupported notification backend: %s", cfg.NotificationBackend)
 	}
 }
 
-func runPollingLoop(
-	ctx context.Context,
-	pdClient *pagerduty.Client,
-	stateManager *state.Manager,
-	n notifier.Notifier,
-	interval time.Duration,
-	cfg *config.Config,
-) error {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// Load initial state
-	currentState, err := stateManager.Load()
-	if err != nil {
+// runPollingLoop checks m's on-call status every check interval until ctx is cancelled. Each
+// check uses the monitor's current configuration, so a reload takes effect on the next check.
+// monitors are all monitored users, whose checks decide together whether a heartbeat is sent.
+func runPollingLoop(ctx context.Context, m *monitor, monitors []*monitor, checkNow <-chan string) error {
+	// Verify state can be loaded before polling
+	if _, err := m.stateManager.Load(); err != nil {
 		return fmt.Errorf("failed to load initial state: %w", err)
 	}
 
+	cfg, _, _ := m.current()
+	currentInterval := cfg.PollInterval()
+	timer := time.NewTimer(jitter(currentInterval, cfg.CheckIntervalJitter))
+	defer timer.Stop()
+
+	// When the notifier last alerted that checks keep failing, zero while they succeed
+	var lastDegradedAlert time.Time
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			// Check on-call status
-			isOnCall, err := pdClient.IsOnCall(ctx)
-			if err != nil {
-				log.Printf("Error checking on-call status: %v", err)
-				continue
+		case <-timer.C:
+		case reason := <-checkNow:
+			slog.Info("Checking on-call status now", "reason", reason, "user_id", m.route.UserID)
+			timer.Stop()
+		}
+
+		cfg, pdClient, n := m.current()
+		logger := monitorLogger(cfg, m.route)
+		checkStarted := time.Now()
+		wakeAt, err := runCheck(ctx, logger, pdClient, m.stateManager, n, cfg)
+		if err != nil {
+			logger.Error("Check failed", "error", err)
+			if m.health.RecordFailure(err) {
+				failures := m.health.Status().ConsecutiveFailures
+				logger.Error("PagerDuty checks failing", "consecutive_failures", failures)
+				attrs := append(monitorAttrs(cfg, m.route), "error", err, "consecutive_failures", failures)
+				if err := errorReporter.Report(ctx, "PagerDuty checks failing", attrs...); err != nil {
+					logger.Warn("Failed to report error", "error", err)
+				}
+			}
+			failures := m.health.Status().ConsecutiveFailures
+			if degradedAlertDue(cfg, failures, lastDegradedAlert, time.Now()) {
+				event := notifier.EventNotifierDegraded
+				logger.Info("Sending degraded alert", "event", event, "consecutive_failures", failures)
+				if err := notifyShift(ctx, n, event, notifier.Shift{Start: time.Now().UTC()}); err != nil {
+					logger.Warn("Failed to send degraded alert", "event", event, "error", err, audit.Failed)
+				} else {
+					logger.Info("Degraded alert sent successfully", "event", event, audit.Sent)
+					lastDegradedAlert = time.Now()
+				}
+			}
+		} else {
+			lastDegradedAlert = time.Time{}
+			if m.health.RecordSuccess() {
+				logger.Info("PagerDuty checks recovered")
+			}
+			sendHeartbeat(ctx, logger, cfg, monitors)
+		}
+		currentInterval = nextPollInterval(logger, pdClient, cfg.PollInterval(), currentInterval, checkStarted)
+		next := jitter(currentInterval, cfg.CheckIntervalJitter)
+		if cfg.EventDrivenChecks && !wakeAt.IsZero() && time.Until(wakeAt) < next {
+			next = time.Until(wakeAt)
+			logger.Debug("Next check scheduled for the next shift event", "at", wakeAt.Round(time.Second))
+		}
+		// Neither jitter nor shift events bring the next check before PagerDuty said to retry
+		_, retryAt := pdClient.RateLimit()
+		timer.Reset(max(next, time.Until(retryAt)))
+	}
+}
+
+// degradedAlertDue reports whether a notifier_degraded alert should be sent at now after
+// failures consecutive failed checks: once they reach DEGRADED_ALERT_THRESHOLD, and again
+// every DEGRADED_ALERT_INTERVAL after lastAlert. lastAlert is zero if none was sent since the
+// last successful check.
+func degradedAlertDue(cfg *config.Config, failures int, lastAlert, now time.Time) bool {
+	if cfg.DegradedAlertThreshold <= 0 || failures < cfg.DegradedAlertThreshold {
+		return false
+	}
+	return lastAlert.IsZero() || now.Sub(lastAlert) >= cfg.DegradedAlertInterval
+}
+
+// shiftEventDelay is how long after a shift event the check for it is made, so that
+// PagerDuty reports the new on-call status
+const shiftEventDelay = 5 * time.Second
+
+// nextShiftEvent returns when the next check is needed for a known shift event: an advance
+// notification, the start of the upcoming shift, the end of the current shift or the reminder
+// before it, or the daily summary. It returns the zero time if none of them is ahead.
+func nextShiftEvent(cfg *config.Config, upcoming, current *pagerduty.UpcomingShift) time.Time {
+	var events []time.Time
+	if upcoming != nil {
+		events = append(events, upcoming.StartTime)
+		for _, advanceTime := range cfg.AdvanceNotificationTimes {
+			events = append(events, deferAdvanceNotification(cfg, upcoming.StartTime.Add(-advanceTime)))
+		}
+	}
+	if current != nil {
+		events = append(events, current.EndTime)
+		if cfg.ShiftEndingNotificationTime > 0 {
+			events = append(events, current.EndTime.Add(-cfg.ShiftEndingNotificationTime))
+		}
+	}
+	if cfg.DailySummaryTime != nil {
+		_, next := dailySummaryTimes(*cfg.DailySummaryTime, cfg.ClockLocation(), time.Now())
+		events = append(events, next)
+	}
+
+	var next time.Time
+	now := time.Now()
+	for _, event := range events {
+		if event = event.Add(shiftEventDelay); event.After(now) && (next.IsZero() || event.Before(next)) {
+			next = event
+		}
+	}
+	return next
+}
+
+// jitter moves a poll interval by a random amount of up to spread either way, so that notifiers
+// started together do not keep polling PagerDuty at the same moment
+func jitter(interval, spread time.Duration) time.Duration {
+	if spread <= 0 {
+		return interval
+	}
+	return interval - spread + rand.N(2*spread+1)
+}
+
+// runOnce checks every monitored user a single time, sending any due notifications and saving
+// state, and reports the users whose check failed
+func runOnce(monitors []*monitor) error {
+	var errs []error
+	for _, m := range monitors {
+		cfg, pdClient, n := m.current()
+		logger := monitorLogger(cfg, m.route)
+		if _, err := runCheck(context.Background(), logger, pdClient, m.stateManager, n, cfg); err != nil {
+			m.health.RecordFailure(err)
+			switch {
+			case cfg.Profile != "" && m.route.UserID != "":
+				err = fmt.Errorf("profile %s, user %s: %w", cfg.Profile, m.route.UserID, err)
+			case cfg.Profile != "":
+				err = fmt.Errorf("profile %s: %w", cfg.Profile, err)
+			case len(monitors) > 1:
+				err = fmt.Errorf("user %s: %w", m.route.UserID, err)
 			}
+			errs = append(errs, err)
+			continue
+		}
+		m.health.RecordSuccess()
+	}
+	// Each heartbeat URL is pinged once, after every user it is configured for was checked
+	pinged := map[string]bool{}
+	for _, m := range monitors {
+		cfg, _, _ := m.current()
+		if !pinged[cfg.HeartbeatURL] {
+			pinged[cfg.HeartbeatURL] = true
+			sendHeartbeat(context.Background(), monitorLogger(cfg, m.route), cfg, monitors)
+		}
+	}
+	return errors.Join(errs...)
+}
 
-			log.Printf("On-call status: %v (previous: %v)", isOnCall, currentState.WasOnCall)
+// metricsTargets returns the monitored users whose on-call status is exported as metrics
+func metricsTargets(monitors []*monitor) []metrics.Target {
+	var targets []metrics.Target
+	for _, m := range monitors {
+		cfg, _, _ := m.current()
+		targets = append(targets, metrics.Target{
+			UserID:       m.route.UserID,
+			ScheduleID:   m.route.ScheduleID,
+			Profile:      cfg.Profile,
+			StateManager: m.stateManager,
+			Health:       m.health,
+		})
+	}
+	return targets
+}
 
-			// Check for upcoming shifts if advance notification is enabled
-			if cfg.AdvanceNotificationTime > 0 {
-				upcomingShift, err := pdClient.GetUpcomingShift(ctx)
-				if err != nil {
-					log.Printf("Error checking upcoming shifts: %v", err)
-				} else if upcomingShift != nil {
-					log.Printf("Upcoming shift found: starts at %v", upcomingShift.StartTime)
+// sendHeartbeat pings HEARTBEAT_URL, if set, to tell an external monitor the notifier is checking.
+// The URL is shared by every monitored user it is configured for, so it is only pinged while
+// the latest check of each of them succeeded.
+func sendHeartbeat(ctx context.Context, logger *slog.Logger, cfg *config.Config, monitors []*monitor) {
+	if cfg.HeartbeatURL == "" {
+		return
+	}
+	for _, m := range monitors {
+		monitorCfg, _, _ := m.current()
+		if monitorCfg.HeartbeatURL != cfg.HeartbeatURL {
+			continue
+		}
+		if status := m.health.Status(); status.ConsecutiveFailures > 0 || status.LastSuccess == nil {
+			logger.Debug("Heartbeat skipped, a check is failing", "failing_user_id", m.route.UserID)
+			return
+		}
+	}
+	if err := heartbeat.Ping(ctx, cfg.HeartbeatURL); err != nil {
+		logger.Warn("Heartbeat failed", "error", err)
+	}
+}
+
+// annotateTransition marks the user going on or off call, as event, in GRAFANA_URL if set
+func annotateTransition(ctx context.Context, logger *slog.Logger, cfg *config.Config, userID string, event notifier.NotificationEvent, at time.Time) {
+	if cfg.GrafanaURL == "" {
+		return
+	}
+	text := userID + " went on call"
+	if event == notifier.EventShiftEnded {
+		text = userID + " went off call"
+	}
+	tags := append([]string{"oncall", "user:" + userID, string(event)}, cfg.GrafanaAnnotationTags...)
+	if err := grafana.Annotate(ctx, cfg.GrafanaURL, cfg.GrafanaAPIToken, grafana.Annotation{Time: at, Tags: tags, Text: text}); err != nil {
+		logger.Warn("Failed to write Grafana annotation", "event", event, "error", err)
+	}
+}
+
+// setSlackStatus sets the Slack status of SLACK_USER_TOKEN's owner, if set, to say they are on
+// call until end, and records it in the state. Slack clears the status at end by itself, in
+// case the notifier is not running.
+func setSlackStatus(ctx context.Context, logger *slog.Logger, cfg *config.Config, stateManager *state.Manager, currentState *state.State, end time.Time) {
+	if cfg.SlackUserToken == "" {
+		return
+	}
+	status := slack.Status{Text: "On call", Emoji: cfg.SlackStatusEmoji}
+	if !end.IsZero() {
+		status.Text = "On call until " + end.In(cfg.ClockLocation()).Format("Mon 15:04")
+		status.Expiration = end
+	}
+	if err := slack.SetStatus(ctx, cfg.SlackUserToken, status); err != nil {
+		logger.Warn("Failed to set Slack status", "error", err)
+		return
+	}
+	logger.Info("Slack status set", "status", status.Text)
+	stateManager.RecordSlackStatus(currentState, status.Text, end)
+}
+
+// refreshSlackStatus sets the Slack status again when the end of the current shift is not the
+// one it shows, e.g. after an override extended the shift, unless the user has set a status
+// of their own since
+func refreshSlackStatus(ctx context.Context, logger *slog.Logger, cfg *config.Config, stateManager *state.Manager, currentState *state.State, end time.Time) {
+	if cfg.SlackUserToken == "" || (currentState.SlackStatusEnd != nil && currentState.SlackStatusEnd.Equal(end)) {
+		return
+	}
+	current, err := slack.GetStatus(ctx, cfg.SlackUserToken)
+	if err != nil {
+		logger.Warn("Failed to check Slack status", "error", err)
+		return
+	}
+	if current.Text != "" && current.Text != currentState.SlackStatusText {
+		logger.Info("Slack status was changed, leaving it", "status", current.Text)
+		stateManager.RecordSlackStatus(currentState, "", end)
+		return
+	}
+	setSlackStatus(ctx, logger, cfg, stateManager, currentState, end)
+}
+
+// clearSlackStatus clears the Slack status of SLACK_USER_TOKEN's owner, if set, unless it is no
+// longer the status the notifier set
+func clearSlackStatus(ctx context.Context, logger *slog.Logger, cfg *config.Config, stateManager *state.Manager, currentState *state.State) {
+	setText := currentState.SlackStatusText
+	stateManager.RecordSlackStatus(currentState, "", time.Time{})
+	if cfg.SlackUserToken == "" || setText == "" {
+		return
+	}
+	current, err := slack.GetStatus(ctx, cfg.SlackUserToken)
+	if err != nil {
+		// The status expires at the end of the shift by itself
+		logger.Warn("Failed to check Slack status, leaving it", "error", err)
+		return
+	}
+	if current.Text != setText {
+		logger.Info("Slack status was changed, leaving it", "status", current.Text)
+		return
+	}
+	if err := slack.ClearStatus(ctx, cfg.SlackUserToken); err != nil {
+		logger.Warn("Failed to clear Slack status", "error", err)
+	} else {
+		logger.Info("Slack status cleared")
+	}
+}
+
+// maxPollBackoffFactor caps how far the poll interval is stretched while rate limited
+const maxPollBackoffFactor = 8
+
+// nextPollInterval doubles the poll interval (up to maxPollBackoffFactor times the configured
+// interval) when the last check was rate limited, and eases back towards the configured
+// interval once checks succeed again. It never polls before PagerDuty said to retry.
+func nextPollInterval(logger *slog.Logger, pdClient pagerduty.OnCallChecker, interval, current time.Duration, checkStarted time.Time) time.Duration {
+	limitedAt, retryAt := pdClient.RateLimit()
+	if !limitedAt.Before(checkStarted) {
+		next := min(current*2, interval*maxPollBackoffFactor)
+		next = max(next, time.Until(retryAt))
+		logger.Warn("Rate limited by PagerDuty", "next_check_in", next.Round(time.Second))
+		return next
+	}
+	if current > interval {
+		next := max(current/2, interval)
+		logger.Info("Rate limit cleared", "next_check_in", next.Round(time.Second))
+		return next
+	}
+	return interval
+}
+
+// runCheck performs a single on-call check, sending any due notifications and persisting state
+// State is reloaded for every check so changes made by other commands (e.g. mute) are respected
+// It returns when the next known shift event is due, or the zero time if none is known
+func runCheck(
+	ctx context.Context,
+	logger *slog.Logger,
+	pdClient pagerduty.OnCallChecker,
+	stateManager *state.Manager,
+	n notifier.Notifier,
+	cfg *config.Config,
+) (time.Time, error) {
+	// Check on-call status
+	isOnCall, err := pdClient.IsOnCall(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check on-call status: %w", err)
+	}
+
+	// Check for upcoming shifts if advance notification, status publishing, override detection,
+	// the daily summary, or the HTTP API's status or metrics are enabled
+	var upcomingShift *pagerduty.UpcomingShift
+	var upcomingErr error
+	lookUpUpcoming := len(cfg.AdvanceNotificationTimes) > 0 || cfg.PushoverGlances || cfg.OverrideNotificationsEnabled ||
+		cfg.EventDrivenChecks || cfg.DailySummaryTime != nil || cfg.HTTPListenAddr != "" ||
+		cfg.MetricsListenAddr != "" || cfg.PushgatewayURL != ""
+	if lookUpUpcoming {
+		upcomingShift, upcomingErr = pdClient.GetUpcomingShift(ctx)
+		if upcomingErr != nil {
+			logger.Warn("Error checking upcoming shifts", "error", upcomingErr)
+		}
+	}
+
+	// Look up when the current shift ends if a reminder should be sent before it does, a check
+	// should be made when it does, or the daily summary, status, metrics, or Slack status may
+	// mention it
+	var currentShiftEnd *pagerduty.UpcomingShift
+	var currentErr error
+	lookUpCurrent := (cfg.ShiftEndingNotificationTime > 0 || cfg.EventDrivenChecks || cfg.DailySummaryTime != nil ||
+		cfg.HTTPListenAddr != "" || cfg.MetricsListenAddr != "" || cfg.PushgatewayURL != "" || cfg.SlackUserToken != "") && isOnCall
+	if lookUpCurrent {
+		currentShiftEnd, currentErr = pdClient.GetCurrentShift(ctx)
+		if currentErr != nil {
+			logger.Warn("Error checking current shift", "error", currentErr)
+		}
+	}
+
+	// Look for upcoming gaps in schedule coverage if enabled
+	var coverageGaps []pagerduty.Gap
+	if cfg.CoverageGapLookahead > 0 {
+		var err error
+		coverageGaps, err = pdClient.FindCoverageGaps(ctx, cfg.CoverageGapLookahead)
+		if err != nil {
+			logger.Warn("Error checking schedule coverage", "error", err)
+		}
+	}
+
+	// List the user's shifts in the change detection window if enabled
+	var scheduledShifts []state.ShiftWindow
+	var scheduledErr error
+	scheduledUntil := time.Now().UTC().AddDate(0, 0, cfg.ScheduleChangeDays)
+	if cfg.ScheduleChangeDays > 0 {
+		var shifts []pagerduty.UpcomingShift
+		shifts, scheduledErr = pdClient.ListShifts(ctx, time.Now().UTC(), scheduledUntil)
+		if scheduledErr != nil {
+			logger.Warn("Error checking scheduled shifts", "error", scheduledErr)
+		}
+		for _, shift := range shifts {
+			scheduledShifts = append(scheduledShifts, state.ShiftWindow{Start: shift.StartTime, End: shift.EndTime})
+		}
+	}
+
+	// Look up everyone on call if unexpected responders should be reported
+	var unexpected []pagerduty.OnCall
+	var respondersErr error
+	if len(cfg.ExpectedOnCallUsers) > 0 {
+		var oncalls []pagerduty.OnCall
+		oncalls, respondersErr = pdClient.CurrentOnCalls(ctx)
+		if respondersErr != nil {
+			logger.Warn("Error checking current responders", "error", respondersErr)
+		}
+		for _, oncall := range oncalls {
+			if !slices.Contains(cfg.ExpectedOnCallUsers, oncall.UserID) {
+				unexpected = append(unexpected, oncall)
+			}
+		}
+	}
+
+	// Publish ambient on-call status if supported by the backend
+	if publisher, ok := n.(notifier.StatusPublisher); ok && cfg.PushoverGlances && upcomingErr == nil {
+		var nextShiftStart *time.Time
+		if upcomingShift != nil {
+			nextShiftStart = &upcomingShift.StartTime
+		}
+		if err := publisher.PublishStatus(ctx, isOnCall, nextShiftStart); err != nil {
+			logger.Warn("Failed to publish on-call status", "error", err)
+		}
+	}
+
+	wakeAt := nextShiftEvent(cfg, upcomingShift, currentShiftEnd)
+	backend := string(cfg.NotificationBackend)
+	return wakeAt, stateManager.Update(func(currentState *state.State) error {
+		logger.Info("On-call status checked", "on_call", isOnCall, "was_on_call", currentState.WasOnCall)
+		stateManager.RecordCheck(currentState)
+
+		// Keep the shift times last looked up for the HTTP API's status and metrics
+		if lookUpUpcoming && upcomingErr == nil {
+			stateManager.RecordNextShift(currentState, shiftWindow(upcomingShift))
+		}
+		if !isOnCall || (lookUpCurrent && currentErr == nil) {
+			stateManager.RecordCurrentShift(currentState, shiftWindow(currentShiftEnd))
+		}
+
+		muted := stateManager.IsMuted(currentState)
+		if muted {
+			logger.Info("Notifications muted", "until", currentState.MutedUntil.Format(time.RFC3339))
+		}
+		if pause := stateManager.ActivePause(currentState); pause != nil {
+			logger.Info("Notifications paused", "until", pause.Until.Format(time.RFC3339))
+			muted = true
+		}
+
+		// Take back reminders handed to the backend once they should no longer be delivered,
+		// because notifications are muted or the shift they were for moved or was overridden
+		if scheduledFor := currentState.AdvanceNotificationScheduledFor; scheduledFor != nil && lookUpUpcoming && upcomingErr == nil {
+			reason := ""
+			if muted {
+				reason = "muted"
+			} else if upcomingShift == nil || !upcomingShift.StartTime.Equal(*scheduledFor) {
+				reason = "shift changed"
+			}
+			if reason != "" {
+				cancelAdvanceNotifications(ctx, logger, n, stateManager.ClearAdvanceNotificationSchedule(currentState), reason)
+			}
+		}
 
-					// Check if we should send an advance notification
-					if stateManager.ShouldSendAdvanceNotification(currentState, upcomingShift.StartTime, cfg.AdvanceNotificationTime) {
-						log.Printf("Sending advance notification for shift starting at %v", upcomingShift.StartTime)
+		if advanceTimes := cfg.AdvanceNotificationTimes; len(advanceTimes) > 0 && upcomingErr == nil {
+			if upcomingShift != nil {
+				logger.Info("Upcoming shift found", "shift_start", upcomingShift.StartTime)
 
-						event := notifier.EventUpcomingShift
-						if err := n.NotifyWithEvent(event, upcomingShift.StartTime); err != nil {
-							log.Printf("Failed to send advance notification: %v", err)
+				// The shortest advance time is delivered last, so once it can be scheduled all of them
+				// can. A reminder whose window has already started is sent right away below.
+				justScheduled := false
+				scheduler, canSchedule := n.(notifier.ScheduledNotifier)
+				if cfg.ScheduledAdvanceNotifications && canSchedule &&
+					stateManager.ShouldScheduleAdvanceNotification(currentState, upcomingShift.StartTime, advanceTimes[len(advanceTimes)-1], scheduler.MaxScheduleDelay()) {
+					if muted {
+						logger.Info("Skipping scheduling advance notification (muted)", "event", notifier.EventUpcomingShift, "shift_start", upcomingShift.StartTime, audit.Suppressed("muted"))
+					} else if scheduled := scheduleAdvanceNotifications(ctx, logger, scheduler, cfg, upcomingShift.StartTime); len(scheduled) > 0 {
+						stateManager.RecordAdvanceNotificationScheduled(currentState, upcomingShift.StartTime, scheduled)
+						justScheduled = true
+					}
+				}
+				if !justScheduled && stateManager.IsAdvanceNotificationScheduled(currentState, upcomingShift.StartTime) {
+					logger.Debug("Advance notification already scheduled for this shift", "event", notifier.EventUpcomingShift)
+				} else if stateManager.ShouldSendAdvanceNotification(currentState, upcomingShift.StartTime, advanceTimes...) {
+					now := time.Now()
+					event := notifier.EventUpcomingShift
+					if muted {
+						logger.Info("Skipping advance notification (muted)", "event", event, "shift_start", upcomingShift.StartTime, audit.Suppressed("muted"))
+					} else if deliverAt := deferAdvanceNotification(cfg, now); !deliverAt.Equal(now) {
+						logger.Info("Advance notification deferred (outside ADVANCE_NOTIFICATION_WINDOW)", "event", event, "shift_start", upcomingShift.StartTime, "deliver_at", deliverAt, audit.Deferred("quiet hours"))
+					} else {
+						logger.Info("Sending advance notification", "event", event, "shift_start", upcomingShift.StartTime)
+
+						shift := notifier.Shift{Start: upcomingShift.StartTime, End: upcomingShift.EndTime}
+						if err := notifyShift(ctx, n, event, shift); err != nil {
+							logger.Warn("Failed to send advance notification", "event", event, "error", err, audit.Failed)
 							// Continue even if notification fails
 						} else {
-							log.Println("Advance notification sent successfully")
+							logger.Info("Advance notification sent successfully", "event", event, audit.Sent)
 							// Record that we sent the advance notification
-							stateManager.RecordAdvanceNotificationSent(currentState)
+							stateManager.RecordAdvanceNotificationSent(currentState, upcomingShift.StartTime)
+							stateManager.RecordNotificationSent(currentState, backend, string(event), upcomingShift.StartTime)
 						}
-					} else {
-						log.Printf("Advance notification not needed (already sent or not in window)")
 					}
+				} else if !justScheduled {
+					logger.Debug("Advance notification not needed (already sent or not in window)", "event", notifier.EventUpcomingShift)
+				}
+			} else {
+				logger.Debug("No upcoming shifts found")
+			}
+		}
+
+		if currentShiftEnd != nil && stateManager.ShouldSendShiftEndingNotification(currentState, currentShiftEnd.EndTime, cfg.ShiftEndingNotificationTime) {
+			event := notifier.EventShiftEnding
+			if muted {
+				logger.Info("Skipping shift ending notification (muted)", "event", event, "shift_end", currentShiftEnd.EndTime, audit.Suppressed("muted"))
+			} else {
+				logger.Info("Sending shift ending notification", "event", event, "shift_end", currentShiftEnd.EndTime)
+
+				shift := notifier.Shift{Start: currentShiftEnd.StartTime, End: currentShiftEnd.EndTime}
+				if err := notifyShift(ctx, n, event, shift); err != nil {
+					logger.Warn("Failed to send shift ending notification", "event", event, "error", err, audit.Failed)
+					// Continue even if notification fails
+				} else {
+					logger.Info("Shift ending notification sent successfully", "event", event, audit.Sent)
+					stateManager.RecordShiftEndingNotificationSent(currentState, currentShiftEnd.EndTime)
+					stateManager.RecordNotificationSent(currentState, backend, string(event), currentShiftEnd.StartTime)
+				}
+			}
+		}
+
+		if cfg.OverrideNotificationsEnabled && upcomingErr == nil {
+			var next *state.ShiftWindow
+			if upcomingShift != nil {
+				next = &state.ShiftWindow{Start: upcomingShift.StartTime, End: upcomingShift.EndTime}
+			}
+			if removed := stateManager.TrackUpcomingShift(currentState, next); removed != nil {
+				event := notifier.EventShiftOverridden
+				if muted {
+					logger.Info("Shift was overridden, skipping notification (muted)", "event", event, "shift_start", removed.Start, audit.Suppressed("muted"))
 				} else {
-					log.Printf("No upcoming shifts found")
+					logger.Info("Shift was overridden. Sending notifier...", "event", event, "shift_start", removed.Start)
+
+					if err := notifyShift(ctx, n, event, notifier.Shift{Start: removed.Start, End: removed.End}); err != nil {
+						logger.Warn("Failed to send shift overridden notification", "event", event, "error", err, audit.Failed)
+						// Continue even if notification fails
+					} else {
+						logger.Info("Shift overridden notification sent successfully", "event", event, audit.Sent)
+						stateManager.RecordNotificationSent(currentState, backend, string(event), removed.Start)
+					}
 				}
 			}
+		}
 
-			// Check for transition to on-call
-			if stateManager.HasTransitionToOnCall(currentState, isOnCall) {
-				log.Printf("Shift started! Sending notifier...")
+		if len(coverageGaps) > 0 {
+			gap := coverageGaps[0]
+			window := state.ShiftWindow{Start: gap.Start, End: gap.End}
+			event := notifier.EventCoverageGap
+			gapLogger := logger.With("event", event, "gap_schedule_id", gap.ScheduleID, "gap_start", gap.Start, "gap_end", gap.End)
+			if !stateManager.ShouldSendCoverageGapAlert(currentState, window) {
+				gapLogger.Debug("Coverage gap already alerted")
+				stateManager.RecordCoverageGapAlert(currentState, window)
+			} else if muted {
+				gapLogger.Info("Coverage gap found, skipping alert (muted)", audit.Suppressed("muted"))
+			} else {
+				gapLogger.Info("Coverage gap found. Sending notifier...")
 
-				event := notifier.EventShiftStarted
-				if err := n.NotifyWithEvent(event, time.Now().UTC()); err != nil {
-					log.Printf("Failed to send shift started notification: %v", err)
+				if err := notifyShift(ctx, n, event, notifier.Shift{Start: gap.Start, End: gap.End}); err != nil {
+					gapLogger.Warn("Failed to send coverage gap alert", "error", err, audit.Failed)
 					// Continue even if notification fails
 				} else {
-					log.Println("Shift started notification sent successfully")
+					gapLogger.Info("Coverage gap alert sent successfully", audit.Sent)
+					stateManager.RecordCoverageGapAlert(currentState, window)
+					stateManager.RecordNotificationSent(currentState, backend, string(event), gap.Start)
 				}
 			}
+		}
+
+		if len(cfg.ExpectedOnCallUsers) > 0 && respondersErr == nil {
+			alertUnexpectedResponders(ctx, logger, n, backend, stateManager, currentState, unexpected, muted)
+		}
+
+		if cfg.ScheduleChangeDays > 0 && scheduledErr == nil {
+			notifyScheduleChanges(ctx, logger, n, backend, stateManager, currentState, scheduledShifts, scheduledUntil, muted)
+		}
 
-			// Check for transition off on-call (shift ended)
-			if cfg.ShiftEndNotificationsEnabled && stateManager.HasTransitionToOffCall(currentState, isOnCall) {
-				log.Printf("Shift ended. Sending notifier...")
+		// The summary waits for the next check if the next shift could not be looked up
+		if cfg.DailySummaryTime != nil && upcomingErr == nil {
+			sendDailySummary(ctx, logger, n, backend, stateManager, currentState, cfg, isOnCall, upcomingShift, currentShiftEnd, muted)
+		}
+
+		// Check for transition to on-call
+		if stateManager.HasTransitionToOnCall(currentState, isOnCall) {
+			shift, scheduleID, override := currentShift(ctx, logger, pdClient, n)
+			stateManager.RecordShiftStarted(currentState, shift.Start, scheduleID)
+			logger.Info("Went on call", "shift_start", shift.Start, audit.Transition)
+			annotateTransition(ctx, logger, cfg, pdClient.UserID(), notifier.EventShiftStarted, time.Now())
+			setSlackStatus(ctx, logger, cfg, stateManager, currentState, shift.End)
+			if cfg.ShiftStartIncidentSummary {
+				shift.OpenIncidents = openIncidents(ctx, logger, pdClient)
+			}
+			event := notifier.EventShiftStarted
+			if override {
+				logger.Info("Covering via a schedule override", "shift_end", shift.End)
+				event = notifier.EventCoverageStarted
+			}
+			if muted {
+				logger.Info("Shift started, skipping notification (muted)", "event", event, audit.Suppressed("muted"))
+			} else {
+				logger.Info("Shift started! Sending notifier...", "event", event)
 
-				event := notifier.EventShiftEnded
-				if err := n.NotifyWithEvent(event, time.Now().UTC()); err != nil {
-					log.Printf("Failed to send shift ended notification: %v", err)
+				if err := notifyShift(ctx, n, event, shift); err != nil {
+					logger.Warn("Failed to send shift started notification", "event", event, "error", err, audit.Failed)
 					// Continue even if notification fails
 				} else {
-					log.Println("Shift ended notification sent successfully")
+					logger.Info("Shift started notification sent successfully", "event", event, audit.Sent)
+					stateManager.RecordNotificationSent(currentState, backend, string(event), shift.Start)
 				}
 			}
+		}
+
+		// Check for transition off on-call (shift ended)
+		if stateManager.HasTransitionToOffCall(currentState, isOnCall) {
+			start, scheduleID := stateManager.RecordShiftEnded(currentState)
+			logger.Info("Went off call", "shift_start", start, audit.Transition)
+			annotateTransition(ctx, logger, cfg, pdClient.UserID(), notifier.EventShiftEnded, time.Now())
+			clearSlackStatus(ctx, logger, cfg, stateManager, currentState)
+			event := notifier.EventShiftEnded
+			if !cfg.ShiftEndNotificationsEnabled {
+				logger.Info("Shift ended, notifications disabled", "event", event, audit.Suppressed("notifications disabled"))
+			} else if muted {
+				logger.Info("Shift ended, skipping notification (muted)", "event", event, audit.Suppressed("muted"))
+			} else {
+				logger.Info("Shift ended. Sending notifier...", "event", event)
 
-			// Update state
-			currentState.WasOnCall = isOnCall
-			if err := stateManager.Save(currentState); err != nil {
-				log.Printf("Failed to save state: %v", err)
-				// Continue even if state save fails
+				shift := endedShift(ctx, logger, pdClient, n, start, scheduleID)
+				if err := notifyShift(ctx, n, event, shift); err != nil {
+					logger.Warn("Failed to send shift ended notification", "event", event, "error", err, audit.Failed)
+					// Continue even if notification fails
+				} else {
+					logger.Info("Shift ended notification sent successfully", "event", event, audit.Sent)
+					stateManager.RecordNotificationSent(currentState, backend, string(event), shift.End)
+				}
 			}
 		}
+
+		// Keep the Slack status in line with the end of the shift while it lasts
+		if currentState.WasOnCall && isOnCall && currentShiftEnd != nil && currentErr == nil {
+			refreshSlackStatus(ctx, logger, cfg, stateManager, currentState, currentShiftEnd.EndTime)
+		}
+
+		// Update state
+		currentState.WasOnCall = isOnCall
+		return nil
+	})
+}
+
+// alertUnexpectedResponders sends an alert for each unexpected responder that has not been
+// alerted about during their current on-call assignment
+func alertUnexpectedResponders(ctx context.Context, logger *slog.Logger, n notifier.Notifier, backend string, stateManager *state.Manager, currentState *state.State, unexpected []pagerduty.OnCall, muted bool) {
+	records := make([]state.OnCallRecord, len(unexpected))
+	for i, oncall := range unexpected {
+		records[i] = state.OnCallRecord{UserID: oncall.UserID, Start: oncall.Start}
+	}
+	pending := stateManager.UnalertedOnCalls(currentState, records)
+
+	for i, oncall := range unexpected {
+		record := records[i]
+		isRecord := func(r state.OnCallRecord) bool {
+			return r.UserID == record.UserID && r.Start.Equal(record.Start)
+		}
+		// Each assignment is handled once even if it appears on several schedules or levels
+		if !slices.ContainsFunc(pending, isRecord) {
+			continue
+		}
+		pending = slices.DeleteFunc(pending, isRecord)
+		event := notifier.EventUnexpectedOnCall
+		responderLogger := logger.With("event", event, "responder_id", oncall.UserID, "responder_name", oncall.UserName)
+		if muted {
+			responderLogger.Info("Unexpected responder on call, skipping alert (muted)", audit.Suppressed("muted"))
+			continue
+		}
+		responderLogger.Info("Unexpected responder on call. Sending notifier...")
+
+		shift := notifier.Shift{Start: oncall.Start, End: oncall.End, Responder: oncall.UserName}
+		if err := notifyShift(ctx, n, event, shift); err != nil {
+			responderLogger.Warn("Failed to send unexpected responder alert", "error", err, audit.Failed)
+			// Continue even if notification fails
+			continue
+		}
+		responderLogger.Info("Unexpected responder alert sent successfully", audit.Sent)
+		stateManager.RecordOnCallAlerted(currentState, record)
+		stateManager.RecordNotificationSent(currentState, backend, string(event), oncall.Start)
+	}
+}
+
+// notifyScheduleChanges sends a single notification listing the shifts that were added, removed,
+// or moved since the last snapshot. The snapshot is only replaced once the changes have been
+// reported, so a failed notification is retried on the next check.
+func notifyScheduleChanges(ctx context.Context, logger *slog.Logger, n notifier.Notifier, backend string, stateManager *state.Manager, currentState *state.State, shifts []state.ShiftWindow, until time.Time, muted bool) {
+	changes := stateManager.ShiftChanges(currentState, time.Now().UTC(), shifts)
+	if len(changes) == 0 {
+		stateManager.RecordShiftSnapshot(currentState, until, shifts)
+		return
+	}
+	event := notifier.EventScheduleChanged
+	if muted {
+		logger.Info("Upcoming shifts changed, skipping notification (muted)", "event", event, "changes", len(changes), audit.Suppressed("muted"))
+		stateManager.RecordShiftSnapshot(currentState, until, shifts)
+		return
+	}
+	logger.Info("Upcoming shifts changed. Sending notifier...", "event", event, "changes", len(changes))
+
+	var shift notifier.Shift
+	for _, change := range changes {
+		var c notifier.ShiftChange
+		switch {
+		case change.Added != nil && change.Removed != nil:
+			c = notifier.ShiftChange{Kind: notifier.ShiftMoved, Start: change.Added.Start, End: change.Added.End,
+				PreviousStart: change.Removed.Start, PreviousEnd: change.Removed.End}
+		case change.Added != nil:
+			c = notifier.ShiftChange{Kind: notifier.ShiftAdded, Start: change.Added.Start, End: change.Added.End}
+		default:
+			c = notifier.ShiftChange{Kind: notifier.ShiftRemoved, Start: change.Removed.Start, End: change.Removed.End}
+		}
+		if shift.Start.IsZero() || c.Start.Before(shift.Start) {
+			shift.Start, shift.End = c.Start, c.End
+		}
+		shift.Changes = append(shift.Changes, c)
+	}
+
+	if err := notifyShift(ctx, n, event, shift); err != nil {
+		logger.Warn("Failed to send schedule changed notification", "event", event, "error", err, audit.Failed)
+		return
+	}
+	logger.Info("Schedule changed notification sent successfully", "event", event, audit.Sent)
+	stateManager.RecordShiftSnapshot(currentState, until, shifts)
+	stateManager.RecordNotificationSent(currentState, backend, string(event), shift.Start)
+}
+
+// currentShift returns the shift that has just started with its handoffs, the schedule it
+// belongs to, and whether the user is covering it via an override. Backends that cannot make
+// use of the full shift details skip the PagerDuty lookups and get only the start time.
+func currentShift(ctx context.Context, logger *slog.Logger, pdClient pagerduty.OnCallChecker, n notifier.Notifier) (notifier.Shift, string, bool) {
+	shift := notifier.Shift{Start: time.Now().UTC()}
+	if _, ok := n.(notifier.ShiftNotifier); !ok {
+		return shift, "", false
+	}
+
+	current, err := pdClient.GetCurrentShift(ctx)
+	if err != nil {
+		logger.Warn("Failed to look up current shift", "error", err)
+		return shift, "", false
+	}
+	if current == nil {
+		return shift, "", false
+	}
+	shift.Start = current.StartTime
+	shift.End = current.EndTime
+
+	if previous, err := pdClient.PreviousResponder(ctx, current.ScheduleID, shift.Start); err != nil {
+		logger.Warn("Failed to look up previous responder", "error", err)
+	} else {
+		shift.Previous = notifierHandoff(previous)
+	}
+	if next, err := pdClient.NextResponder(ctx, current.ScheduleID, shift.Start, shift.End); err != nil {
+		logger.Warn("Failed to look up next responder", "error", err)
+	} else {
+		shift.Next = notifierHandoff(next)
+	}
+	return shift, current.ScheduleID, current.Override
+}
+
+// openIncidents summarises the incidents waiting for the user, or returns nil if they cannot be looked up
+func openIncidents(ctx context.Context, logger *slog.Logger, pdClient pagerduty.OnCallChecker) *notifier.IncidentSummary {
+	summary, err := pdClient.OpenIncidents(ctx)
+	if err != nil {
+		logger.Warn("Failed to look up open incidents", "error", err)
+		return nil
+	}
+	logger.Info("Open incidents at shift start", "count", summary.Count)
+	return &notifier.IncidentSummary{Count: summary.Count, Oldest: summary.Oldest}
+}
+
+// endedShift returns the shift that has just ended with who took over from the user. The
+// PagerDuty lookup is skipped for backends that cannot make use of the full shift details.
+func endedShift(ctx context.Context, logger *slog.Logger, pdClient pagerduty.OnCallChecker, n notifier.Notifier, start time.Time, scheduleID string) notifier.Shift {
+	shift := notifier.Shift{Start: start, End: time.Now().UTC()}
+	if _, ok := n.(notifier.ShiftNotifier); !ok || start.IsZero() {
+		return shift
+	}
+
+	if next, err := pdClient.NextResponder(ctx, scheduleID, shift.Start, shift.End); err != nil {
+		logger.Warn("Failed to look up next responder", "error", err)
+	} else {
+		shift.Next = notifierHandoff(next)
+	}
+	return shift
+}
+
+// shiftWindow returns the window of a PagerDuty shift, or nil if there is none
+func shiftWindow(shift *pagerduty.UpcomingShift) *state.ShiftWindow {
+	if shift == nil {
+		return nil
+	}
+	return &state.ShiftWindow{Start: shift.StartTime, End: shift.EndTime}
+}
+
+// notifierHandoff converts a PagerDuty handoff for notifications
+func notifierHandoff(handoff *pagerduty.Handoff) *notifier.Handoff {
+	if handoff == nil {
+		return nil
+	}
+	return &notifier.Handoff{Name: handoff.UserName, At: handoff.At}
+}
+
+// scheduleAdvanceNotifications hands the backend a reminder for each advance time that is still
+// ahead, returning those scheduled. Reminders already due are left to polling.
+// Reminders outside ADVANCE_NOTIFICATION_WINDOW are delivered when it next opens, once if
+// several fall in the same night, and not at all if that is after the shift starts.
+func scheduleAdvanceNotifications(ctx context.Context, logger *slog.Logger, scheduler notifier.ScheduledNotifier, cfg *config.Config, shiftStartTime time.Time) []state.ScheduledDelivery {
+	var scheduled []state.ScheduledDelivery
+	var lastDeliverAt time.Time
+	for _, advanceTime := range cfg.AdvanceNotificationTimes {
+		deliverAt := shiftStartTime.Add(-advanceTime)
+		if !deliverAt.After(time.Now()) {
+			continue
+		}
+		deliverAt = deferAdvanceNotification(cfg, deliverAt)
+		if !deliverAt.Before(shiftStartTime) || deliverAt.Equal(lastDeliverAt) {
+			continue
+		}
+		lastDeliverAt = deliverAt
+		id, err := scheduler.ScheduleWithEvent(ctx, notifier.EventUpcomingShift, shiftStartTime, deliverAt)
+		if err != nil {
+			logger.Warn("Failed to schedule advance notification", "event", notifier.EventUpcomingShift, "error", err, audit.Failed)
+			continue
+		}
+		logger.Info("Advance notification scheduled", "event", notifier.EventUpcomingShift, "deliver_at", deliverAt, audit.Scheduled)
+		scheduled = append(scheduled, state.ScheduledDelivery{ID: id, DeliverAt: deliverAt.UTC()})
+	}
+	return scheduled
+}
+
+// cancelAdvanceNotifications takes back scheduled reminders that are no longer wanted. A
+// reminder that cannot be cancelled is still delivered, which is logged.
+func cancelAdvanceNotifications(ctx context.Context, logger *slog.Logger, n notifier.Notifier, deliveries []state.ScheduledDelivery, reason string) {
+	scheduler, canCancel := n.(notifier.ScheduledNotifier)
+	for _, delivery := range deliveries {
+		if !canCancel || delivery.ID == "" {
+			logger.Warn("Cannot cancel scheduled advance notification", "event", notifier.EventUpcomingShift, "deliver_at", delivery.DeliverAt)
+			continue
+		}
+		if err := scheduler.CancelScheduled(ctx, delivery.ID); err != nil {
+			logger.Warn("Failed to cancel scheduled advance notification", "event", notifier.EventUpcomingShift, "deliver_at", delivery.DeliverAt, "error", err)
+			continue
+		}
+		logger.Info("Scheduled advance notification cancelled", "event", notifier.EventUpcomingShift, "deliver_at", delivery.DeliverAt, audit.Suppressed(reason))
+	}
+}
+
+// deferAdvanceNotification returns when an advance notification due at t may be delivered: t
+// itself, or the next start of ADVANCE_NOTIFICATION_WINDOW if t falls outside it
+func deferAdvanceNotification(cfg *config.Config, t time.Time) time.Time {
+	if cfg.AdvanceNotificationWindow == nil {
+		return t
+	}
+	return cfg.AdvanceNotificationWindow.Defer(t.In(cfg.ClockLocation())).In(t.Location())
+}
+
+// notifyShift sends a notification with full shift details if the backend supports them
+func notifyShift(ctx context.Context, n notifier.Notifier, event notifier.NotificationEvent, shift notifier.Shift) error {
+	if shiftNotifier, ok := n.(notifier.ShiftNotifier); ok {
+		return shiftNotifier.NotifyShift(ctx, event, shift)
+	}
+	if event == notifier.EventShiftEnded || event == notifier.EventShiftEnding {
+		return n.NotifyWithEvent(ctx, event, shift.End)
 	}
+	return n.NotifyWithEvent(ctx, event, shift.Start)
 }
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// recordingNotifier records the events it is asked to send
+type recordingNotifier struct {
+	events []notifier.NotificationEvent
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, message string) error {
+	return nil
+}
+
+func (r *recordingNotifier) NotifyWithEvent(ctx context.Context, event notifier.NotificationEvent, shiftStartTime time.Time) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestRunCheckNotifiesOnTransitions(t *testing.T) {
+	ctx := context.Background()
+	pdClient := &pagerduty.Mock{User: "PUSER01"}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	n := &recordingNotifier{}
+	cfg := &config.Config{ShiftEndNotificationsEnabled: true}
+
+	check := func() {
+		t.Helper()
+		if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+			t.Fatalf("runCheck returned error: %v", err)
+		}
+	}
+
+	check()
+	pdClient.OnCall = true
+	check()
+	check()
+	pdClient.OnCall = false
+	check()
+
+	want := []notifier.NotificationEvent{notifier.EventShiftStarted, notifier.EventShiftEnded}
+	if !slices.Equal(n.events, want) {
+		t.Fatalf("expected events %v, got %v", want, n.events)
+	}
+	if calls := pdClient.Calls("IsOnCall"); calls != 4 {
+		t.Fatalf("expected 4 on-call checks, got %d", calls)
+	}
+}
+
+func TestRunCheckRecordsShiftTimesForStatus(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	pdClient := &pagerduty.Mock{
+		User:    "PUSER01",
+		OnCall:  true,
+		Current: &pagerduty.UpcomingShift{StartTime: start, EndTime: start.Add(8 * time.Hour)},
+	}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	cfg := &config.Config{HTTPListenAddr: ":8080"}
+
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, &recordingNotifier{}, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	loaded, err := stateManager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.CurrentShiftEnd == nil || !loaded.CurrentShiftEnd.Equal(start.Add(8*time.Hour)) {
+		t.Fatalf("expected the current shift end to be recorded, got %v", loaded.CurrentShiftEnd)
+	}
+
+	pdClient.OnCall = false
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, &recordingNotifier{}, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	if loaded, _ = stateManager.Load(); loaded.CurrentShiftEnd != nil {
+		t.Fatalf("expected the current shift end to be cleared off call, got %v", loaded.CurrentShiftEnd)
+	}
+}
+
+func TestRunCheckSendsAdvanceNotificationOnce(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(30 * time.Minute)
+	pdClient := &pagerduty.Mock{
+		User:   "PUSER01",
+		Shifts: []pagerduty.UpcomingShift{{StartTime: start, EndTime: start.Add(8 * time.Hour)}},
+	}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	n := &recordingNotifier{}
+	cfg := &config.Config{AdvanceNotificationTimes: []time.Duration{time.Hour}}
+
+	for range 2 {
+		if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+			t.Fatalf("runCheck returned error: %v", err)
+		}
+	}
+
+	want := []notifier.NotificationEvent{notifier.EventUpcomingShift}
+	if !slices.Equal(n.events, want) {
+		t.Fatalf("expected events %v, got %v", want, n.events)
+	}
+}
+
+// schedulingNotifier records the reminders it is asked to schedule and cancel
+type schedulingNotifier struct {
+	recordingNotifier
+	scheduled []time.Time
+	cancelled []string
+}
+
+func (s *schedulingNotifier) ScheduleWithEvent(ctx context.Context, event notifier.NotificationEvent, shiftStartTime, deliverAt time.Time) (string, error) {
+	s.scheduled = append(s.scheduled, deliverAt)
+	return fmt.Sprintf("msg%d", len(s.scheduled)), nil
+}
+
+func (s *schedulingNotifier) CancelScheduled(ctx context.Context, id string) error {
+	s.cancelled = append(s.cancelled, id)
+	return nil
+}
+
+func (s *schedulingNotifier) MaxScheduleDelay() time.Duration {
+	return 72 * time.Hour
+}
+
+func TestRunCheckCancelsScheduledAdvanceNotifications(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(5 * time.Hour).Truncate(time.Second)
+	pdClient := &pagerduty.Mock{
+		User:   "PUSER01",
+		Shifts: []pagerduty.UpcomingShift{{StartTime: start, EndTime: start.Add(8 * time.Hour)}},
+	}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	n := &schedulingNotifier{}
+	cfg := &config.Config{AdvanceNotificationTimes: []time.Duration{time.Hour}, ScheduledAdvanceNotifications: true}
+
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	if len(n.scheduled) != 1 {
+		t.Fatalf("expected one reminder to be scheduled, got %v", n.scheduled)
+	}
+
+	// The shift moving takes the reminder back and schedules one for the new start
+	moved := start.Add(time.Hour)
+	pdClient.Shifts = []pagerduty.UpcomingShift{{StartTime: moved, EndTime: moved.Add(8 * time.Hour)}}
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	if !slices.Equal(n.cancelled, []string{"msg1"}) || len(n.scheduled) != 2 {
+		t.Fatalf("expected the moved shift to be rescheduled, got scheduled %v and cancelled %v", n.scheduled, n.cancelled)
+	}
+
+	// Muting takes it back too, and nothing is scheduled while muted
+	if err := stateManager.Update(func(currentState *state.State) error {
+		stateManager.Mute(currentState, 24*time.Hour)
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	if !slices.Equal(n.cancelled, []string{"msg1", "msg2"}) || len(n.scheduled) != 2 {
+		t.Fatalf("expected muting to cancel the reminder, got scheduled %v and cancelled %v", n.scheduled, n.cancelled)
+	}
+	if len(n.events) != 0 {
+		t.Fatalf("expected no notifications to be sent, got %v", n.events)
+	}
+}
+
+func TestRunCheckAlertsCoverageGapOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	pdClient := &pagerduty.Mock{User: "PUSER01", Gaps: []pagerduty.Gap{{Start: now, End: now.Add(24 * time.Hour)}}}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	n := &recordingNotifier{}
+	cfg := &config.Config{CoverageGapLookahead: 24 * time.Hour}
+
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+	// The next check sees the same ongoing gap clamped to its own lookahead window
+	later := now.Add(time.Minute)
+	pdClient.Gaps = []pagerduty.Gap{{Start: later, End: later.Add(24 * time.Hour)}}
+	if _, err := runCheck(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+		t.Fatalf("runCheck returned error: %v", err)
+	}
+
+	want := []notifier.NotificationEvent{notifier.EventCoverageGap}
+	if !slices.Equal(n.events, want) {
+		t.Fatalf("expected events %v, got %v", want, n.events)
+	}
+}
+
+func TestCreateNotifierPrefersUserWebhookTarget(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/global" || r.URL.Path == "/user" {
+			paths = append(paths, r.URL.Path)
+			return
+		}
+		// PagerDuty lookups of the user's name
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		NotificationBackend: config.BackendWebhook,
+		MessageLocale:       notifier.DefaultLocale,
+		WebhookMethod:       http.MethodPost,
+		WebhookURLs:         notifier.EventOverrides{notifier.EventShiftStarted: server.URL + "/global"},
+		PagerDutyAPIURL:     server.URL,
+	}
+	route := config.UserRoute{UserID: "PUSER01", APIToken: "token", Target: server.URL + "/user"}
+	pdClient, err := newPagerDutyClient(cfg, route)
+	if err != nil {
+		t.Fatalf("newPagerDutyClient returned error: %v", err)
+	}
+	n, err := createNotifier(cfg, pdClient, route)
+	if err != nil {
+		t.Fatalf("createNotifier returned error: %v", err)
+	}
+	if err := n.NotifyWithEvent(context.Background(), notifier.EventShiftStarted, time.Now()); err != nil {
+		t.Fatalf("NotifyWithEvent returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(paths, []string{"/user"}) {
+		t.Fatalf("expected the user's target to receive the notification, got %v", paths)
+	}
+}
+
+func TestSendHeartbeatWaitsForEveryUser(t *testing.T) {
+	var mu sync.Mutex
+	pings := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pings++
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{HeartbeatURL: server.URL}
+	var monitors []*monitor
+	for _, userID := range []string{"PUSER01", "PUSER02"} {
+		monitors = append(monitors, &monitor{
+			route:  config.UserRoute{UserID: userID},
+			health: health.NewTracker(health.DefaultUnhealthyThreshold),
+			cfg:    cfg,
+		})
+	}
+	heartbeats := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return pings
+	}
+
+	// The second user has not been checked yet
+	monitors[0].health.RecordSuccess()
+	sendHeartbeat(context.Background(), slog.Default(), cfg, monitors)
+	if got := heartbeats(); got != 0 {
+		t.Fatalf("expected no heartbeat before every user was checked, got %d", got)
+	}
+
+	monitors[1].health.RecordFailure(errors.New("PagerDuty unavailable"))
+	sendHeartbeat(context.Background(), slog.Default(), cfg, monitors)
+	if got := heartbeats(); got != 0 {
+		t.Fatalf("expected no heartbeat while a user's check is failing, got %d", got)
+	}
+
+	monitors[1].health.RecordSuccess()
+	sendHeartbeat(context.Background(), slog.Default(), cfg, monitors)
+	if got := heartbeats(); got != 1 {
+		t.Fatalf("expected a heartbeat once every check succeeded, got %d", got)
+	}
+}
+
+func TestDegradedAlertDue(t *testing.T) {
+	cfg := &config.Config{DegradedAlertThreshold: 3, DegradedAlertInterval: time.Hour}
+	now := time.Now()
+
+	if degradedAlertDue(cfg, 2, time.Time{}, now) {
+		t.Fatalf("expected no alert below the threshold")
+	}
+	if !degradedAlertDue(cfg, 3, time.Time{}, now) {
+		t.Fatalf("expected an alert at the threshold")
+	}
+	if degradedAlertDue(cfg, 4, now.Add(-30*time.Minute), now) {
+		t.Fatalf("expected no repeat within the interval")
+	}
+	if !degradedAlertDue(cfg, 10, now.Add(-time.Hour), now) {
+		t.Fatalf("expected a repeat once the interval passed")
+	}
+	// A successful check resets the failures and the last alert, so the next run of failures
+	// alerts as soon as it reaches the threshold again
+	if degradedAlertDue(cfg, 0, time.Time{}, now) || !degradedAlertDue(cfg, 3, time.Time{}, now.Add(time.Minute)) {
+		t.Fatalf("expected the next run of failures to alert at the threshold after a success")
+	}
+	if degradedAlertDue(&config.Config{}, 100, time.Time{}, now) {
+		t.Fatalf("expected no alerts when DEGRADED_ALERT_THRESHOLD is unset")
+	}
+}
+
+func TestStateRoute(t *testing.T) {
+	cfg := &config.Config{Users: []config.UserRoute{{UserID: "PAAAAAA"}, {UserID: "PBBBBBB", Account: "acme"}}}
+
+	if _, err := stateRoute(cfg, ""); err == nil {
+		t.Fatalf("expected --user to be required with several users")
+	}
+	for _, user := range []string{"PBBBBBB", "acme"} {
+		route, err := stateRoute(cfg, user)
+		if err != nil || route.UserID != "PBBBBBB" {
+			t.Fatalf("expected %s to select PBBBBBB, got %+v (%v)", user, route, err)
+		}
+	}
+	if _, err := stateRoute(cfg, "PCCCCCC"); err == nil {
+		t.Fatalf("expected an error for an unmonitored user")
+	}
+
+	single := &config.Config{Users: []config.UserRoute{{}}}
+	if _, err := stateRoute(single, ""); err != nil {
+		t.Fatalf("expected the only user to be selected, got %v", err)
+	}
+}
+
+func TestRestartOnlySettings(t *testing.T) {
+	cfg := &config.Config{CheckInterval: time.Minute, StateFilePath: "/data/state.json"}
+	reloaded := &config.Config{CheckInterval: 2 * time.Minute, StateFilePath: "/data/other.json", NtfyControlTopic: "control"}
+
+	changed := restartOnlySettings(cfg, reloaded)
+	if !slices.Equal(changed, []string{"STATE_FILE_PATH", "NTFY_CONTROL_TOPIC"}) {
+		t.Fatalf("expected STATE_FILE_PATH and NTFY_CONTROL_TOPIC to need a restart, got %v", changed)
+	}
+}
+
+func TestJitterStaysWithinSpread(t *testing.T) {
+	if got := jitter(time.Minute, 0); got != time.Minute {
+		t.Fatalf("expected no jitter without a spread, got %v", got)
+	}
+	for range 100 {
+		if got := jitter(time.Minute, 10*time.Second); got < 50*time.Second || got > 70*time.Second {
+			t.Fatalf("expected an interval between 50s and 70s, got %v", got)
+		}
+	}
+}
+
+func TestReconcileMissedShiftsSendsCatchUp(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-3 * time.Hour)
+	pdClient := &pagerduty.Mock{
+		User: "PUSER01",
+		Shifts: []pagerduty.UpcomingShift{
+			{StartTime: start, EndTime: start.Add(time.Hour)},
+			{StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)},
+		},
+	}
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	lastCheck := time.Now().Add(-4 * time.Hour)
+	if err := stateManager.Save(&state.State{LastCheckAt: &lastCheck}); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+	n := &recordingNotifier{}
+	cfg := &config.Config{CheckInterval: time.Minute, CatchUpNotifications: true}
+
+	for range 2 {
+		if err := reconcileMissedShifts(ctx, slog.Default(), pdClient, stateManager, n, cfg); err != nil {
+			t.Fatalf("reconcileMissedShifts returned error: %v", err)
+		}
+	}
+
+	// Only the shift that is already over is caught up, and only once
+	want := []notifier.NotificationEvent{notifier.EventShiftEnded}
+	if !slices.Equal(n.events, want) {
+		t.Fatalf("expected events %v, got %v", want, n.events)
+	}
+}
+
+func TestDailySummaryTimes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	at := config.TimeOfDay{Hour: 8, Minute: 30}
+
+	// 08:00 UTC is 09:00 in Berlin, after today's summary
+	now := time.Date(2030, time.March, 5, 8, 0, 0, 0, time.UTC)
+	last, next := dailySummaryTimes(at, berlin, now)
+	if want := time.Date(2030, time.March, 5, 8, 30, 0, 0, berlin); !last.Equal(want) {
+		t.Fatalf("expected last summary at %v, got %v", want, last)
+	}
+	if want := time.Date(2030, time.March, 6, 8, 30, 0, 0, berlin); !next.Equal(want) {
+		t.Fatalf("expected next summary at %v, got %v", want, next)
+	}
+
+	// In UTC today's summary is still ahead
+	last, next = dailySummaryTimes(at, time.UTC, now)
+	if want := time.Date(2030, time.March, 4, 8, 30, 0, 0, time.UTC); !last.Equal(want) {
+		t.Fatalf("expected last summary at %v, got %v", want, last)
+	}
+	if want := time.Date(2030, time.March, 5, 8, 30, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("expected next summary at %v, got %v", want, next)
+	}
+}
+
+func TestDeferAdvanceNotification(t *testing.T) {
+	day := time.Date(2030, time.March, 5, 0, 0, 0, 0, time.UTC)
+	cfg := &config.Config{AdvanceNotificationWindow: &config.DailyWindow{
+		Start: config.TimeOfDay{Hour: 8},
+		End:   config.TimeOfDay{Hour: 22},
+	}}
+
+	for _, tc := range []struct {
+		due, want time.Duration
+	}{
+		{due: 12 * time.Hour, want: 12 * time.Hour},
+		{due: 3 * time.Hour, want: 8 * time.Hour},
+		{due: 23 * time.Hour, want: 32 * time.Hour},
+		{due: 22 * time.Hour, want: 32 * time.Hour},
+	} {
+		if got, want := deferAdvanceNotification(cfg, day.Add(tc.due)), day.Add(tc.want); !got.Equal(want) {
+			t.Errorf("reminder due at %v: expected delivery at %v, got %v", day.Add(tc.due), want, got)
+		}
+	}
+
+	// A window that wraps past midnight only holds reminders back during the day
+	cfg.AdvanceNotificationWindow = &config.DailyWindow{Start: config.TimeOfDay{Hour: 20}, End: config.TimeOfDay{Hour: 6}}
+	if got := deferAdvanceNotification(cfg, day.Add(2*time.Hour)); !got.Equal(day.Add(2 * time.Hour)) {
+		t.Errorf("expected a reminder within a wrapping window to be delivered on time, got %v", got)
+	}
+	if got, want := deferAdvanceNotification(cfg, day.Add(12*time.Hour)), day.Add(20*time.Hour); !got.Equal(want) {
+		t.Errorf("expected a reminder outside a wrapping window to be delivered at %v, got %v", want, got)
+	}
+}
+
+func TestNextShiftEvent(t *testing.T) {
+	start := time.Now().Add(3 * time.Hour)
+	upcoming := &pagerduty.UpcomingShift{StartTime: start, EndTime: start.Add(8 * time.Hour)}
+	cfg := &config.Config{AdvanceNotificationTimes: []time.Duration{24 * time.Hour, 2 * time.Hour}}
+
+	// The 24h reminder is already past, so the 2h one is next
+	if got, want := nextShiftEvent(cfg, upcoming, nil), start.Add(-2*time.Hour+shiftEventDelay); !got.Equal(want) {
+		t.Fatalf("expected next event at %v, got %v", want, got)
+	}
+
+	current := &pagerduty.UpcomingShift{StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(30 * time.Minute)}
+	if got, want := nextShiftEvent(cfg, upcoming, current), current.EndTime.Add(shiftEventDelay); !got.Equal(want) {
+		t.Fatalf("expected next event at the current shift end %v, got %v", want, got)
+	}
+
+	if got := nextShiftEvent(&config.Config{}, nil, nil); !got.IsZero() {
+		t.Fatalf("expected no event without known shifts, got %v", got)
+	}
+}
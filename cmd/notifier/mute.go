@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+func newMuteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mute <duration>",
+		Short: "Mute notifications for a duration (e.g., 30m, 4h)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			duration, err := time.ParseDuration(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid duration %q: %w", args[0], err)
+			}
+			if duration <= 0 {
+				return fmt.Errorf("duration must be greater than 0")
+			}
+			return runMute("mute", duration)
+		},
+	}
+}
+
+func newUnmuteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unmute",
+		Short: "Unmute notifications",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMute("unmute", 0)
+		},
+	}
+}
+
+// runMute mutes or unmutes notifications by updating the persisted state
+// A running notifier picks up the change on its next check
+func runMute(command string, duration time.Duration) error {
+	return updateAllStates(command, func(stateManager *state.Manager, currentState *state.State, route config.UserRoute) {
+		if command == "unmute" {
+			stateManager.Unmute(currentState)
+			slog.Info("Notifications unmuted", "user_id", route.UserID)
+			return
+		}
+		stateManager.Mute(currentState, duration)
+		slog.Info("Notifications muted", "user_id", route.UserID, "until", currentState.MutedUntil.Format(time.RFC3339))
+	})
+}
+
+// updateAllStates applies update to the persisted state of every monitored user
+func updateAllStates(command string, update func(stateManager *state.Manager, currentState *state.State, route config.UserRoute)) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.StateBackend == config.StateBackendMemory {
+		// The state only exists inside the running notifier
+		return fmt.Errorf("%s is not supported with STATE_BACKEND=memory", command)
+	}
+
+	newStateManager, err := openStateBackend(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Apply to every monitored user
+	for _, route := range cfg.Users {
+		stateManager := newStateManager(route)
+		err := stateManager.Update(func(currentState *state.State) error {
+			update(stateManager, currentState, route)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("user %s: %w", route.UserID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+func newPauseCommand() *cobra.Command {
+	var from, until string
+	cmd := &cobra.Command{
+		Use:   "pause --until <date>",
+		Short: "Pause notifications for a period, e.g. while someone else covers your leave",
+		Long: "Pause notifications for a period, e.g. while someone else covers your leave.\n\n" +
+			"Dates are YYYY-MM-DD, meaning midnight at the start of that day in MESSAGE_TIMEZONE (or UTC),\n" +
+			"or RFC 3339 times. PagerDuty is still checked while paused, so state stays current.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPause(from, until)
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "Start of the pause (default: now)")
+	cmd.Flags().StringVar(&until, "until", "", "End of the pause (required)")
+	_ = cmd.MarkFlagRequired("until")
+	return cmd
+}
+
+func newResumeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Clear every pause, including ones that have not started yet",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateAllStates("resume", func(stateManager *state.Manager, currentState *state.State, route config.UserRoute) {
+				stateManager.Resume(currentState)
+				slog.Info("Notifications resumed", "user_id", route.UserID)
+			})
+		},
+	}
+}
+
+// runPause records a pause for every monitored user
+func runPause(fromStr, untilStr string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	from := time.Now()
+	if fromStr != "" {
+		if from, err = parsePauseTime(fromStr, cfg.ClockLocation()); err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+	}
+	until, err := parsePauseTime(untilStr, cfg.ClockLocation())
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	if !until.After(time.Now()) {
+		return fmt.Errorf("--until must be in the future")
+	}
+	if !until.After(from) {
+		return fmt.Errorf("--until must be after --from")
+	}
+
+	return updateAllStates("pause", func(stateManager *state.Manager, currentState *state.State, route config.UserRoute) {
+		stateManager.Pause(currentState, from, until)
+		slog.Info("Notifications paused", "user_id", route.UserID, "from", from.Format(time.RFC3339), "until", until.Format(time.RFC3339))
+	})
+}
+
+// parsePauseTime parses a YYYY-MM-DD date as midnight in location, or an RFC 3339 time
+func parsePauseTime(raw string, location *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, raw, location); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date or RFC 3339 time", raw)
+	}
+	return t, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/audit"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// reconcileMissedShifts looks for shifts that started and ended since the last check, which
+// the next check cannot notice since the user is off call on both sides of them. They are
+// logged, and with CATCH_UP_NOTIFICATIONS a shift_ended notification is sent for each.
+// Shifts still in progress are left to the next check, which sees the transition itself.
+func reconcileMissedShifts(ctx context.Context, logger *slog.Logger, pdClient pagerduty.OnCallChecker, stateManager *state.Manager, n notifier.Notifier, cfg *config.Config) error {
+	currentState, err := stateManager.Load()
+	if err != nil {
+		return err
+	}
+	if currentState.LastCheckAt == nil {
+		return nil
+	}
+	since := *currentState.LastCheckAt
+	now := time.Now()
+	downtime := now.Sub(since)
+	if downtime <= cfg.PollInterval() {
+		return nil
+	}
+	logger.Info("Looking for shifts missed since the last check", "downtime", downtime.Round(time.Second))
+	// PagerDuty only lists on-calls over a limited window
+	if earliest := now.AddDate(0, 0, -pagerduty.MaxShiftListingDays); since.Before(earliest) {
+		since = earliest
+	}
+
+	shifts, err := pdClient.ListShifts(ctx, since, now)
+	if err != nil {
+		return fmt.Errorf("failed to list shifts since the last check: %w", err)
+	}
+	var missed []pagerduty.UpcomingShift
+	for _, shift := range shifts {
+		if shift.StartTime.After(since) && !shift.EndTime.After(now) {
+			missed = append(missed, shift)
+		}
+	}
+	if len(missed) == 0 {
+		return nil
+	}
+
+	backend := string(cfg.NotificationBackend)
+	return stateManager.Update(func(currentState *state.State) error {
+		// Shifts up to now are accounted for, so a restart does not report them again
+		stateManager.RecordCheck(currentState)
+		muted := stateManager.IsMuted(currentState) || stateManager.ActivePause(currentState) != nil
+		for _, shift := range missed {
+			event := notifier.EventShiftEnded
+			shiftLogger := logger.With("event", event, "shift_start", shift.StartTime, "shift_end", shift.EndTime)
+			shiftLogger.Info("Missed shift while the notifier was not running")
+			if !cfg.CatchUpNotifications {
+				continue
+			}
+			if muted {
+				shiftLogger.Info("Skipping catch-up notification (muted)", audit.Suppressed("muted"))
+				continue
+			}
+			if err := notifyShift(ctx, n, event, notifier.Shift{Start: shift.StartTime, End: shift.EndTime}); err != nil {
+				shiftLogger.Warn("Failed to send catch-up notification", "error", err, audit.Failed)
+				continue
+			}
+			shiftLogger.Info("Catch-up notification sent successfully", audit.Sent)
+			stateManager.RecordNotificationSent(currentState, backend, string(event), shift.StartTime)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// fileWatchInterval is how often CONFIG_FILE and the other files the configuration is read
+// from are checked for changes
+const fileWatchInterval = 10 * time.Second
+
+// current returns the configuration, PagerDuty client, and notifier the next check should use
+func (m *monitor) current() (*config.Config, *pagerduty.Client, notifier.Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg, m.pdClient, m.notifier
+}
+
+// replace swaps in the components built from a reloaded configuration
+func (m *monitor) replace(cfg *config.Config, pdClient *pagerduty.Client, n notifier.Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg, m.pdClient, m.notifier = cfg, pdClient, n
+}
+
+// reloadMonitors loads the configuration of every profile again and rebuilds each monitor's
+// PagerDuty client and notifier from it. The monitors are in the order of cfgs and their users.
+// State is kept, and either every monitor is updated or, if anything fails, none is. Settings
+// that are only read at startup are reported as needing a restart.
+func reloadMonitors(cfgs []*config.Config, monitors []*monitor) ([]*config.Config, error) {
+	newCfgs, err := config.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Each monitor owns a user's state, so the set of profiles and users cannot change
+	sameProfile := func(a, b *config.Config) bool { return a.Profile == b.Profile }
+	if !slices.EqualFunc(cfgs, newCfgs, sameProfile) {
+		return nil, fmt.Errorf("the profiles changed; restart to apply")
+	}
+	sameUser := func(a, b config.UserRoute) bool { return a.UserID == b.UserID && a.Account == b.Account }
+	type userConfig struct {
+		cfg   *config.Config
+		route config.UserRoute
+	}
+	var users []userConfig
+	for i, cfg := range cfgs {
+		newCfg := newCfgs[i]
+		if !slices.EqualFunc(cfg.Users, newCfg.Users, sameUser) {
+			return nil, fmt.Errorf("the monitored users changed; restart to apply")
+		}
+		for _, setting := range restartOnlySettings(cfg, newCfg) {
+			if newCfg.Profile != "" {
+				slog.Warn("Configuration reload: setting changed; restart to apply", "setting", setting, "profile", newCfg.Profile)
+			} else {
+				slog.Warn("Configuration reload: setting changed; restart to apply", "setting", setting)
+			}
+		}
+		for _, route := range newCfg.Users {
+			users = append(users, userConfig{cfg: newCfg, route: route})
+		}
+	}
+
+	type components struct {
+		pdClient *pagerduty.Client
+		notifier notifier.Notifier
+	}
+	rebuilt := make([]components, len(monitors))
+	for i, m := range monitors {
+		route := users[i].route
+		// Keep the user resolved from the API token at startup
+		route.UserID = m.route.UserID
+		_, pdClient, n, err := newMonitorComponents(users[i].cfg, route)
+		if err != nil {
+			return nil, err
+		}
+		rebuilt[i] = components{pdClient: pdClient, notifier: n}
+	}
+	for i, m := range monitors {
+		m.replace(users[i].cfg, rebuilt[i].pdClient, rebuilt[i].notifier)
+	}
+	return newCfgs, nil
+}
+
+// restartOnlySettings lists the settings that differ between cfg and newCfg but are only
+// applied at startup: the state backend, HTTP API, and ntfy control topic
+func restartOnlySettings(cfg, newCfg *config.Config) []string {
+	var changed []string
+	check := func(name string, differs bool) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	check("STATE_BACKEND", cfg.StateBackend != newCfg.StateBackend)
+	check("STATE_FILE_PATH", cfg.StateFilePath != newCfg.StateFilePath)
+	check("STATE_POSTGRES_URL", cfg.StatePostgresURL != newCfg.StatePostgresURL)
+	check("STATE_POSTGRES_KEY", cfg.StatePostgresKey != newCfg.StatePostgresKey)
+	check("STATE_S3_*", cfg.StateS3Bucket != newCfg.StateS3Bucket || cfg.StateS3Key != newCfg.StateS3Key ||
+		cfg.StateS3Region != newCfg.StateS3Region || cfg.StateS3Endpoint != newCfg.StateS3Endpoint ||
+		cfg.StateS3PathStyle != newCfg.StateS3PathStyle)
+	check("STATE_ENCRYPTION_KEY", !slices.Equal(cfg.StateEncryptionKey, newCfg.StateEncryptionKey))
+	check("HTTP_LISTEN_ADDR", cfg.HTTPListenAddr != newCfg.HTTPListenAddr)
+	check("DEBUG_LISTEN_ADDR", cfg.DebugListenAddr != newCfg.DebugListenAddr)
+	check("METRICS_LISTEN_ADDR", cfg.MetricsListenAddr != newCfg.MetricsListenAddr)
+	check("HTTP_API_TOKEN", cfg.HTTPAPIToken != newCfg.HTTPAPIToken)
+	check("HTTP_ACTION_TOKEN", cfg.HTTPActionToken != newCfg.HTTPActionToken)
+	check("HTTP_API_ALLOW_UNAUTHENTICATED", cfg.HTTPAllowUnauthenticated != newCfg.HTTPAllowUnauthenticated)
+	check("CALENDAR_TOKEN", cfg.CalendarToken != newCfg.CalendarToken)
+	check("CALENDAR_WEEKS", cfg.CalendarWeeks != newCfg.CalendarWeeks)
+	check("PD_WEBHOOK_SECRET", cfg.PagerDutyWebhookSecret != newCfg.PagerDutyWebhookSecret)
+	check("NTFY_CONTROL_TOPIC", cfg.NtfyControlTopic != newCfg.NtfyControlTopic)
+	check("NTFY_CONTROL_SECRET", cfg.NtfyControlSecret != newCfg.NtfyControlSecret)
+	check("SENTRY_DSN", cfg.SentryDSN != newCfg.SentryDSN)
+	check("SENTRY_ENVIRONMENT", cfg.SentryEnvironment != newCfg.SentryEnvironment)
+	check("AUDIT_LOG_*", cfg.AuditLogPath != newCfg.AuditLogPath || cfg.AuditLogMaxSize != newCfg.AuditLogMaxSize ||
+		cfg.AuditLogMaxBackups != newCfg.AuditLogMaxBackups)
+	return changed
+}
+
+// watchFiles signals reload whenever the content of one of paths changes, until ctx is
+// cancelled. Contents are compared rather than modification times, as the kubelet updates
+// mounted ConfigMaps and Secrets by swapping a symlink to a new directory.
+func watchFiles(ctx context.Context, paths []string, reload chan<- struct{}) {
+	sums := make(map[string][sha256.Size]byte, len(paths))
+	for _, path := range paths {
+		if data, err := os.ReadFile(path); err == nil {
+			sums[path] = sha256.Sum256(data)
+		}
+	}
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed := false
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				// Mid-rotation or removed; keep the last known content
+				continue
+			}
+			if sum := sha256.Sum256(data); sum != sums[path] {
+				slog.Info("Configuration file changed", "path", path)
+				sums[path] = sum
+				changed = true
+			}
+		}
+		if changed {
+			select {
+			case reload <- struct{}{}:
+			default: // A reload is already pending
+			}
+		}
+	}
+}
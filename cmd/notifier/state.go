@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+func newStateCommand() *cobra.Command {
+	var user, file string
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect or replace a monitored user's persisted state",
+	}
+	cmd.PersistentFlags().StringVar(&user, "user", "", "User ID or PD_ACCOUNTS name whose state to use (required when monitoring several users)")
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Replace the persisted state with the JSON read from --file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runState("import", user, file)
+		},
+	}
+	importCmd.Flags().StringVar(&file, "file", "-", "File to read the state from, or - for stdin")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "dump",
+			Short: "Print the persisted state as JSON",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runState("dump", user, "")
+			},
+		},
+		importCmd,
+	)
+	return cmd
+}
+
+// runState prints or replaces a monitored user's persisted state, so it can be inspected,
+// reset, or copied to another backend. A running notifier picks up imported state on its
+// next check.
+func runState(command, user, file string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.StateBackend == config.StateBackendMemory {
+		// The state only exists inside the running notifier
+		return fmt.Errorf("state %s is not supported with STATE_BACKEND=memory", command)
+	}
+	route, err := stateRoute(cfg, user)
+	if err != nil {
+		return err
+	}
+
+	newStateManager, err := openStateBackend(cfg)
+	if err != nil {
+		return err
+	}
+	stateManager := newStateManager(route)
+
+	if command == "dump" {
+		currentState, err := stateManager.Load()
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(currentState)
+	}
+
+	var data []byte
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	// Reject typos in field names rather than silently dropping them
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var imported state.State
+	if err := decoder.Decode(&imported); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	err = stateManager.Update(func(currentState *state.State) error {
+		*currentState = imported
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("State imported")
+	return nil
+}
+
+// stateRoute returns the monitored user whose user ID or account name is user, or the only
+// monitored user if user is empty
+func stateRoute(cfg *config.Config, user string) (config.UserRoute, error) {
+	if user == "" {
+		if len(cfg.Users) > 1 {
+			return config.UserRoute{}, fmt.Errorf("--user is required when monitoring several users")
+		}
+		return cfg.Users[0], nil
+	}
+	for _, route := range cfg.Users {
+		if route.UserID == user || route.Account == user {
+			return route, nil
+		}
+	}
+	return config.UserRoute{}, fmt.Errorf("%s is not a monitored user", user)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/audit"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// dailySummaryTimes returns when the daily summary was last due at or before now, and when it
+// is next due, reading the time of day in location
+func dailySummaryTimes(at config.TimeOfDay, location *time.Location, now time.Time) (time.Time, time.Time) {
+	today := now.In(location)
+	due := at.On(today)
+	if due.After(now) {
+		return at.On(today.AddDate(0, 0, -1)), due
+	}
+	return due, at.On(today.AddDate(0, 0, 1))
+}
+
+// dailySummaryGrace is how late a daily summary may be sent, allowing for checks that are
+// further apart than usual
+func dailySummaryGrace(cfg *config.Config) time.Duration {
+	return max(time.Hour, 2*cfg.PollInterval())
+}
+
+// sendDailySummary sends the daily summary if it is due: the current shift if the user is on
+// call, or else the next one within the week PagerDuty is asked about
+func sendDailySummary(ctx context.Context, logger *slog.Logger, n notifier.Notifier, backend string, stateManager *state.Manager, currentState *state.State, cfg *config.Config, isOnCall bool, upcoming, current *pagerduty.UpcomingShift, muted bool) {
+	due, _ := dailySummaryTimes(*cfg.DailySummaryTime, cfg.ClockLocation(), time.Now())
+	if !stateManager.ShouldSendDailySummary(currentState, due, dailySummaryGrace(cfg)) {
+		return
+	}
+	event := notifier.EventDailySummary
+	if muted {
+		logger.Info("Daily summary due, skipping notification (muted)", "event", event, "due", due, audit.Suppressed("muted"))
+		return
+	}
+	logger.Info("Daily summary due. Sending notifier...", "event", event, "due", due)
+
+	var shift notifier.Shift
+	switch {
+	case isOnCall && current != nil:
+		shift = notifier.Shift{Start: current.StartTime, End: current.EndTime}
+	case isOnCall && currentState.CurrentShiftStart != nil:
+		shift = notifier.Shift{Start: *currentState.CurrentShiftStart}
+	case isOnCall:
+		shift = notifier.Shift{Start: time.Now().UTC()}
+	case upcoming != nil:
+		shift = notifier.Shift{Start: upcoming.StartTime, End: upcoming.EndTime}
+	}
+
+	if err := notifyShift(ctx, n, event, shift); err != nil {
+		logger.Warn("Failed to send daily summary", "event", event, "error", err, audit.Failed)
+		return
+	}
+	logger.Info("Daily summary sent successfully", "event", event, audit.Sent)
+	stateManager.RecordDailySummarySent(currentState, due)
+	stateManager.RecordNotificationSent(currentState, backend, string(event), shift.Start)
+}
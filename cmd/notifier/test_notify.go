@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+)
+
+// defaultTestShiftLead is how far in the future the sample shift starts for upcoming_shift test notifications
+// when no advance notification time is configured
+const defaultTestShiftLead = 2 * time.Hour
+
+func newTestNotifyCommand() *cobra.Command {
+	var eventName string
+	cmd := &cobra.Command{
+		Use:   "test-notify",
+		Short: "Send a sample notification through the configured backend",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTestNotify(eventName)
+		},
+	}
+	cmd.Flags().StringVar(&eventName, "event", string(notifier.EventShiftStarted), "Event to send (shift_started, upcoming_shift, shift_ended, or all)")
+	return cmd
+}
+
+// runTestNotify sends a sample notification through the configured backend and returns
+func runTestNotify(eventName string) error {
+	var events []notifier.NotificationEvent
+	if eventName == "all" {
+		events = notifier.Events()
+	} else {
+		event, err := notifier.ParseEvent(eventName)
+		if err != nil {
+			return fmt.Errorf("%w (valid events: %s, all)", err, strings.Join(eventNames(), ", "))
+		}
+		events = []notifier.NotificationEvent{event}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	monitors, err := newMonitors(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create notifier: %w", err)
+	}
+
+	ctx := context.Background()
+	for _, m := range monitors {
+		for _, event := range events {
+			slog.Info("Sending test notification...", "event", event, "user_id", m.route.UserID, "backend", cfg.NotificationBackend)
+			if err := sendTestNotification(ctx, cfg, m.notifier, event); err != nil {
+				return fmt.Errorf("failed to send test %s notification for %s: %w", event, m.route.UserID, err)
+			}
+			slog.Info("Test notification sent successfully", "event", event, "user_id", m.route.UserID)
+		}
+	}
+
+	return nil
+}
+
+// sendTestNotification sends a sample notification for event, about a shift starting now or,
+// for reminders, as far ahead as the reminder would be sent
+func sendTestNotification(ctx context.Context, cfg *config.Config, n notifier.Notifier, event notifier.NotificationEvent) error {
+	shiftStartTime := time.Now().UTC()
+	if event == notifier.EventUpcomingShift {
+		lead := defaultTestShiftLead
+		if len(cfg.AdvanceNotificationTimes) > 0 {
+			lead = cfg.AdvanceNotificationTimes[0]
+		}
+		shiftStartTime = shiftStartTime.Add(lead)
+	}
+	if event == notifier.EventShiftEnding {
+		// The reminder is about the shift end, which NotifyWithEvent takes in place of the start
+		lead := cfg.ShiftEndingNotificationTime
+		if lead <= 0 {
+			lead = defaultTestShiftLead
+		}
+		shiftStartTime = shiftStartTime.Add(lead)
+	}
+	return n.NotifyWithEvent(ctx, event, shiftStartTime)
+}
+
+// eventNames returns the names of all notification events
+func eventNames() []string {
+	var names []string
+	for _, event := range notifier.Events() {
+		names = append(names, string(event))
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/config"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+func newValidateCommand() *cobra.Command {
+	var online bool
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration and list every problem found",
+		Long:  "Checks the configuration, lists every problem found and exits non-zero if there are any.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidate(online)
+		},
+	}
+	cmd.Flags().BoolVar(&online, "online", false, "Also check the PagerDuty token, IDs and the state backend")
+	return cmd
+}
+
+// runValidate checks the configuration of every profile without starting the notifier,
+// printing every problem found. With online it also checks that PagerDuty and the state
+// backend are reachable.
+func runValidate(online bool) error {
+	cfgs, err := config.LoadAll()
+	if err == nil {
+		var errs []error
+		for _, cfg := range cfgs {
+			problems := checkTLSFiles(cfg)
+			if len(problems) == 0 && online {
+				problems = probeServices(cfg)
+			}
+			for _, problem := range problems {
+				if cfg.Profile != "" {
+					problem = fmt.Errorf("profile %s: %w", cfg.Profile, problem)
+				}
+				errs = append(errs, problem)
+			}
+		}
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		fmt.Fprintln(os.Stderr, "Configuration is invalid:")
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  - %v\n", problem)
+		}
+		return fmt.Errorf("found %d problem(s)", len(problems))
+	}
+
+	if online {
+		fmt.Println("Configuration is valid and PagerDuty and the state backend are reachable")
+	} else {
+		fmt.Println("Configuration is valid")
+	}
+	return nil
+}
+
+// checkTLSFiles loads the configured client certificates and CA bundles, which config.Load
+// only records the paths of
+func checkTLSFiles(cfg *config.Config) []error {
+	var errs []error
+	if _, err := httpclient.LoadTLSConfig(cfg.PagerDutyTLS); err != nil {
+		errs = append(errs, fmt.Errorf("PagerDuty TLS: %w", err))
+	}
+	if _, err := httpclient.LoadTLSConfig(cfg.WebhookTLS); err != nil {
+		errs = append(errs, fmt.Errorf("webhook TLS: %w", err))
+	}
+	return errs
+}
+
+// probeServices checks each user's PagerDuty settings against the API and reads their state,
+// without changing anything. Notification backends are not probed; use test-notify for that.
+func probeServices(cfg *config.Config) []error {
+	var errs []error
+	ctx := context.Background()
+	for _, route := range cfg.Users {
+		pdClient, err := newPagerDutyClient(cfg, route)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if route.UserID == "" {
+			user, err := pdClient.ResolveCurrentUser(ctx)
+			if err != nil {
+				if route.Account != "" {
+					err = fmt.Errorf("%s: %w", route.Label(), err)
+				}
+				errs = append(errs, err)
+				continue
+			}
+			route.UserID = user.ID
+		}
+		if err := pdClient.Validate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", route.Label(), err))
+		}
+	}
+
+	newManager, err := openStateBackend(cfg)
+	if err != nil {
+		return append(errs, fmt.Errorf("state backend: %w", err))
+	}
+	for _, route := range cfg.Users {
+		if _, err := newManager(route).Load(); err != nil {
+			errs = append(errs, fmt.Errorf("state for %s: %w", route.Label(), err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+// Package audit keeps an append-only JSON Lines record of on-call transitions and notification
+// decisions, separate from the notifier's logs, so that "why didn't I get paged?" can be
+// answered after the fact
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// Key is the attribute that marks a log record as a decision to be audited
+const Key = "decision"
+
+// Decisions recorded for notifications and on-call status
+var (
+	// Sent marks a notification that was delivered
+	Sent = slog.String(Key, "sent")
+	// Failed marks a notification the backend did not accept; the record's error says why
+	Failed = slog.String(Key, "failed")
+	// Scheduled marks a notification handed to the backend to be delivered later
+	Scheduled = slog.String(Key, "scheduled")
+	// Transition marks a change of on-call status
+	Transition = slog.String(Key, "transition")
+)
+
+// Suppressed marks a notification that was not sent, e.g. Suppressed("muted")
+func Suppressed(reason string) slog.Attr {
+	return slog.String(Key, "suppressed: "+reason)
+}
+
+// Deferred marks a notification held back to be sent later, e.g. Deferred("quiet hours")
+func Deferred(reason string) slog.Attr {
+	return slog.String(Key, "deferred: "+reason)
+}
+
+// Handler passes records to another handler and also writes those carrying a decision, as
+// JSON objects, to an audit log. Decisions are written whatever the other handler's level, as
+// long as they are logged at info level or above.
+type Handler struct {
+	next  slog.Handler
+	audit slog.Handler
+}
+
+// NewHandler returns a handler that logs to next and audits decisions to w
+func NewHandler(next slog.Handler, w io.Writer) *Handler {
+	return &Handler{next: next, audit: slog.NewJSONHandler(w, nil)}
+}
+
+// Enabled reports whether the record would be logged or could be audited
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo || h.next.Enabled(ctx, level)
+}
+
+// Handle audits the record if it carries a decision and passes it on if next is enabled for it
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	if isDecision(record) {
+		if err := h.audit.Handle(ctx, record.Clone()); err != nil {
+			return err
+		}
+	}
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler that adds attrs to both logged and audited records
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs), audit: h.audit.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler that nests later attributes of both logged and audited records
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), audit: h.audit.WithGroup(name)}
+}
+
+// isDecision reports whether the record was logged with a decision
+func isDecision(record slog.Record) bool {
+	found := false
+	record.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == Key
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandlerAuditsDecisions(t *testing.T) {
+	var logs, audited strings.Builder
+	next := slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelError})
+	logger := slog.New(NewHandler(next, &audited)).With("user_id", "PUSER01")
+
+	logger.Info("Shift started, skipping notification (muted)", "event", "shift_started", Suppressed("muted"))
+	logger.Warn("Failed to send shift ended notification", "event", "shift_ended", "error", errors.New("503"), Failed)
+	logger.Info("On-call status checked", "on_call", true)
+	logger.Error("Check failed", "error", errors.New("timeout"))
+
+	lines := strings.Split(strings.TrimSpace(audited.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two audited decisions, got %q", audited.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse audit entry: %v", err)
+	}
+	if entry["decision"] != "suppressed: muted" || entry["user_id"] != "PUSER01" || entry["event"] != "shift_started" {
+		t.Fatalf("unexpected audit entry: %v", entry)
+	}
+	if !strings.Contains(lines[1], `"error":"503"`) {
+		t.Fatalf("expected the error in the audit entry, got %s", lines[1])
+	}
+
+	// LOG_LEVEL still applies to the logs themselves
+	if strings.Contains(logs.String(), "decision") || !strings.Contains(logs.String(), "Check failed") {
+		t.Fatalf("unexpected logs: %s", logs.String())
+	}
+}
+
+func TestFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	f, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	for name, want := range map[string]string{"audit.jsonl": "fourth\n", "audit.jsonl.1": "third\n", "audit.jsonl.2": "second\n"} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil || string(data) != want {
+			t.Errorf("expected %s to contain %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only two rotated files to be kept")
+	}
+}
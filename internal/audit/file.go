@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File is an append-only log file that is rotated once it would grow past a size limit. The
+// current file keeps its path, and older ones are renamed to path.1, path.2, and so on, with
+// the oldest removed.
+type File struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// Open opens path for appending, creating it and its directory if needed. The file is rotated
+// when it would exceed maxSize bytes, keeping maxBackups rotated files.
+func Open(path string, maxSize int64, maxBackups int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f := &File{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the current file and notes its size
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p, which should be one or more whole lines, rotating the file first if p
+// would take it past the size limit
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, fs.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the rotated files along, dropping the oldest, and starts a new current file.
+// If that fails, the current file is reopened so that later writes can still be appended.
+func (f *File) rotate() error {
+	f.file.Close()
+	f.file = nil
+	err := f.shift()
+	if openErr := f.open(); openErr != nil {
+		return openErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return nil
+}
+
+// shift renames each rotated file to the next number and the current file to path.1
+func (f *File) shift() error {
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if f.maxBackups == 0 {
+		return os.Remove(f.path)
+	}
+	return os.Rename(f.path, f.path+".1")
+}
+
+// Close closes the current file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -1,11 +1,27 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/errorreport"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/logging"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
 )
 
 // NotificationBackend represents the type of notification backend
@@ -17,135 +33,1412 @@ const (
 	BackendPushover NotificationBackend = "pushover"
 )
 
+// StateBackend represents where state is persisted
+type StateBackend string
+
+const (
+	StateBackendFile     StateBackend = "file"
+	StateBackendPostgres StateBackend = "postgres"
+	StateBackendS3       StateBackend = "s3"
+	StateBackendMemory   StateBackend = "memory"
+)
+
+// StartupValidation controls what happens when the PagerDuty settings fail validation at startup
+type StartupValidation string
+
+const (
+	StartupValidationFail StartupValidation = "fail"
+	StartupValidationWarn StartupValidation = "warn"
+	StartupValidationOff  StartupValidation = "off"
+)
+
+// TimeOfDay is a wall clock time, e.g. 08:30
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+}
+
+// On returns the time of day on the date of t, in t's location
+func (tod TimeOfDay) On(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), tod.Hour, tod.Minute, 0, 0, t.Location())
+}
+
+// String formats the time of day as HH:MM
+func (tod TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", tod.Hour, tod.Minute)
+}
+
+// DailyWindow is a range of times of day, e.g. 08:00–22:00, that wraps past midnight if End
+// is before Start
+type DailyWindow struct {
+	Start TimeOfDay
+	End   TimeOfDay
+}
+
+// Defer returns t if it falls within the window, or else the next start of the window after
+// t. Times of day are read in t's location.
+func (w DailyWindow) Defer(t time.Time) time.Time {
+	start, end := w.Start.On(t), w.End.On(t)
+	inside := !t.Before(start) && t.Before(end)
+	if end.Before(start) {
+		inside = !t.Before(start) || t.Before(end)
+	}
+	switch {
+	case inside:
+		return t
+	case t.Before(start):
+		return start
+	default:
+		return w.Start.On(t.AddDate(0, 0, 1))
+	}
+}
+
+// String formats the window as HH:MM-HH:MM
+func (w DailyWindow) String() string {
+	return w.Start.String() + "-" + w.End.String()
+}
+
+// UserRoute maps a monitored PagerDuty user to where their notifications are sent
+type UserRoute struct {
+	// UserID is empty when the user should be resolved from the API token
+	UserID string
+	// Target replaces the backend's default destination for this user: an ntfy topic,
+	// a comma-separated list of Pushover user keys, or a webhook URL. Empty uses the default.
+	Target string
+	// StateFilePath is where this user's state is persisted with the file backend, and
+	// StateKey the row it is kept in with a database backend
+	StateFilePath string
+	StateKey      string
+	// Account names the PD_ACCOUNTS entry the user belongs to; empty for a single account
+	Account string
+	// APIToken and ScheduleID are those of the user's account
+	APIToken   string
+	ScheduleID string
+}
+
+// Label identifies the route in logs and errors, e.g. "user PXXXXXX" or "account acme"
+func (r UserRoute) Label() string {
+	if r.Account != "" {
+		return "account " + r.Account
+	}
+	return "user " + r.UserID
+}
+
+// Recipients splits the target into its comma-separated entries, for backends that accept multiple recipients
+func (r UserRoute) Recipients() []string {
+	return splitList(r.Target)
+}
+
+// accountNamePattern matches PD_ACCOUNTS names, which become part of environment variable names
+var accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
 // Config holds all configuration for the application
 type Config struct {
-	PagerDutyAPIToken            string
-	PagerDutyScheduleID          string
-	PagerDutyUserID              string
-	CheckInterval                time.Duration
-	AdvanceNotificationTime      time.Duration
-	ShiftEndNotificationsEnabled bool
-	NotificationBackend          NotificationBackend
-	NotificationWebhookURL       string
-	NtfyServerURL                string
-	NtfyTopic                    string
-	NtfyAPIKey                   string
-	PushoverAppToken             string
-	PushoverUserKey              string
-	PushoverDevice               string
-	PushoverSound                string
-	StateFilePath                string
-}
-
-// Load loads configuration from environment variables
+	PagerDutyAPIToken             string
+	PagerDutyAPITokenFile         string
+	PagerDutyOAuth                *pagerduty.OAuthCredentials
+	PagerDutyAPIURL               string
+	PagerDutyProxyURL             *url.URL
+	PagerDutyTLS                  httpclient.TLSFiles
+	PagerDutyMaxPages             int
+	PagerDutyRetryMaxElapsed      time.Duration
+	PagerDutyShiftCacheTTL        time.Duration
+	PagerDutyMaxEscalationLevel   int
+	PagerDutyScheduleSource       pagerduty.ScheduleSource
+	PagerDutyScheduleLayers       []string
+	PagerDutyStartupValidation    StartupValidation
+	PagerDutyScheduleID           string
+	PagerDutyEscalationPolicyID   string
+	PagerDutyTeamID               string
+	PagerDutyUserID               string
+	Users                         []UserRoute
+	CheckInterval                 time.Duration
+	CheckIntervalJitter           time.Duration
+	WebhookPollInterval           time.Duration
+	EventDrivenChecks             bool
+	DegradedAlertThreshold        int
+	DegradedAlertInterval         time.Duration
+	HeartbeatURL                  string
+	SentryDSN                     string
+	SentryEnvironment             string
+	GrafanaURL                    string
+	GrafanaAPIToken               string
+	GrafanaAnnotationTags         []string
+	SlackUserToken                string
+	SlackStatusEmoji              string
+	DailySummaryTime              *TimeOfDay
+	AdvanceNotificationWindow     *DailyWindow
+	AdvanceNotificationTimes      []time.Duration
+	ShiftEndingNotificationTime   time.Duration
+	ScheduledAdvanceNotifications bool
+	ShiftEndNotificationsEnabled  bool
+	OverrideNotificationsEnabled  bool
+	ShiftStartIncidentSummary     bool
+	CatchUpNotifications          bool
+	CoverageGapLookahead          time.Duration
+	ScheduleChangeDays            int
+	ExpectedOnCallUsers           []string
+	NotificationBackend           NotificationBackend
+	NotificationWebhookURL        string
+	WebhookURLs                   notifier.EventOverrides
+	WebhookFormat                 notifier.WebhookFormat
+	WebhookMethod                 string
+	WebhookEncoding               notifier.WebhookEncoding
+	WebhookTemplate               string
+	WebhookTemplateFile           string
+	WebhookTLS                    httpclient.TLSFiles
+	WebhookTimeout                time.Duration
+	NtfyServerURL                 string
+	NtfyTopic                     string
+	NtfyAPIKey                    string
+	NtfyPriorities                notifier.EventOverrides
+	NtfyMarkdown                  bool
+	NtfyEmails                    notifier.EventOverrides
+	NtfyTags                      notifier.EventOverrides
+	NtfyControlTopic              string
+	NtfyControlSecret             string
+	NtfyTimeout                   time.Duration
+	PushoverAppToken              string
+	PushoverUserKeys              []string
+	PushoverDevice                string
+	PushoverSound                 string
+	PushoverSounds                notifier.EventOverrides
+	PushoverTTLs                  notifier.EventOverrides
+	PushoverPriorities            notifier.EventOverrides
+	PushoverHTML                  bool
+	PushoverGlances               bool
+	PushoverURL                   string
+	PushoverTimeout               time.Duration
+	MessageLocale                 notifier.Locale
+	MessageTimezone               *time.Location
+	LogLevel                      slog.Level
+	LogFormat                     string
+	AuditLogPath                  string
+	AuditLogMaxSize               int64
+	AuditLogMaxBackups            int
+	HTTPListenAddr                string
+	DebugListenAddr               string
+	MetricsListenAddr             string
+	PushgatewayURL                string
+	PushgatewayJob                string
+	HTTPPublicURL                 string
+	HTTPAPIToken                  string
+	HTTPActionToken               string
+	HTTPAllowUnauthenticated      bool
+	CalendarWeeks                 int
+	CalendarToken                 string
+	PagerDutyWebhookSecret        string
+	StateBackend                  StateBackend
+	StateFilePath                 string
+	StatePostgresURL              string
+	StatePostgresKey              string
+	StateS3Bucket                 string
+	StateS3Key                    string
+	StateS3Region                 string
+	StateS3Endpoint               string
+	StateS3PathStyle              bool
+	StateEncryptionKey            []byte
+	StateEncryptionKeyFile        string
+	ConfigFile                    string
+	// Profile is the CONFIG_FILE profile this configuration was loaded for, if any
+	Profile string
+}
+
+// ClockLocation returns the timezone times of day such as DAILY_SUMMARY_TIME are read in:
+// MESSAGE_TIMEZONE if set, otherwise UTC
+func (c *Config) ClockLocation() *time.Location {
+	if c.MessageTimezone != nil {
+		return c.MessageTimezone
+	}
+	return time.UTC
+}
+
+// PollInterval returns how often PagerDuty is polled: PD_WEBHOOK_POLL_INTERVAL if set, as
+// webhook events and shift events then trigger checks in between, otherwise CHECK_INTERVAL
+func (c *Config) PollInterval() time.Duration {
+	if c.WebhookPollInterval > 0 {
+		return c.WebhookPollInterval
+	}
+	return c.CheckInterval
+}
+
+// Files returns the files the configuration was read from, such as CONFIG_FILE, the API token
+// file, and TLS certificates, so changes to them can be watched for
+func (c *Config) Files() []string {
+	var files []string
+	for _, path := range []string{
+		c.ConfigFile,
+		c.PagerDutyAPITokenFile,
+		c.PagerDutyTLS.CertFile, c.PagerDutyTLS.KeyFile, c.PagerDutyTLS.CAFile,
+		c.WebhookTemplateFile,
+		c.WebhookTLS.CertFile, c.WebhookTLS.KeyFile, c.WebhookTLS.CAFile,
+		c.StateEncryptionKeyFile,
+	} {
+		if path != "" && !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Load loads configuration from environment variables, and from CONFIG_FILE if it is set.
+// If CONFIG_FILE defines several profiles, one must be selected with SelectProfile.
 func Load() (*Config, error) {
-	cfg := &Config{}
+	cfgs, err := loadProfiles(true)
+	if err != nil {
+		return nil, err
+	}
+	return cfgs[0], nil
+}
 
-	// Required: PagerDuty API Token
-	cfg.PagerDutyAPIToken = os.Getenv("PD_API_TOKEN")
-	if cfg.PagerDutyAPIToken == "" {
-		return nil, fmt.Errorf("PD_API_TOKEN environment variable is required")
+// LoadAll loads the configuration of each profile defined in CONFIG_FILE, or of the profile
+// selected with SelectProfile, or the single configuration if there are no profiles
+func LoadAll() ([]*Config, error) {
+	return loadProfiles(false)
+}
+
+// loadProfiles implements Load and LoadAll; with single, CONFIG_FILE must define at most one
+// profile unless one is selected
+func loadProfiles(single bool) ([]*Config, error) {
+	// Optional: File of KEY=VALUE settings that override the environment. It is read again on
+	// every Load, so a running notifier can pick up edits.
+	if err := loadConfigFile(); err != nil {
+		return nil, err
+	}
+	defer func() { profileValues = nil }()
+	if single && selectedProfile == "" && len(fileProfiles) > 1 {
+		return nil, errProfileRequired()
+	}
+
+	profiles := fileProfiles
+	if selectedProfile != "" {
+		profile, err := findProfile(selectedProfile)
+		if err != nil {
+			return nil, err
+		}
+		profiles = []fileProfile{profile}
+	}
+	if len(profiles) == 0 {
+		cfg, err := load("")
+		if err != nil {
+			return nil, err
+		}
+		return []*Config{cfg}, nil
 	}
 
-	// Required: PagerDuty Schedule ID
-	cfg.PagerDutyScheduleID = os.Getenv("PD_SCHEDULE_ID")
-	if cfg.PagerDutyScheduleID == "" {
-		return nil, fmt.Errorf("PD_SCHEDULE_ID environment variable is required")
+	var cfgs []*Config
+	var errs []error
+	for _, profile := range profiles {
+		profileValues = profile.values
+		cfg, err := load(profile.name)
+		if err != nil {
+			for _, err := range unwrapJoined(err) {
+				errs = append(errs, fmt.Errorf("profile %s: %w", profile.name, err))
+			}
+			continue
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	// The HTTP API and remote commands act on a single user's state
+	if len(cfgs) > 1 && slices.ContainsFunc(cfgs, func(cfg *Config) bool { return cfg.HTTPListenAddr != "" || cfg.NtfyControlTopic != "" }) {
+		return nil, fmt.Errorf("HTTP_LISTEN_ADDR and NTFY_CONTROL_TOPIC are not supported when running several profiles")
 	}
+	return cfgs, nil
+}
 
-	// Required: PagerDuty User ID
-	cfg.PagerDutyUserID = os.Getenv("PD_USER_ID")
-	if cfg.PagerDutyUserID == "" {
-		return nil, fmt.Errorf("PD_USER_ID environment variable is required")
+// unwrapJoined returns the errors joined into err by errors.Join, however deeply, or err itself
+func unwrapJoined(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, unwrapJoined(err)...)
 	}
+	return errs
+}
 
-	// Required: Notification Backend
-	backendStr := os.Getenv("NOTIFICATION_BACKEND")
-	if backendStr == "" {
-		return nil, fmt.Errorf("NOTIFICATION_BACKEND environment variable is required (must be 'webhook', 'ntfy', or 'pushover')")
+// load reads the configuration of the named profile, or the only configuration if profile is
+// empty, once CONFIG_FILE has been read
+func load(profile string) (*Config, error) {
+	cfg := &Config{Profile: profile}
+	cfg.ConfigFile = os.Getenv("CONFIG_FILE")
+	if err := resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	// Every invalid setting is reported, not just the first
+	var errs []error
+
+	// Optional: Several PagerDuty accounts, each with its own token, schedule, user, and target
+	accounts, err := parseAccounts("PD_ACCOUNTS")
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Required: PagerDuty API Token, or a file holding it, unless a scoped OAuth app or PD_ACCOUNTS is configured
+	cfg.PagerDutyAPIToken = getenv("PD_API_TOKEN")
+	cfg.PagerDutyAPITokenFile = getenv("PD_API_TOKEN_FILE")
+	if cfg.PagerDutyAPITokenFile != "" {
+		if cfg.PagerDutyAPIToken != "" {
+			errs = append(errs, fmt.Errorf("PD_API_TOKEN and PD_API_TOKEN_FILE cannot both be set"))
+		}
+		// The file is re-read whenever it changes, but must hold a token to start with
+		data, err := os.ReadFile(cfg.PagerDutyAPITokenFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read PD_API_TOKEN_FILE: %w", err))
+		} else if strings.TrimSpace(string(data)) == "" {
+			errs = append(errs, fmt.Errorf("PD_API_TOKEN_FILE %s is empty", cfg.PagerDutyAPITokenFile))
+		}
+	}
+
+	// Optional: PagerDuty service region or API URL (default: US region)
+	cfg.PagerDutyAPIURL = getenv("PD_API_URL")
+	if region := strings.ToLower(getenv("PD_REGION")); region != "" {
+		regionURL, ok := pagerduty.RegionAPIURLs[region]
+		if !ok {
+			errs = append(errs, fmt.Errorf("PD_REGION must be one of [us eu], got: %s", region))
+		}
+		if cfg.PagerDutyAPIURL != "" {
+			errs = append(errs, fmt.Errorf("PD_REGION and PD_API_URL cannot both be set"))
+		}
+		cfg.PagerDutyAPIURL = regionURL
+	}
+	if cfg.PagerDutyAPIURL != "" {
+		if u, err := url.Parse(cfg.PagerDutyAPIURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("PD_API_URL must be an absolute URL (e.g., 'https://api.eu.pagerduty.com'), got: %s", cfg.PagerDutyAPIURL))
+		}
+		cfg.PagerDutyAPIURL = strings.TrimSuffix(cfg.PagerDutyAPIURL, "/")
+	}
+
+	// Optional: Scoped OAuth app credentials, used instead of PD_API_TOKEN
+	clientID, clientSecret := getenv("PD_OAUTH_CLIENT_ID"), getenv("PD_OAUTH_CLIENT_SECRET")
+	switch {
+	case clientID != "" || clientSecret != "":
+		if clientID == "" || clientSecret == "" {
+			errs = append(errs, fmt.Errorf("PD_OAUTH_CLIENT_ID and PD_OAUTH_CLIENT_SECRET must be set together"))
+		}
+		if cfg.PagerDutyAPIToken != "" || cfg.PagerDutyAPITokenFile != "" {
+			errs = append(errs, fmt.Errorf("PD_API_TOKEN or PD_API_TOKEN_FILE and PD_OAUTH_CLIENT_ID cannot both be set"))
+		}
+		subdomain := getenv("PD_OAUTH_SUBDOMAIN")
+		if subdomain == "" {
+			errs = append(errs, fmt.Errorf("PD_OAUTH_SUBDOMAIN is required with PD_OAUTH_CLIENT_ID (e.g., 'acme' for acme.pagerduty.com)"))
+		}
+		region := strings.ToLower(getenv("PD_REGION"))
+		if region == "" {
+			region = "us"
+		}
+		scopes := pagerduty.DefaultOAuthScopes
+		if scopesStr := getenv("PD_OAUTH_SCOPES"); scopesStr != "" {
+			scopes = strings.FieldsFunc(scopesStr, func(r rune) bool { return r == ',' || r == ' ' })
+		}
+		cfg.PagerDutyOAuth = &pagerduty.OAuthCredentials{
+			TokenURL:     pagerduty.OAuthTokenURLs[region],
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       append([]string{fmt.Sprintf("as_account-%s.%s", region, subdomain)}, scopes...),
+		}
+	case cfg.PagerDutyAPIToken == "" && cfg.PagerDutyAPITokenFile == "" && len(accounts) == 0:
+		errs = append(errs, fmt.Errorf("PD_API_TOKEN (or PD_API_TOKEN_FILE, or PD_OAUTH_CLIENT_ID and PD_OAUTH_CLIENT_SECRET) environment variable is required"))
 	}
+	if len(accounts) > 0 && (cfg.PagerDutyAPIToken != "" || cfg.PagerDutyAPITokenFile != "" || cfg.PagerDutyOAuth != nil) {
+		errs = append(errs, fmt.Errorf("PD_ACCOUNTS cannot be combined with PD_API_TOKEN, PD_API_TOKEN_FILE, or PD_OAUTH_CLIENT_ID"))
+	}
+
+	// Optional: Proxy and TLS settings for the PagerDuty API (and OAuth token endpoint)
+	if proxyStr := getenv("PD_PROXY_URL"); proxyStr != "" {
+		proxyURL, err := url.Parse(proxyStr)
+		if err != nil || proxyURL.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, proxyURL.Scheme) {
+			errs = append(errs, fmt.Errorf("PD_PROXY_URL must be an http, https, or socks5 URL (e.g., 'http://proxy:3128'), got: %s", proxyStr))
+		}
+		cfg.PagerDutyProxyURL = proxyURL
+	}
+	cfg.PagerDutyTLS = httpclient.TLSFiles{
+		CertFile: getenv("PD_TLS_CERT_FILE"),
+		KeyFile:  getenv("PD_TLS_KEY_FILE"),
+		CAFile:   getenv("PD_TLS_CA_FILE"),
+	}
+	if (cfg.PagerDutyTLS.CertFile == "") != (cfg.PagerDutyTLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("PD_TLS_CERT_FILE and PD_TLS_KEY_FILE must be set together"))
+	}
+
+	// Optional: Maximum pages fetched per on-call listing
+	cfg.PagerDutyMaxPages = pagerduty.DefaultMaxPages
+	if maxPagesStr := getenv("PD_MAX_PAGES"); maxPagesStr != "" {
+		maxPages, err := strconv.Atoi(maxPagesStr)
+		if err != nil || maxPages <= 0 {
+			errs = append(errs, fmt.Errorf("PD_MAX_PAGES must be a positive integer, got: %s", maxPagesStr))
+		}
+		cfg.PagerDutyMaxPages = maxPages
+	}
+
+	// Optional: Time budget for retrying failed PagerDuty calls
+	cfg.PagerDutyRetryMaxElapsed = pagerduty.DefaultRetryMaxElapsed
+	if retryStr := getenv("PD_RETRY_MAX_ELAPSED"); retryStr != "" {
+		retryMaxElapsed, err := time.ParseDuration(retryStr)
+		if err != nil || retryMaxElapsed <= 0 {
+			errs = append(errs, fmt.Errorf("PD_RETRY_MAX_ELAPSED must be a positive duration (e.g., '30s', '2m'), got: %s", retryStr))
+		}
+		cfg.PagerDutyRetryMaxElapsed = retryMaxElapsed
+	}
+
+	// Optional: How long the next upcoming shift is cached between checks
+	cfg.PagerDutyShiftCacheTTL = pagerduty.DefaultShiftCacheTTL
+	if ttlStr := getenv("PD_SHIFT_CACHE_TTL"); ttlStr != "" {
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil || ttl < 0 {
+			errs = append(errs, fmt.Errorf("PD_SHIFT_CACHE_TTL must be a non-negative duration (e.g., '15m', '0' to disable), got: %s", ttlStr))
+		}
+		cfg.PagerDutyShiftCacheTTL = ttl
+	}
+
+	// Optional: Ignore on-call entries at escalation levels deeper than this (e.g. 1 for primary only)
+	if levelStr := getenv("PD_MAX_ESCALATION_LEVEL"); levelStr != "" {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level <= 0 {
+			errs = append(errs, fmt.Errorf("PD_MAX_ESCALATION_LEVEL must be a positive integer, got: %s", levelStr))
+		}
+		cfg.PagerDutyMaxEscalationLevel = level
+	}
+
+	// Required: PagerDuty Schedule ID, or an escalation policy or team to evaluate on-call membership against
+	cfg.PagerDutyScheduleID = getenv("PD_SCHEDULE_ID")
+	cfg.PagerDutyEscalationPolicyID = getenv("PD_ESCALATION_POLICY_ID")
+	cfg.PagerDutyTeamID = getenv("PD_TEAM_ID")
+	scopes := 0
+	for _, id := range []string{cfg.PagerDutyScheduleID, cfg.PagerDutyEscalationPolicyID, cfg.PagerDutyTeamID} {
+		if id != "" {
+			scopes++
+		}
+	}
+	if len(accounts) > 0 && scopes > 0 {
+		errs = append(errs, fmt.Errorf("PD_ACCOUNTS cannot be combined with PD_SCHEDULE_ID, PD_ESCALATION_POLICY_ID, or PD_TEAM_ID"))
+	}
+	if scopes == 0 && len(accounts) == 0 {
+		errs = append(errs, fmt.Errorf("one of PD_SCHEDULE_ID, PD_ESCALATION_POLICY_ID, or PD_TEAM_ID environment variables is required"))
+	}
+	if scopes > 1 {
+		errs = append(errs, fmt.Errorf("only one of PD_SCHEDULE_ID, PD_ESCALATION_POLICY_ID, or PD_TEAM_ID can be set"))
+	}
+
+	// Optional: Which schedule entries count as on call (on-call listing, final schedule, or layers)
+	cfg.PagerDutyScheduleSource = pagerduty.SourceOnCalls
+	if sourceStr := getenv("PD_SCHEDULE_SOURCE"); sourceStr != "" {
+		source, err := pagerduty.ParseScheduleSource(strings.ToLower(sourceStr))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("PD_SCHEDULE_SOURCE: %w", err))
+		} else if source != pagerduty.SourceOnCalls && cfg.PagerDutyEscalationPolicyID != "" {
+			errs = append(errs, fmt.Errorf("PD_SCHEDULE_SOURCE=%s requires PD_SCHEDULE_ID or PD_TEAM_ID", source))
+		}
+		cfg.PagerDutyScheduleSource = source
+	}
+	cfg.PagerDutyScheduleLayers = splitList(getenv("PD_SCHEDULE_LAYERS"))
+	if cfg.PagerDutyScheduleSource == pagerduty.SourceLayers && len(cfg.PagerDutyScheduleLayers) == 0 {
+		errs = append(errs, fmt.Errorf("PD_SCHEDULE_LAYERS is required when PD_SCHEDULE_SOURCE=layers"))
+	}
+
+	// Optional: Whether invalid PagerDuty IDs stop startup, are only logged, or are not checked
+	cfg.PagerDutyStartupValidation = StartupValidationFail
+	if validationStr := getenv("PD_STARTUP_VALIDATION"); validationStr != "" {
+		validation := StartupValidation(strings.ToLower(validationStr))
+		switch validation {
+		case StartupValidationFail, StartupValidationWarn, StartupValidationOff:
+			cfg.PagerDutyStartupValidation = validation
+		default:
+			errs = append(errs, fmt.Errorf("PD_STARTUP_VALIDATION must be fail, warn, or off, got: %s", validationStr))
+		}
+	}
+
+	// Optional: PagerDuty User ID, or a list of users to monitor (default: the API token's user)
+	cfg.PagerDutyUserID = getenv("PD_USER_ID")
+	users, err := parseUserRoutes("PD_USERS")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(users) > 0 && cfg.PagerDutyUserID != "" {
+		errs = append(errs, fmt.Errorf("PD_USER_ID and PD_USERS cannot both be set"))
+	}
+	if len(accounts) > 0 && (len(users) > 0 || cfg.PagerDutyUserID != "") {
+		errs = append(errs, fmt.Errorf("PD_ACCOUNTS cannot be combined with PD_USER_ID or PD_USERS; set PD_ACCOUNT_<NAME>_USER_ID instead"))
+	}
+	if len(users) == 0 && cfg.PagerDutyOAuth != nil && cfg.PagerDutyUserID == "" {
+		errs = append(errs, fmt.Errorf("PD_USER_ID or PD_USERS is required with PD_OAUTH_CLIENT_ID, as OAuth app tokens do not belong to a user"))
+	}
+	if len(users) == 0 {
+		// An empty PD_USER_ID is resolved from the API token's own user at startup
+		users = []UserRoute{{UserID: cfg.PagerDutyUserID}}
+	}
+	for i := range users {
+		users[i].APIToken = cfg.PagerDutyAPIToken
+		users[i].ScheduleID = cfg.PagerDutyScheduleID
+	}
+	if len(accounts) > 0 {
+		users = accounts
+	}
+	cfg.Users = users
+	// Backend destinations may be omitted when every user has their own
+	allRouted := !slices.ContainsFunc(cfg.Users, func(u UserRoute) bool { return u.Target == "" })
+
+	// Required: Notification Backend
+	backendStr := getenv("NOTIFICATION_BACKEND")
 	cfg.NotificationBackend = NotificationBackend(backendStr)
 	switch cfg.NotificationBackend {
 	case BackendWebhook, BackendNtfy, BackendPushover:
+	case "":
+		errs = append(errs, fmt.Errorf("NOTIFICATION_BACKEND environment variable is required (must be 'webhook', 'ntfy', or 'pushover')"))
 	default:
-		return nil, fmt.Errorf("NOTIFICATION_BACKEND must be 'webhook', 'ntfy', or 'pushover', got: %s", backendStr)
+		errs = append(errs, fmt.Errorf("NOTIFICATION_BACKEND must be 'webhook', 'ntfy', or 'pushover', got: %s", backendStr))
 	}
 
 	// Backend-specific configuration
 	switch cfg.NotificationBackend {
 	case BackendWebhook:
-		cfg.NotificationWebhookURL = os.Getenv("NOTIFICATION_WEBHOOK_URL")
-		if cfg.NotificationWebhookURL == "" {
-			return nil, fmt.Errorf("NOTIFICATION_WEBHOOK_URL environment variable is required when using webhook backend")
+		cfg.NotificationWebhookURL = getenv("NOTIFICATION_WEBHOOK_URL")
+		// Optional per-event URLs; NOTIFICATION_WEBHOOK_URL is the fallback for unlisted events
+		webhookURLs, err := parseEventOverrides("WEBHOOK_URLS", ",", nil)
+		if err != nil {
+			errs = append(errs, err)
 		}
+		for event, webhookURL := range webhookURLs {
+			if u, err := url.Parse(webhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("WEBHOOK_URLS value for %s must be an absolute URL, got: %s", event, webhookURL))
+			}
+		}
+		cfg.WebhookURLs = webhookURLs
+		for _, user := range cfg.Users {
+			if user.Target == "" {
+				continue
+			}
+			if u, err := url.Parse(user.Target); err != nil || u.Scheme == "" || u.Host == "" {
+				if user.Account != "" {
+					errs = append(errs, fmt.Errorf("PD_ACCOUNT_%s_TARGET must be an absolute URL, got: %s", strings.ToUpper(user.Account), user.Target))
+					continue
+				}
+				errs = append(errs, fmt.Errorf("PD_USERS webhook URL for %s must be an absolute URL, got: %s", user.UserID, user.Target))
+			}
+		}
+		if cfg.NotificationWebhookURL == "" && len(cfg.WebhookURLs) == 0 && !allRouted {
+			errs = append(errs, fmt.Errorf("NOTIFICATION_WEBHOOK_URL or WEBHOOK_URLS environment variable is required when using webhook backend"))
+		}
+		cfg.WebhookFormat = notifier.WebhookFormatDefault
+		if formatStr := getenv("WEBHOOK_FORMAT"); formatStr != "" {
+			if !slices.Contains(notifier.WebhookFormats, formatStr) {
+				errs = append(errs, fmt.Errorf("WEBHOOK_FORMAT must be one of %v, got: %s", notifier.WebhookFormats, formatStr))
+			}
+			cfg.WebhookFormat = notifier.WebhookFormat(formatStr)
+		}
+		cfg.WebhookTemplate = getenv("WEBHOOK_TEMPLATE")
+		cfg.WebhookTemplateFile = getenv("WEBHOOK_TEMPLATE_FILE")
+		if templateFile := cfg.WebhookTemplateFile; templateFile != "" {
+			if cfg.WebhookTemplate != "" {
+				errs = append(errs, fmt.Errorf("WEBHOOK_TEMPLATE and WEBHOOK_TEMPLATE_FILE cannot both be set"))
+			}
+			data, err := os.ReadFile(templateFile)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read WEBHOOK_TEMPLATE_FILE: %w", err))
+			}
+			cfg.WebhookTemplate = string(data)
+		}
+		if cfg.WebhookTemplate != "" && cfg.WebhookFormat != notifier.WebhookFormatDefault {
+			errs = append(errs, fmt.Errorf("WEBHOOK_FORMAT cannot be combined with a custom webhook template"))
+		}
+		cfg.WebhookMethod = http.MethodPost
+		if method := strings.ToUpper(getenv("WEBHOOK_METHOD")); method != "" {
+			if !slices.Contains(notifier.WebhookMethods, method) {
+				errs = append(errs, fmt.Errorf("WEBHOOK_METHOD must be one of %v, got: %s", notifier.WebhookMethods, method))
+			}
+			cfg.WebhookMethod = method
+		}
+		cfg.WebhookEncoding = notifier.WebhookEncodingJSON
+		if encodingStr := getenv("WEBHOOK_ENCODING"); encodingStr != "" {
+			if !slices.Contains(notifier.WebhookEncodings, encodingStr) {
+				errs = append(errs, fmt.Errorf("WEBHOOK_ENCODING must be one of %v, got: %s", notifier.WebhookEncodings, encodingStr))
+			}
+			cfg.WebhookEncoding = notifier.WebhookEncoding(encodingStr)
+		}
+		if cfg.WebhookEncoding == notifier.WebhookEncodingForm && (cfg.WebhookFormat != notifier.WebhookFormatDefault || cfg.WebhookTemplate != "") {
+			errs = append(errs, fmt.Errorf("WEBHOOK_ENCODING=form can only be used with the default webhook format"))
+		}
+		cfg.WebhookTLS = httpclient.TLSFiles{
+			CertFile: getenv("WEBHOOK_TLS_CERT_FILE"),
+			KeyFile:  getenv("WEBHOOK_TLS_KEY_FILE"),
+			CAFile:   getenv("WEBHOOK_TLS_CA_FILE"),
+		}
+		if (cfg.WebhookTLS.CertFile == "") != (cfg.WebhookTLS.KeyFile == "") {
+			errs = append(errs, fmt.Errorf("WEBHOOK_TLS_CERT_FILE and WEBHOOK_TLS_KEY_FILE must be set together"))
+		}
+		timeout, err := parseTimeout("WEBHOOK_TIMEOUT")
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.WebhookTimeout = timeout
 	case BackendNtfy:
-		cfg.NtfyServerURL = os.Getenv("NTFY_SERVER_URL")
+		cfg.NtfyServerURL = getenv("NTFY_SERVER_URL")
 		if cfg.NtfyServerURL == "" {
-			return nil, fmt.Errorf("NTFY_SERVER_URL environment variable is required when using ntfy backend")
+			errs = append(errs, fmt.Errorf("NTFY_SERVER_URL environment variable is required when using ntfy backend"))
 		}
-		cfg.NtfyTopic = os.Getenv("NTFY_TOPIC")
-		if cfg.NtfyTopic == "" {
-			return nil, fmt.Errorf("NTFY_TOPIC environment variable is required when using ntfy backend")
+		cfg.NtfyTopic = getenv("NTFY_TOPIC")
+		if cfg.NtfyTopic == "" && !allRouted {
+			errs = append(errs, fmt.Errorf("NTFY_TOPIC environment variable is required when using ntfy backend"))
 		}
 		// API key is optional for ntfy
-		cfg.NtfyAPIKey = os.Getenv("NTFY_API_KEY")
+		cfg.NtfyAPIKey = getenv("NTFY_API_KEY")
+		priorities, err := parseEventOverrides("NTFY_PRIORITIES", ",", notifier.NtfyPriorities)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.NtfyPriorities = priorities
+		emails, err := parseEventOverrides("NTFY_EMAIL", ",", nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for event, email := range emails {
+			if _, err := mail.ParseAddress(email); err != nil {
+				errs = append(errs, fmt.Errorf("NTFY_EMAIL value for %s must be a valid email address: %w", event, err))
+			}
+		}
+		cfg.NtfyEmails = emails
+		// Tags are comma-separated themselves, so events are separated by semicolons
+		tags, err := parseEventOverrides("NTFY_TAGS", ";", nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.NtfyTags = tags
+		// Control topic is optional; replies are published to NTFY_TOPIC so it must differ
+		cfg.NtfyControlTopic = getenv("NTFY_CONTROL_TOPIC")
+		if cfg.NtfyControlTopic != "" && cfg.NtfyControlTopic == cfg.NtfyTopic {
+			errs = append(errs, fmt.Errorf("NTFY_CONTROL_TOPIC must be different from NTFY_TOPIC"))
+		}
+		// Topics are often public, so commands must start with a shared secret
+		cfg.NtfyControlSecret = getenv("NTFY_CONTROL_SECRET")
+		if cfg.NtfyControlTopic != "" && len(cfg.NtfyControlSecret) < 12 {
+			errs = append(errs, fmt.Errorf("NTFY_CONTROL_SECRET of at least 12 characters is required when NTFY_CONTROL_TOPIC is set"))
+		}
+		if strings.ContainsFunc(cfg.NtfyControlSecret, unicode.IsSpace) {
+			errs = append(errs, fmt.Errorf("NTFY_CONTROL_SECRET must not contain whitespace"))
+		}
+		if markdownStr := getenv("NTFY_MARKDOWN"); markdownStr != "" {
+			markdown, err := strconv.ParseBool(markdownStr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("NTFY_MARKDOWN must be a boolean (true/false): %w", err))
+			}
+			cfg.NtfyMarkdown = markdown
+		}
+		timeout, err := parseTimeout("NTFY_TIMEOUT")
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.NtfyTimeout = timeout
 	case BackendPushover:
-		cfg.PushoverAppToken = os.Getenv("PUSHOVER_APP_TOKEN")
+		cfg.PushoverAppToken = getenv("PUSHOVER_APP_TOKEN")
 		if cfg.PushoverAppToken == "" {
-			return nil, fmt.Errorf("PUSHOVER_APP_TOKEN environment variable is required when using pushover backend")
+			errs = append(errs, fmt.Errorf("PUSHOVER_APP_TOKEN environment variable is required when using pushover backend"))
+		}
+		// Comma-separated list of user or delivery group keys
+		cfg.PushoverUserKeys = splitList(getenv("PUSHOVER_USER_KEY"))
+		if len(cfg.PushoverUserKeys) == 0 && !allRouted {
+			errs = append(errs, fmt.Errorf("PUSHOVER_USER_KEY environment variable is required when using pushover backend"))
 		}
-		cfg.PushoverUserKey = os.Getenv("PUSHOVER_USER_KEY")
-		if cfg.PushoverUserKey == "" {
-			return nil, fmt.Errorf("PUSHOVER_USER_KEY environment variable is required when using pushover backend")
+		cfg.PushoverDevice = getenv("PUSHOVER_DEVICE")
+		cfg.PushoverSound = getenv("PUSHOVER_SOUND")
+		sounds, err := parseEventOverrides("PUSHOVER_SOUNDS", ",", nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.PushoverSounds = sounds
+		ttls, err := parseEventOverrides("PUSHOVER_TTL", ",", nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for event, ttl := range ttls {
+			if ttl == notifier.PushoverTTLUntilShiftStart {
+				if event != notifier.EventUpcomingShift {
+					errs = append(errs, fmt.Errorf("PUSHOVER_TTL value %s is only supported for %s", ttl, notifier.EventUpcomingShift))
+				}
+				continue
+			}
+			d, err := time.ParseDuration(ttl)
+			if err != nil || d < time.Second {
+				errs = append(errs, fmt.Errorf("PUSHOVER_TTL value for %s must be a duration of at least 1s (e.g., '2h') or %s, got: %s", event, notifier.PushoverTTLUntilShiftStart, ttl))
+			}
+		}
+		cfg.PushoverTTLs = ttls
+		priorities, err := parseEventOverrides("PUSHOVER_PRIORITIES", ",", notifier.PushoverPriorities)
+		if err != nil {
+			errs = append(errs, err)
 		}
-		cfg.PushoverDevice = os.Getenv("PUSHOVER_DEVICE")
-		cfg.PushoverSound = os.Getenv("PUSHOVER_SOUND")
+		cfg.PushoverPriorities = priorities
+		if htmlStr := getenv("PUSHOVER_HTML"); htmlStr != "" {
+			html, err := strconv.ParseBool(htmlStr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("PUSHOVER_HTML must be a boolean (true/false): %w", err))
+			}
+			cfg.PushoverHTML = html
+		}
+		if glancesStr := getenv("PUSHOVER_GLANCES"); glancesStr != "" {
+			glances, err := strconv.ParseBool(glancesStr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("PUSHOVER_GLANCES must be a boolean (true/false): %w", err))
+			}
+			cfg.PushoverGlances = glances
+		}
+		// URL is optional; defaults to the PagerDuty schedule's web page
+		cfg.PushoverURL = getenv("PUSHOVER_URL")
+		if cfg.PushoverURL != "" {
+			if u, err := url.Parse(cfg.PushoverURL); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("PUSHOVER_URL must be an absolute URL, got: %s", cfg.PushoverURL))
+			}
+		}
+		timeout, err := parseTimeout("PUSHOVER_TIMEOUT")
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.PushoverTimeout = timeout
 	}
 
 	// Optional: Check Interval (default: 300 seconds / 5 minutes)
-	checkIntervalStr := os.Getenv("CHECK_INTERVAL")
+	checkIntervalStr := getenv("CHECK_INTERVAL")
 	if checkIntervalStr == "" {
 		cfg.CheckInterval = 5 * time.Minute
 	} else {
 		interval, err := strconv.Atoi(checkIntervalStr)
 		if err != nil {
-			return nil, fmt.Errorf("CHECK_INTERVAL must be a valid integer: %w", err)
-		}
-		if interval <= 0 {
-			return nil, fmt.Errorf("CHECK_INTERVAL must be greater than 0")
+			errs = append(errs, fmt.Errorf("CHECK_INTERVAL must be a valid integer: %w", err))
+		} else if interval <= 0 {
+			errs = append(errs, fmt.Errorf("CHECK_INTERVAL must be greater than 0"))
 		}
 		cfg.CheckInterval = time.Duration(interval) * time.Second
 	}
 
-	// Optional: Advance Notification Time (default: disabled/0 if not set)
-	advanceTimeStr := os.Getenv("ADVANCE_NOTIFICATION_TIME")
-	if advanceTimeStr != "" {
+	// Optional: Check Interval Jitter (default: 0 / disabled)
+	// Spreads the polls of notifiers that share an account token
+	if jitterStr := getenv("CHECK_INTERVAL_JITTER"); jitterStr != "" {
+		jitter, err := strconv.Atoi(jitterStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CHECK_INTERVAL_JITTER must be a valid integer: %w", err))
+		} else if jitter < 0 {
+			errs = append(errs, fmt.Errorf("CHECK_INTERVAL_JITTER must not be negative"))
+		} else if cfg.CheckInterval > 0 && time.Duration(jitter)*time.Second >= cfg.CheckInterval {
+			errs = append(errs, fmt.Errorf("CHECK_INTERVAL_JITTER must be less than CHECK_INTERVAL"))
+		}
+		cfg.CheckIntervalJitter = time.Duration(jitter) * time.Second
+	}
+
+	// Optional: Also check when known shift events are due (default: false)
+	if eventDrivenStr := getenv("EVENT_DRIVEN_CHECKS"); eventDrivenStr != "" {
+		enabled, err := strconv.ParseBool(eventDrivenStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("EVENT_DRIVEN_CHECKS must be a boolean (true/false): %w", err))
+		}
+		cfg.EventDrivenChecks = enabled
+	}
+
+	// Optional: Alert through the backend after this many consecutive failed checks (default: disabled)
+	if thresholdStr := getenv("DEGRADED_ALERT_THRESHOLD"); thresholdStr != "" {
+		threshold, err := strconv.Atoi(thresholdStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("DEGRADED_ALERT_THRESHOLD must be a valid integer: %w", err))
+		} else if threshold < 0 {
+			errs = append(errs, fmt.Errorf("DEGRADED_ALERT_THRESHOLD must not be negative"))
+		}
+		cfg.DegradedAlertThreshold = threshold
+	}
+
+	// Optional: Minimum time between repeated degraded alerts (default: 6h)
+	cfg.DegradedAlertInterval = 6 * time.Hour
+	if intervalStr := getenv("DEGRADED_ALERT_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			errs = append(errs, fmt.Errorf("DEGRADED_ALERT_INTERVAL must be a positive duration (e.g., '6h', '30m'), got: %s", intervalStr))
+		}
+		cfg.DegradedAlertInterval = interval
+	}
+
+	// Optional: URL pinged after every successful check (default: disabled)
+	cfg.HeartbeatURL = getenv("HEARTBEAT_URL")
+	if cfg.HeartbeatURL != "" {
+		if u, err := url.Parse(cfg.HeartbeatURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("HEARTBEAT_URL must be an absolute URL (e.g., 'https://hc-ping.com/<uuid>'), got: %s", cfg.HeartbeatURL))
+		}
+	}
+
+	// Optional: Report panics and persistent errors to Sentry (default: disabled)
+	cfg.SentryDSN = getenv("SENTRY_DSN")
+	if cfg.SentryDSN != "" {
+		if _, err := errorreport.New(cfg.SentryDSN, ""); err != nil {
+			errs = append(errs, fmt.Errorf("SENTRY_DSN is %w", err))
+		}
+	}
+	cfg.SentryEnvironment = getenv("SENTRY_ENVIRONMENT")
+
+	// Optional: Grafana to annotate shift starts and ends in (default: disabled)
+	cfg.GrafanaURL = getenv("GRAFANA_URL")
+	cfg.GrafanaAPIToken = getenv("GRAFANA_API_TOKEN")
+	cfg.GrafanaAnnotationTags = splitList(getenv("GRAFANA_ANNOTATION_TAGS"))
+	if cfg.GrafanaURL != "" {
+		if u, err := url.Parse(cfg.GrafanaURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("GRAFANA_URL must be an absolute http(s) URL (e.g., 'https://grafana.example.com'), got: %s", cfg.GrafanaURL))
+		}
+		if cfg.GrafanaAPIToken == "" {
+			errs = append(errs, fmt.Errorf("GRAFANA_API_TOKEN is required when GRAFANA_URL is set"))
+		}
+	}
+
+	// Optional: Slack user token to set the user's status with while on call (default: disabled)
+	cfg.SlackUserToken = getenv("SLACK_USER_TOKEN")
+	if cfg.SlackUserToken != "" && !strings.HasPrefix(cfg.SlackUserToken, "xoxp-") {
+		errs = append(errs, fmt.Errorf("SLACK_USER_TOKEN must be a Slack user token (starting with 'xoxp-')"))
+	}
+	cfg.SlackStatusEmoji = getenv("SLACK_STATUS_EMOJI")
+	if cfg.SlackStatusEmoji == "" {
+		cfg.SlackStatusEmoji = ":pager:"
+	}
+	if emoji := cfg.SlackStatusEmoji; len(emoji) < 3 || !strings.HasPrefix(emoji, ":") || !strings.HasSuffix(emoji, ":") {
+		errs = append(errs, fmt.Errorf("SLACK_STATUS_EMOJI must be an emoji code (e.g., ':pager:'), got: %s", emoji))
+	}
+
+	// Optional: Local time to send a daily summary at, in MESSAGE_TIMEZONE (default: disabled)
+	if summaryStr := getenv("DAILY_SUMMARY_TIME"); summaryStr != "" {
+		summaryTime, err := parseTimeOfDay(summaryStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("DAILY_SUMMARY_TIME must be a time of day (e.g., '08:30'), got: %s", summaryStr))
+		}
+		cfg.DailySummaryTime = &summaryTime
+	}
+
+	// Optional: Advance Notification Times, longest first (default: disabled if not set)
+	for _, advanceTimeStr := range splitList(getenv("ADVANCE_NOTIFICATION_TIME")) {
 		advanceTime, err := time.ParseDuration(advanceTimeStr)
 		if err != nil {
-			return nil, fmt.Errorf("ADVANCE_NOTIFICATION_TIME must be a valid duration (e.g., '2h', '30m', '1h30m'): %w", err)
+			errs = append(errs, fmt.Errorf("ADVANCE_NOTIFICATION_TIME must be a valid duration or comma-separated list of durations (e.g., '2h', '24h,2h,15m'): %w", err))
+		} else if advanceTime <= 0 {
+			errs = append(errs, fmt.Errorf("ADVANCE_NOTIFICATION_TIME must be greater than 0, got: %s", advanceTimeStr))
+		} else if !slices.Contains(cfg.AdvanceNotificationTimes, advanceTime) {
+			cfg.AdvanceNotificationTimes = append(cfg.AdvanceNotificationTimes, advanceTime)
 		}
-		if advanceTime <= 0 {
-			return nil, fmt.Errorf("ADVANCE_NOTIFICATION_TIME must be greater than 0")
+	}
+	if len(cfg.AdvanceNotificationTimes) > 0 {
+		slices.Sort(cfg.AdvanceNotificationTimes)
+		slices.Reverse(cfg.AdvanceNotificationTimes)
+		slog.Debug("Advance notification times", "times", cfg.AdvanceNotificationTimes)
+	}
+
+	// Optional: Times of day advance notifications may be delivered, in MESSAGE_TIMEZONE
+	// (default: any time)
+	if windowStr := getenv("ADVANCE_NOTIFICATION_WINDOW"); windowStr != "" {
+		window, err := parseDailyWindow(windowStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ADVANCE_NOTIFICATION_WINDOW must be a range of times of day (e.g., '08:00-22:00'), got: %s", windowStr))
+		} else if len(cfg.AdvanceNotificationTimes) == 0 {
+			errs = append(errs, fmt.Errorf("ADVANCE_NOTIFICATION_WINDOW requires ADVANCE_NOTIFICATION_TIME to be set"))
 		}
-		cfg.AdvanceNotificationTime = advanceTime
-		log.Printf("Advance notification time: %v", advanceTime)
+		cfg.AdvanceNotificationWindow = &window
+	}
+
+	// Optional: Time before the end of a shift to send a reminder (default: disabled/0 if not set)
+	if endingTimeStr := getenv("SHIFT_ENDING_NOTIFICATION_TIME"); endingTimeStr != "" {
+		endingTime, err := time.ParseDuration(endingTimeStr)
+		if err != nil || endingTime <= 0 {
+			errs = append(errs, fmt.Errorf("SHIFT_ENDING_NOTIFICATION_TIME must be a positive duration (e.g., '1h', '30m'), got: %s", endingTimeStr))
+		}
+		cfg.ShiftEndingNotificationTime = endingTime
+	}
+
+	// Optional: Scheduled Advance Notifications (ntfy only, default: false)
+	if scheduledStr := getenv("NTFY_SCHEDULED_REMINDERS"); scheduledStr != "" {
+		scheduled, err := strconv.ParseBool(scheduledStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("NTFY_SCHEDULED_REMINDERS must be a boolean (true/false): %w", err))
+		}
+		if scheduled && cfg.NotificationBackend != BackendNtfy {
+			errs = append(errs, fmt.Errorf("NTFY_SCHEDULED_REMINDERS requires the ntfy backend"))
+		}
+		if scheduled && len(cfg.AdvanceNotificationTimes) == 0 {
+			errs = append(errs, fmt.Errorf("NTFY_SCHEDULED_REMINDERS requires ADVANCE_NOTIFICATION_TIME to be set"))
+		}
+		cfg.ScheduledAdvanceNotifications = scheduled
 	}
 
 	// Optional: Shift End Notifications Enabled (default: true)
 	cfg.ShiftEndNotificationsEnabled = true
-	if shiftEndEnabledStr := os.Getenv("SHIFT_END_NOTIFICATIONS_ENABLED"); shiftEndEnabledStr != "" {
+	if shiftEndEnabledStr := getenv("SHIFT_END_NOTIFICATIONS_ENABLED"); shiftEndEnabledStr != "" {
 		enabled, err := strconv.ParseBool(shiftEndEnabledStr)
 		if err != nil {
-			return nil, fmt.Errorf("SHIFT_END_NOTIFICATIONS_ENABLED must be a boolean (true/false): %w", err)
+			errs = append(errs, fmt.Errorf("SHIFT_END_NOTIFICATIONS_ENABLED must be a boolean (true/false): %w", err))
 		}
 		cfg.ShiftEndNotificationsEnabled = enabled
 	}
 
+	// Optional: Notify when an override removes the user from an upcoming shift (default: false)
+	if overrideEnabledStr := getenv("OVERRIDE_NOTIFICATIONS_ENABLED"); overrideEnabledStr != "" {
+		enabled, err := strconv.ParseBool(overrideEnabledStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OVERRIDE_NOTIFICATIONS_ENABLED must be a boolean (true/false): %w", err))
+		}
+		cfg.OverrideNotificationsEnabled = enabled
+	}
+
+	// Optional: Summarise open incidents in shift-started notifications (default: false)
+	if incidentsStr := getenv("SHIFT_START_INCIDENT_SUMMARY"); incidentsStr != "" {
+		enabled, err := strconv.ParseBool(incidentsStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SHIFT_START_INCIDENT_SUMMARY must be a boolean (true/false): %w", err))
+		}
+		cfg.ShiftStartIncidentSummary = enabled
+	}
+
+	// Optional: Notify about shifts missed while the notifier was down (default: false)
+	if catchUpStr := getenv("CATCH_UP_NOTIFICATIONS"); catchUpStr != "" {
+		enabled, err := strconv.ParseBool(catchUpStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CATCH_UP_NOTIFICATIONS must be a boolean (true/false): %w", err))
+		}
+		cfg.CatchUpNotifications = enabled
+	}
+
+	// Optional: Look ahead for gaps in schedule coverage (disabled if not set)
+	if gapStr := getenv("COVERAGE_GAP_LOOKAHEAD"); gapStr != "" {
+		lookahead, err := time.ParseDuration(gapStr)
+		if err != nil || lookahead <= 0 {
+			errs = append(errs, fmt.Errorf("COVERAGE_GAP_LOOKAHEAD must be a positive duration (e.g., '24h', '72h'), got: %s", gapStr))
+		}
+		if cfg.PagerDutyEscalationPolicyID != "" {
+			errs = append(errs, fmt.Errorf("COVERAGE_GAP_LOOKAHEAD requires PD_SCHEDULE_ID or PD_TEAM_ID"))
+		}
+		cfg.CoverageGapLookahead = lookahead
+	}
+
+	// Optional: Notify when the user's shifts in the next N days change (disabled unless set)
+	if daysStr := getenv("SCHEDULE_CHANGE_DAYS"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > pagerduty.MaxShiftListingDays {
+			errs = append(errs, fmt.Errorf("SCHEDULE_CHANGE_DAYS must be an integer between 1 and %d, got: %s", pagerduty.MaxShiftListingDays, daysStr))
+		}
+		cfg.ScheduleChangeDays = days
+	}
+
+	// Optional: Alert when someone other than these users is on call
+	cfg.ExpectedOnCallUsers = splitList(getenv("EXPECTED_ONCALL_USERS"))
+
+	// Optional: Message Locale (default: en)
+	cfg.MessageLocale = notifier.DefaultLocale
+	if localeStr := getenv("MESSAGE_LOCALE"); localeStr != "" {
+		cfg.MessageLocale = notifier.Locale(localeStr)
+		if _, err := notifier.NewMessages(cfg.MessageLocale); err != nil {
+			errs = append(errs, fmt.Errorf("MESSAGE_LOCALE must be one of %v, got: %s", notifier.SupportedLocales(), localeStr))
+		}
+	}
+
+	// Optional: Timezone for times in notifications (default: the schedule's timezone, or UTC)
+	if tzStr := getenv("MESSAGE_TIMEZONE"); tzStr != "" {
+		location, err := time.LoadLocation(tzStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MESSAGE_TIMEZONE must be an IANA timezone name (e.g., 'Europe/Berlin', 'UTC'), got: %s", tzStr))
+		}
+		cfg.MessageTimezone = location
+	}
+
+	// Optional: Log level and format (default: info, text)
+	if levelStr := getenv("LOG_LEVEL"); levelStr != "" {
+		level, err := logging.ParseLevel(levelStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got: %s", levelStr))
+		}
+		cfg.LogLevel = level
+	}
+	cfg.LogFormat = logging.FormatText
+	if formatStr := getenv("LOG_FORMAT"); formatStr != "" {
+		cfg.LogFormat = strings.ToLower(formatStr)
+		if !slices.Contains(logging.Formats, cfg.LogFormat) {
+			errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of %v, got: %s", logging.Formats, formatStr))
+		}
+	}
+
+	// Optional: Audit log of on-call transitions and notification decisions (default: disabled),
+	// rotated at AUDIT_LOG_MAX_SIZE_MB (default: 10) keeping AUDIT_LOG_MAX_BACKUPS (default: 5)
+	cfg.AuditLogPath = getenv("AUDIT_LOG_PATH")
+	cfg.AuditLogMaxSize = 10 << 20
+	if sizeStr := getenv("AUDIT_LOG_MAX_SIZE_MB"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil || size <= 0 {
+			errs = append(errs, fmt.Errorf("AUDIT_LOG_MAX_SIZE_MB must be a positive integer, got: %s", sizeStr))
+		}
+		cfg.AuditLogMaxSize = int64(size) << 20
+	}
+	cfg.AuditLogMaxBackups = 5
+	if backupsStr := getenv("AUDIT_LOG_MAX_BACKUPS"); backupsStr != "" {
+		backups, err := strconv.Atoi(backupsStr)
+		if err != nil || backups < 0 {
+			errs = append(errs, fmt.Errorf("AUDIT_LOG_MAX_BACKUPS must be a non-negative integer, got: %s", backupsStr))
+		}
+		cfg.AuditLogMaxBackups = backups
+	}
+
+	// Optional: HTTP API (disabled unless a listen address is set)
+	cfg.HTTPListenAddr = getenv("HTTP_LISTEN_ADDR")
+	cfg.HTTPAPIToken = getenv("HTTP_API_TOKEN")
+	cfg.HTTPActionToken = getenv("HTTP_ACTION_TOKEN")
+	if allowStr := getenv("HTTP_API_ALLOW_UNAUTHENTICATED"); allowStr != "" {
+		allow, err := strconv.ParseBool(allowStr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_API_ALLOW_UNAUTHENTICATED must be a boolean (true/false): %w", err))
+		}
+		cfg.HTTPAllowUnauthenticated = allow
+	}
+	// An open API lets anyone who can reach it mute the pager, so it has to be asked for
+	if cfg.HTTPListenAddr != "" && cfg.HTTPAPIToken == "" && !cfg.HTTPAllowUnauthenticated {
+		errs = append(errs, fmt.Errorf("HTTP_API_TOKEN is required when HTTP_LISTEN_ADDR is set (set HTTP_API_ALLOW_UNAUTHENTICATED=true to serve the API without a token)"))
+	}
+	// The action token is readable by anyone subscribed to the ntfy topic
+	if cfg.HTTPActionToken != "" && cfg.HTTPActionToken == cfg.HTTPAPIToken {
+		errs = append(errs, fmt.Errorf("HTTP_ACTION_TOKEN must differ from HTTP_API_TOKEN"))
+	}
+	cfg.HTTPPublicURL = getenv("HTTP_PUBLIC_URL")
+	if cfg.HTTPPublicURL != "" {
+		if cfg.HTTPListenAddr == "" {
+			errs = append(errs, fmt.Errorf("HTTP_LISTEN_ADDR environment variable is required when HTTP_PUBLIC_URL is set"))
+		}
+		if u, err := url.Parse(cfg.HTTPPublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("HTTP_PUBLIC_URL must be an absolute URL (e.g., 'https://notifier.example.com'), got: %s", cfg.HTTPPublicURL))
+		}
+		// The ntfy action buttons cannot be given the API token
+		if cfg.NotificationBackend == BackendNtfy && cfg.HTTPAPIToken != "" && cfg.HTTPActionToken == "" {
+			errs = append(errs, fmt.Errorf("HTTP_ACTION_TOKEN is required for the ntfy action buttons when HTTP_PUBLIC_URL and HTTP_API_TOKEN are set"))
+		}
+	}
+
+	// Optional: Read-only token for the calendar feed, which is only served when it is set
+	cfg.CalendarToken = getenv("CALENDAR_TOKEN")
+	if cfg.CalendarToken != "" && (cfg.CalendarToken == cfg.HTTPAPIToken || cfg.CalendarToken == cfg.HTTPActionToken) {
+		errs = append(errs, fmt.Errorf("CALENDAR_TOKEN must differ from HTTP_API_TOKEN and HTTP_ACTION_TOKEN"))
+	}
+
+	// Optional: Weeks of shifts served by the HTTP API's calendar feed (default: 4)
+	cfg.CalendarWeeks = 4
+	if weeksStr := getenv("CALENDAR_WEEKS"); weeksStr != "" {
+		weeks, err := strconv.Atoi(weeksStr)
+		if maxWeeks := pagerduty.MaxShiftListingDays / 7; err != nil || weeks < 1 || weeks > maxWeeks {
+			errs = append(errs, fmt.Errorf("CALENDAR_WEEKS must be between 1 and %d, got: %s", maxWeeks, weeksStr))
+		}
+		cfg.CalendarWeeks = weeks
+	}
+
+	// Optional: pprof debug endpoints (disabled unless a listen address is set)
+	cfg.DebugListenAddr = getenv("DEBUG_LISTEN_ADDR")
+	if cfg.DebugListenAddr != "" && cfg.DebugListenAddr == cfg.HTTPListenAddr {
+		errs = append(errs, fmt.Errorf("DEBUG_LISTEN_ADDR must differ from HTTP_LISTEN_ADDR, got: %s", cfg.DebugListenAddr))
+	}
+
+	// Optional: Prometheus metrics of every monitored user (disabled unless a listen address is set)
+	cfg.MetricsListenAddr = getenv("METRICS_LISTEN_ADDR")
+	if cfg.MetricsListenAddr != "" && (cfg.MetricsListenAddr == cfg.HTTPListenAddr || cfg.MetricsListenAddr == cfg.DebugListenAddr) {
+		errs = append(errs, fmt.Errorf("METRICS_LISTEN_ADDR must differ from HTTP_LISTEN_ADDR and DEBUG_LISTEN_ADDR, got: %s", cfg.MetricsListenAddr))
+	}
+
+	// Optional: Push the same metrics to a Prometheus Pushgateway after a run with --once, grouped
+	// under PUSHGATEWAY_JOB (default: pagerduty-oncall-notifier)
+	cfg.PushgatewayURL = getenv("PUSHGATEWAY_URL")
+	if cfg.PushgatewayURL != "" {
+		if u, err := url.Parse(cfg.PushgatewayURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("PUSHGATEWAY_URL must be an absolute URL (e.g., 'http://pushgateway:9091'), got: %s", cfg.PushgatewayURL))
+		}
+	}
+	cfg.PushgatewayJob = getenv("PUSHGATEWAY_JOB")
+	if cfg.PushgatewayJob == "" {
+		cfg.PushgatewayJob = "pagerduty-oncall-notifier"
+	}
+
+	// Optional: Accept signed PagerDuty V3 webhook events on the HTTP API to check immediately
+	cfg.PagerDutyWebhookSecret = getenv("PD_WEBHOOK_SECRET")
+	if cfg.PagerDutyWebhookSecret != "" && cfg.HTTPListenAddr == "" {
+		errs = append(errs, fmt.Errorf("HTTP_LISTEN_ADDR environment variable is required when PD_WEBHOOK_SECRET is set"))
+	}
+
+	// Optional: Poll less often while webhook events trigger checks, only reconciling missed
+	// deliveries and schedule changes (default: CHECK_INTERVAL)
+	if pollStr := getenv("PD_WEBHOOK_POLL_INTERVAL"); pollStr != "" {
+		interval, err := time.ParseDuration(pollStr)
+		switch {
+		case err != nil || interval <= 0:
+			errs = append(errs, fmt.Errorf("PD_WEBHOOK_POLL_INTERVAL must be a positive duration (e.g., '1h', '30m'), got: %s", pollStr))
+		case cfg.PagerDutyWebhookSecret == "":
+			errs = append(errs, fmt.Errorf("PD_WEBHOOK_SECRET environment variable is required when PD_WEBHOOK_POLL_INTERVAL is set"))
+		case !cfg.EventDrivenChecks:
+			// Without them, a shift would only be noticed at the next slow poll
+			errs = append(errs, fmt.Errorf("EVENT_DRIVEN_CHECKS must be enabled when PD_WEBHOOK_POLL_INTERVAL is set"))
+		}
+		cfg.WebhookPollInterval = interval
+	}
+
 	// Optional: State File Path (default: /data/state.json)
-	cfg.StateFilePath = os.Getenv("STATE_FILE_PATH")
+	cfg.StateFilePath = getenv("STATE_FILE_PATH")
 	if cfg.StateFilePath == "" {
 		cfg.StateFilePath = "/data/state.json"
 	}
+	// Optional: State Backend (default: file)
+	cfg.StateBackend = StateBackendFile
+	if backendStr := getenv("STATE_BACKEND"); backendStr != "" {
+		cfg.StateBackend = StateBackend(strings.ToLower(backendStr))
+		switch cfg.StateBackend {
+		case StateBackendFile, StateBackendMemory:
+		case StateBackendPostgres:
+			cfg.StatePostgresURL = getenv("STATE_POSTGRES_URL")
+			if cfg.StatePostgresURL == "" {
+				errs = append(errs, fmt.Errorf("STATE_POSTGRES_URL environment variable is required when STATE_BACKEND=postgres"))
+			}
+			cfg.StatePostgresKey = strings.TrimSpace(getenv("STATE_POSTGRES_KEY"))
+		case StateBackendS3:
+			cfg.StateS3Bucket = getenv("STATE_S3_BUCKET")
+			if cfg.StateS3Bucket == "" {
+				errs = append(errs, fmt.Errorf("STATE_S3_BUCKET environment variable is required when STATE_BACKEND=s3"))
+			}
+			cfg.StateS3Key = getenv("STATE_S3_KEY")
+			if cfg.StateS3Key == "" {
+				cfg.StateS3Key = "state.json"
+			}
+			cfg.StateS3Region = getenv("STATE_S3_REGION")
+			cfg.StateS3Endpoint = getenv("STATE_S3_ENDPOINT")
+			if cfg.StateS3Endpoint != "" {
+				endpoint, err := url.Parse(cfg.StateS3Endpoint)
+				if err != nil || !endpoint.IsAbs() || endpoint.Host == "" {
+					errs = append(errs, fmt.Errorf("STATE_S3_ENDPOINT must be an absolute URL, got: %s", cfg.StateS3Endpoint))
+				}
+			}
+			if pathStyleStr := getenv("STATE_S3_PATH_STYLE"); pathStyleStr != "" {
+				pathStyle, err := strconv.ParseBool(pathStyleStr)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("STATE_S3_PATH_STYLE must be a boolean (true/false): %w", err))
+				}
+				cfg.StateS3PathStyle = pathStyle
+			}
+		default:
+			errs = append(errs, fmt.Errorf("STATE_BACKEND must be 'file', 'memory', 'postgres' or 's3', got: %s", backendStr))
+		}
+	}
 
+	// Optional: Key to encrypt persisted state with, inline or in a file
+	encryptionKey := getenv("STATE_ENCRYPTION_KEY")
+	cfg.StateEncryptionKeyFile = getenv("STATE_ENCRYPTION_KEY_FILE")
+	if keyFile := cfg.StateEncryptionKeyFile; keyFile != "" {
+		if encryptionKey != "" {
+			errs = append(errs, fmt.Errorf("STATE_ENCRYPTION_KEY and STATE_ENCRYPTION_KEY_FILE cannot both be set"))
+		}
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read STATE_ENCRYPTION_KEY_FILE: %w", err))
+		}
+		encryptionKey = strings.TrimSpace(string(data))
+	}
+	if encryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(encryptionKey)
+		if err != nil || len(key) != 32 {
+			errs = append(errs, fmt.Errorf("state encryption key must be 32 bytes encoded as base64 (e.g. from `openssl rand -base64 32`)"))
+		}
+		cfg.StateEncryptionKey = key
+	}
+
+	for i := range cfg.Users {
+		cfg.Users[i].StateFilePath = cfg.StateFilePath
+		cfg.Users[i].StateKey = "default"
+		if cfg.StatePostgresKey != "" {
+			cfg.Users[i].StateKey = cfg.StatePostgresKey
+		}
+		// Keep each profile's and user's state separate, e.g. /data/state-PXXXXXX.json,
+		// /data/state-acme.json, or /data/state-team-a.json
+		var names []string
+		if cfg.Profile != "" {
+			names = append(names, cfg.Profile)
+		}
+		if len(cfg.Users) > 1 {
+			name := cfg.Users[i].UserID
+			if cfg.Users[i].Account != "" {
+				name = cfg.Users[i].Account
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			name := strings.Join(names, "-")
+			ext := filepath.Ext(cfg.StateFilePath)
+			cfg.Users[i].StateFilePath = strings.TrimSuffix(cfg.StateFilePath, ext) + "-" + name + ext
+			cfg.Users[i].StateKey = name
+			if cfg.StatePostgresKey != "" {
+				cfg.Users[i].StateKey = cfg.StatePostgresKey + "-" + name
+			}
+		}
+	}
+
+	// The HTTP API and remote commands act on a single user's state
+	if len(cfg.Users) > 1 && (cfg.HTTPListenAddr != "" || cfg.NtfyControlTopic != "") {
+		errs = append(errs, fmt.Errorf("HTTP_LISTEN_ADDR and NTFY_CONTROL_TOPIC are not supported when monitoring multiple users"))
+	}
+	// A user token can only set its owner's status
+	if len(cfg.Users) > 1 && cfg.SlackUserToken != "" {
+		errs = append(errs, fmt.Errorf("SLACK_USER_TOKEN is not supported when monitoring multiple users; use a profile per user"))
+	}
+
+	if len(errs) > 0 {
+		// Settings such as PD_USERS report each invalid entry
+		return nil, errors.Join(unwrapJoined(errors.Join(errs...))...)
+	}
 	return cfg, nil
 }
+
+// parseUserRoutes parses a list of users from the named environment variable as user=target entries
+// separated by semicolons (e.g., "PABC123=alice;PDEF456=bob"). The target is optional.
+func parseUserRoutes(name string) ([]UserRoute, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var routes []UserRoute
+	var errs []error
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		userID, target, _ := strings.Cut(entry, "=")
+		userID = strings.TrimSpace(userID)
+		if userID == "" {
+			errs = append(errs, fmt.Errorf("%s entries must be in user=target form, got: %s", name, entry))
+			continue
+		}
+		if slices.ContainsFunc(routes, func(r UserRoute) bool { return r.UserID == userID }) {
+			errs = append(errs, fmt.Errorf("%s lists user %s more than once", name, userID))
+			continue
+		}
+		routes = append(routes, UserRoute{UserID: userID, Target: strings.TrimSpace(target)})
+	}
+
+	return routes, errors.Join(errs...)
+}
+
+// parseAccounts reads the account names listed in the named environment variable and the
+// settings of each from PD_ACCOUNT_<NAME>_API_TOKEN, _SCHEDULE_ID, _USER_ID, and _TARGET.
+// Every invalid account is reported; the accounts are returned even then, so that settings
+// that depend on PD_ACCOUNTS being set are not reported as well.
+func parseAccounts(name string) ([]UserRoute, error) {
+	var routes []UserRoute
+	var errs []error
+	for _, account := range splitList(getenv(name)) {
+		if !accountNamePattern.MatchString(account) {
+			errs = append(errs, fmt.Errorf("%s names may only contain letters, digits, and underscores, got: %s", name, account))
+			continue
+		}
+		if slices.ContainsFunc(routes, func(r UserRoute) bool { return strings.EqualFold(r.Account, account) }) {
+			errs = append(errs, fmt.Errorf("%s lists account %s more than once", name, account))
+			continue
+		}
+		prefix := "PD_ACCOUNT_" + strings.ToUpper(account) + "_"
+		route := UserRoute{
+			Account:    account,
+			APIToken:   getenv(prefix + "API_TOKEN"),
+			ScheduleID: getenv(prefix + "SCHEDULE_ID"),
+			UserID:     getenv(prefix + "USER_ID"),
+			Target:     strings.TrimSpace(getenv(prefix + "TARGET")),
+		}
+		if route.APIToken == "" || route.ScheduleID == "" {
+			errs = append(errs, fmt.Errorf("%sAPI_TOKEN and %sSCHEDULE_ID are required for account %s", prefix, prefix, account))
+		}
+		routes = append(routes, route)
+	}
+	return routes, errors.Join(errs...)
+}
+
+// parseEventOverrides parses a list of event=value pairs separated by sep from the named environment variable
+// (e.g., "shift_started=max,upcoming_shift=min"). If allowed is non-empty, values must be one of its entries.
+func parseEventOverrides(name, sep string, allowed []string) (notifier.EventOverrides, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	overrides := notifier.EventOverrides{}
+	var errs []error
+	for _, pair := range strings.Split(raw, sep) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s entries must be in event=value form, got: %s", name, pair))
+			continue
+		}
+		event, err := notifier.ParseEvent(strings.TrimSpace(key))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(allowed) > 0 && !slices.Contains(allowed, value) {
+			errs = append(errs, fmt.Errorf("%s value for %s must be one of %v, got: %s", name, event, allowed, value))
+			continue
+		}
+		overrides[event] = value
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return overrides, nil
+}
+
+// parseTimeOfDay parses a 24-hour HH:MM time of day
+func parseTimeOfDay(raw string) (TimeOfDay, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return TimeOfDay{}, err
+	}
+	return TimeOfDay{Hour: t.Hour(), Minute: t.Minute()}, nil
+}
+
+// parseDailyWindow parses a range of times of day such as 08:00-22:00; the start and end must differ
+func parseDailyWindow(raw string) (DailyWindow, error) {
+	startStr, endStr, ok := strings.Cut(raw, "-")
+	if !ok {
+		return DailyWindow{}, fmt.Errorf("missing '-' between start and end")
+	}
+	start, err := parseTimeOfDay(startStr)
+	if err != nil {
+		return DailyWindow{}, err
+	}
+	end, err := parseTimeOfDay(endStr)
+	if err != nil {
+		return DailyWindow{}, err
+	}
+	if start == end {
+		return DailyWindow{}, fmt.Errorf("start and end are the same")
+	}
+	return DailyWindow{Start: start, End: end}, nil
+}
+
+// parseTimeout parses the named request timeout, defaulting to notifier.DefaultTimeout
+func parseTimeout(name string) (time.Duration, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return notifier.DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		return notifier.DefaultTimeout, fmt.Errorf("%s must be a positive duration (e.g., '10s', '1m'), got: %s", name, raw)
+	}
+	return timeout, nil
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty entries
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fileValues holds the settings read from CONFIG_FILE by the last Load; they take precedence
+// over the process environment so that editing the file and reloading applies the edit
+var fileValues map[string]string
+
+// fileProfile is a [name] section of CONFIG_FILE, whose settings override those outside any
+// section for that profile
+type fileProfile struct {
+	name   string
+	values map[string]string
+}
+
+// fileProfiles holds the profiles defined in CONFIG_FILE, in the order they appear
+var fileProfiles []fileProfile
+
+// profileValues holds the settings of the profile being loaded
+var profileValues map[string]string
+
+// selectedProfile is the profile Load and LoadAll are limited to, if set
+var selectedProfile string
+
+// SelectProfile limits Load and LoadAll to the named CONFIG_FILE profile
+func SelectProfile(name string) {
+	selectedProfile = name
+}
+
+// findProfile returns the CONFIG_FILE profile with the given name
+func findProfile(name string) (fileProfile, error) {
+	for _, profile := range fileProfiles {
+		if profile.name == name {
+			return profile, nil
+		}
+	}
+	return fileProfile{}, fmt.Errorf("profile %s is not defined in CONFIG_FILE", name)
+}
+
+// errProfileRequired is returned when CONFIG_FILE defines several profiles and a command acts
+// on just one
+func errProfileRequired() error {
+	var names []string
+	for _, profile := range fileProfiles {
+		names = append(names, profile.name)
+	}
+	return fmt.Errorf("CONFIG_FILE defines profiles %s; select one with --profile", strings.Join(names, ", "))
+}
+
+// profileNamePattern matches the names of CONFIG_FILE profiles, which become part of state
+// file names and keys
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// getenv returns the setting named key from the command-line flags, CONFIG_FILE, or the
+// environment, in that order. A secretmanager:// reference is replaced by the secret it refers to.
+func getenv(key string) string {
+	value, _ := lookup(key)
+	if secret, ok := secretValues[value]; ok {
+		return secret
+	}
+	return value
+}
+
+// lookup returns the raw value of the setting named key and where it was found, or an empty
+// source if it is not set
+func lookup(key string) (string, Source) {
+	if value, ok := flagValues[key]; ok {
+		return value, SourceFlag
+	}
+	if value, ok := profileValues[key]; ok {
+		return value, SourceProfile
+	}
+	if value, ok := fileValues[key]; ok {
+		return value, SourceFile
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value, SourceEnv
+	}
+	return "", ""
+}
+
+// loadConfigFile reads CONFIG_FILE, if it is set, for getenv
+func loadConfigFile() error {
+	fileValues, fileProfiles, profileValues = nil, nil, nil
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	values, profiles, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	fileValues, fileProfiles = values, profiles
+	return nil
+}
+
+// readConfigFile parses a file of KEY=VALUE lines in the format used by Docker's --env-file:
+// blank lines and lines starting with # are ignored, an optional "export " prefix is allowed,
+// and values may be wrapped in single or double quotes. A [name] line starts a profile; the
+// lines after it belong to that profile rather than to every profile.
+func readConfigFile(path string) (map[string]string, []fileProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+	defer f.Close()
+
+	shared := map[string]string{}
+	values := shared
+	var profiles []fileProfile
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if !profileNamePattern.MatchString(name) {
+				return nil, nil, fmt.Errorf("CONFIG_FILE %s line %d: profile names may only contain letters, digits, dashes, and underscores, got: %s", path, lineNo, name)
+			}
+			if slices.ContainsFunc(profiles, func(p fileProfile) bool { return p.name == name }) {
+				return nil, nil, fmt.Errorf("CONFIG_FILE %s line %d: profile %s is defined more than once", path, lineNo, name)
+			}
+			values = map[string]string{}
+			profiles = append(profiles, fileProfile{name: name, values: values})
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("CONFIG_FILE %s line %d must be KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("CONFIG_FILE %s line %d has an invalid quoted value: %w", path, lineNo, err)
+			}
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+	return shared, profiles, nil
+}
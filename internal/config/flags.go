@@ -0,0 +1,51 @@
+package config
+
+import "strings"
+
+// Settings lists the names of the settings Load reads, each of which can also be given as a
+// command-line flag. The per-account PD_ACCOUNT_<NAME>_* settings are not included.
+var Settings = []string{
+	"PD_API_TOKEN", "PD_API_TOKEN_FILE", "PD_API_URL", "PD_REGION", "PD_OAUTH_CLIENT_ID",
+	"PD_OAUTH_CLIENT_SECRET", "PD_OAUTH_SUBDOMAIN", "PD_OAUTH_SCOPES", "PD_PROXY_URL",
+	"PD_TLS_CERT_FILE", "PD_TLS_KEY_FILE", "PD_TLS_CA_FILE", "PD_MAX_PAGES", "PD_RETRY_MAX_ELAPSED",
+	"PD_SHIFT_CACHE_TTL", "PD_MAX_ESCALATION_LEVEL", "PD_SCHEDULE_ID", "PD_ESCALATION_POLICY_ID",
+	"PD_TEAM_ID", "PD_SCHEDULE_SOURCE", "PD_SCHEDULE_LAYERS", "PD_STARTUP_VALIDATION", "PD_USER_ID",
+	"PD_USERS", "PD_ACCOUNTS",
+	"NOTIFICATION_BACKEND", "NOTIFICATION_WEBHOOK_URL", "WEBHOOK_URLS", "WEBHOOK_FORMAT",
+	"WEBHOOK_TEMPLATE", "WEBHOOK_TEMPLATE_FILE", "WEBHOOK_METHOD", "WEBHOOK_ENCODING",
+	"WEBHOOK_TLS_CERT_FILE", "WEBHOOK_TLS_KEY_FILE", "WEBHOOK_TLS_CA_FILE", "WEBHOOK_TIMEOUT",
+	"NTFY_SERVER_URL", "NTFY_TOPIC", "NTFY_API_KEY", "NTFY_PRIORITIES", "NTFY_EMAIL", "NTFY_TAGS",
+	"NTFY_CONTROL_TOPIC", "NTFY_CONTROL_SECRET", "NTFY_MARKDOWN", "NTFY_SCHEDULED_REMINDERS", "NTFY_TIMEOUT",
+	"PUSHOVER_APP_TOKEN", "PUSHOVER_USER_KEY", "PUSHOVER_DEVICE", "PUSHOVER_SOUND", "PUSHOVER_SOUNDS",
+	"PUSHOVER_TTL", "PUSHOVER_PRIORITIES", "PUSHOVER_HTML", "PUSHOVER_GLANCES", "PUSHOVER_URL",
+	"PUSHOVER_TIMEOUT",
+	"CHECK_INTERVAL", "CHECK_INTERVAL_JITTER", "EVENT_DRIVEN_CHECKS",
+	"DEGRADED_ALERT_THRESHOLD", "DEGRADED_ALERT_INTERVAL", "HEARTBEAT_URL", "SENTRY_DSN", "SENTRY_ENVIRONMENT",
+	"GRAFANA_URL", "GRAFANA_API_TOKEN", "GRAFANA_ANNOTATION_TAGS", "SLACK_USER_TOKEN", "SLACK_STATUS_EMOJI",
+	"DAILY_SUMMARY_TIME",
+	"ADVANCE_NOTIFICATION_TIME", "ADVANCE_NOTIFICATION_WINDOW", "SHIFT_ENDING_NOTIFICATION_TIME",
+	"SHIFT_END_NOTIFICATIONS_ENABLED", "OVERRIDE_NOTIFICATIONS_ENABLED",
+	"SHIFT_START_INCIDENT_SUMMARY", "CATCH_UP_NOTIFICATIONS",
+	"COVERAGE_GAP_LOOKAHEAD", "SCHEDULE_CHANGE_DAYS",
+	"EXPECTED_ONCALL_USERS", "MESSAGE_LOCALE", "MESSAGE_TIMEZONE", "LOG_LEVEL", "LOG_FORMAT",
+	"AUDIT_LOG_PATH", "AUDIT_LOG_MAX_SIZE_MB", "AUDIT_LOG_MAX_BACKUPS",
+	"HTTP_LISTEN_ADDR", "HTTP_API_TOKEN", "HTTP_ACTION_TOKEN", "HTTP_API_ALLOW_UNAUTHENTICATED", "HTTP_PUBLIC_URL", "PD_WEBHOOK_SECRET", "PD_WEBHOOK_POLL_INTERVAL", "CALENDAR_TOKEN", "CALENDAR_WEEKS", "DEBUG_LISTEN_ADDR",
+	"METRICS_LISTEN_ADDR", "PUSHGATEWAY_URL", "PUSHGATEWAY_JOB",
+	"STATE_FILE_PATH", "STATE_BACKEND", "STATE_POSTGRES_URL", "STATE_POSTGRES_KEY", "STATE_S3_BUCKET", "STATE_S3_KEY",
+	"STATE_S3_REGION", "STATE_S3_ENDPOINT", "STATE_S3_PATH_STYLE", "STATE_ENCRYPTION_KEY",
+	"STATE_ENCRYPTION_KEY_FILE",
+}
+
+// flagValues holds the settings given as command-line flags; they take precedence over both
+// CONFIG_FILE and the environment
+var flagValues map[string]string
+
+// FlagName returns the command-line flag for a setting, e.g. --check-interval for CHECK_INTERVAL
+func FlagName(setting string) string {
+	return strings.ReplaceAll(strings.ToLower(setting), "_", "-")
+}
+
+// SetFlagValues sets the settings given as command-line flags for Load, keyed by setting name
+func SetFlagValues(values map[string]string) {
+	flagValues = values
+}
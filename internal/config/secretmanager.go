@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+)
+
+// secretManagerScheme prefixes setting values that are references to GCP Secret Manager secrets
+const secretManagerScheme = "secretmanager://"
+
+// secretManagerTimeout bounds the lookup of each secret at startup or reload
+const secretManagerTimeout = 10 * time.Second
+
+// secretManagerAPIURL is the Secret Manager REST endpoint secrets are read from
+var secretManagerAPIURL = "https://secretmanager.googleapis.com/v1/"
+
+// secretManagerClient returns the HTTP client authenticated to Secret Manager, replaced in tests
+var secretManagerClient = func(ctx context.Context) (*http.Client, error) {
+	return google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+}
+
+// secretReferencePattern matches secretmanager://projects/<project>/secrets/<name>, optionally
+// followed by /versions/<version>
+var secretReferencePattern = regexp.MustCompile(`^projects/[^/]+/secrets/[^/]+(/versions/[^/]+)?$`)
+
+// secretValues maps each secretmanager:// reference found by the last Load to the secret it
+// refers to
+var secretValues map[string]string
+
+// resolveSecrets reads every secret referenced by a setting in the environment or CONFIG_FILE
+// from GCP Secret Manager, authenticating with Application Default Credentials (workload
+// identity on GKE and Cloud Run, or GOOGLE_APPLICATION_CREDENTIALS elsewhere)
+func resolveSecrets() error {
+	secretValues = nil
+	refs := map[string]string{}
+	for _, entry := range os.Environ() {
+		if key, value, _ := strings.Cut(entry, "="); strings.HasPrefix(value, secretManagerScheme) {
+			refs[value] = key
+		}
+	}
+	for _, values := range []map[string]string{fileValues, profileValues, flagValues} {
+		for key, value := range values {
+			if strings.HasPrefix(value, secretManagerScheme) {
+				refs[value] = key
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	client, err := secretManagerClient(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to GCP Secret Manager: %w", err)
+	}
+
+	secretValues = map[string]string{}
+	var errs []error
+	for ref, key := range refs {
+		value, err := accessSecret(client, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		secretValues[ref] = value
+	}
+	return errors.Join(errs...)
+}
+
+// accessSecret reads the secret version a secretmanager:// reference names, or the latest
+// version if it names none
+func accessSecret(client *http.Client, ref string) (string, error) {
+	name := strings.TrimPrefix(ref, secretManagerScheme)
+	if !secretReferencePattern.MatchString(name) {
+		return "", fmt.Errorf("secret reference must be secretmanager://projects/<project>/secrets/<name>[/versions/<version>], got: %s", ref)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretManagerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretManagerAPIURL+name+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Secret Manager request: %w", err)
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to read secret %s: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Payload struct {
+			Data []byte `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode secret %s: %w", name, err)
+	}
+	// Secrets created from files often end with a newline
+	return strings.TrimSpace(string(result.Payload.Data)), nil
+}
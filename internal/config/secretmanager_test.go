@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSecretManager serves the secrets in versions, keyed by resource name, as Secret Manager does
+func newSecretManager(t *testing.T, versions map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")
+		value, ok := versions[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"status": "NOT_FOUND"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"payload": map[string]any{"data": []byte(value)}})
+	}))
+	t.Cleanup(server.Close)
+
+	apiURL, newClient := secretManagerAPIURL, secretManagerClient
+	secretManagerAPIURL = server.URL + "/v1/"
+	secretManagerClient = func(ctx context.Context) (*http.Client, error) {
+		return server.Client(), nil
+	}
+	t.Cleanup(func() {
+		secretManagerAPIURL, secretManagerClient = apiURL, newClient
+		secretValues = nil
+	})
+	return server
+}
+
+func TestAccessSecret(t *testing.T) {
+	server := newSecretManager(t, map[string]string{
+		"projects/acme/secrets/pd-token/versions/latest": "latest-token\n",
+		"projects/acme/secrets/pd-token/versions/3":      "old-token",
+	})
+
+	for ref, want := range map[string]string{
+		"secretmanager://projects/acme/secrets/pd-token":            "latest-token",
+		"secretmanager://projects/acme/secrets/pd-token/versions/3": "old-token",
+	} {
+		got, err := accessSecret(server.Client(), ref)
+		if err != nil {
+			t.Fatalf("%s: accessSecret returned error: %v", ref, err)
+		}
+		if got != want {
+			t.Fatalf("%s: expected %q, got %q", ref, want, got)
+		}
+	}
+
+	if _, err := accessSecret(server.Client(), "secretmanager://pd-token"); err == nil || !strings.Contains(err.Error(), "must be secretmanager://projects/") {
+		t.Fatalf("expected an error for an invalid reference, got %v", err)
+	}
+	if _, err := accessSecret(server.Client(), "secretmanager://projects/acme/secrets/missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("expected an error for a missing secret, got %v", err)
+	}
+}
+
+func TestResolveSecretsReplacesReferences(t *testing.T) {
+	newSecretManager(t, map[string]string{"projects/acme/secrets/pd-token/versions/latest": "resolved-token"})
+	t.Setenv("PAGERDUTY_API_TOKEN", "secretmanager://projects/acme/secrets/pd-token")
+	t.Setenv("NTFY_TOPIC", "plain-topic")
+
+	if err := resolveSecrets(); err != nil {
+		t.Fatalf("resolveSecrets returned error: %v", err)
+	}
+	if got := getenv("PAGERDUTY_API_TOKEN"); got != "resolved-token" {
+		t.Fatalf("expected the secret to replace the reference, got %q", got)
+	}
+	if got := getenv("NTFY_TOPIC"); got != "plain-topic" {
+		t.Fatalf("expected other settings to be left alone, got %q", got)
+	}
+
+	// Every failed reference is reported with the setting it came from
+	t.Setenv("NTFY_API_KEY", "secretmanager://projects/acme/secrets/missing")
+	err := resolveSecrets()
+	if err == nil || !strings.Contains(err.Error(), "NTFY_API_KEY") {
+		t.Fatalf("expected an error naming NTFY_API_KEY, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Source says where the value of a setting was found
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceProfile Source = "profile"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+)
+
+// EffectiveSetting is a setting as Load sees it after flags, CONFIG_FILE, and the environment
+// are merged
+type EffectiveSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// Effective returns every setting that is set, with the value that takes precedence and where
+// it came from. Secrets are masked, and secretmanager:// references are shown unresolved.
+func Effective() ([]EffectiveSetting, error) {
+	if err := loadConfigFile(); err != nil {
+		return nil, err
+	}
+	switch {
+	case selectedProfile != "":
+		profile, err := findProfile(selectedProfile)
+		if err != nil {
+			return nil, err
+		}
+		profileValues = profile.values
+		defer func() { profileValues = nil }()
+	case len(fileProfiles) > 1:
+		return nil, errProfileRequired()
+	case len(fileProfiles) == 1:
+		profileValues = fileProfiles[0].values
+		defer func() { profileValues = nil }()
+	}
+
+	var settings []EffectiveSetting
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		settings = append(settings, EffectiveSetting{Name: "CONFIG_FILE", Value: path, Source: SourceEnv})
+	}
+	names := slices.Clone(Settings)
+	for _, account := range splitList(getenv("PD_ACCOUNTS")) {
+		prefix := "PD_ACCOUNT_" + strings.ToUpper(account) + "_"
+		names = append(names, prefix+"API_TOKEN", prefix+"SCHEDULE_ID", prefix+"USER_ID", prefix+"TARGET")
+	}
+	for _, name := range names {
+		value, source := lookup(name)
+		if source == "" {
+			continue
+		}
+		if !strings.HasPrefix(value, secretManagerScheme) {
+			value = redact(name, value)
+		}
+		settings = append(settings, EffectiveSetting{Name: name, Value: value, Source: source})
+	}
+	return settings, nil
+}
+
+// redact masks the value of a secret setting, keeping the last four characters of long values
+// so that different tokens can still be told apart. URLs keep their scheme and host; their
+// credentials, and for webhooks and heartbeats their path, are masked.
+func redact(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case strings.HasSuffix(name, "_TOKEN"), strings.HasSuffix(name, "_SECRET"),
+		strings.HasSuffix(name, "_API_KEY"), strings.HasSuffix(name, "_USER_KEY"),
+		name == "STATE_ENCRYPTION_KEY":
+		if len(value) < 12 {
+			return "****"
+		}
+		return "****" + value[len(value)-4:]
+	case name == "NOTIFICATION_WEBHOOK_URL", name == "HEARTBEAT_URL", strings.HasSuffix(name, "_TARGET"):
+		return redactWebhookURL(value)
+	case name == "WEBHOOK_URLS", name == "PD_USERS":
+		// Lists of event=URL or user=target entries
+		sep := ","
+		if name == "PD_USERS" {
+			sep = ";"
+		}
+		entries := strings.Split(value, sep)
+		for i, entry := range entries {
+			if key, target, ok := strings.Cut(entry, "="); ok {
+				entries[i] = key + "=" + redactWebhookURL(target)
+			}
+		}
+		return strings.Join(entries, sep)
+	case name == "SENTRY_DSN":
+		// The DSN's key is in its user info
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			u.User = url.User("****")
+			return u.String()
+		}
+	case strings.HasSuffix(name, "_URL"):
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+// redactWebhookURL masks the path of a webhook URL, since URLs such as Slack's carry their
+// secret in it. Values that are not URLs, such as ntfy topics, are returned as is.
+func redactWebhookURL(value string) string {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
@@ -0,0 +1,153 @@
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+// ErrUnauthenticated is returned for commands that do not start with the shared secret
+var ErrUnauthenticated = errors.New("command does not start with the control secret")
+
+// ErrNothingToResend is returned by Resend before the first notification has been sent
+var ErrNothingToResend = errors.New("no notification has been sent yet")
+
+// Controller executes remote text commands (e.g. "<secret> mute 2h") against the notifier's state
+type Controller struct {
+	stateManager *state.Manager
+	notifier     func() notifier.Notifier
+	secret       string
+}
+
+// New creates a new command controller that only executes commands prefixed with secret.
+// n is called for each command that sends a notification, so a reloaded notifier is used.
+func New(stateManager *state.Manager, n func() notifier.Notifier, secret string) *Controller {
+	return &Controller{
+		stateManager: stateManager,
+		notifier:     n,
+		secret:       secret,
+	}
+}
+
+// Execute runs a command and returns a human-readable reply. The command's first word must be
+// the shared secret, or ErrUnauthenticated is returned. Notifications it sends are abandoned
+// if ctx is cancelled.
+func (c *Controller) Execute(ctx context.Context, command string) (string, error) {
+	fields := strings.Fields(command)
+	if c.secret == "" || len(fields) == 0 || subtle.ConstantTimeCompare([]byte(fields[0]), []byte(c.secret)) != 1 {
+		return "", ErrUnauthenticated
+	}
+	fields = strings.Fields(strings.ToLower(strings.Join(fields[1:], " ")))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	switch fields[0] {
+	case "mute", "snooze":
+		if len(fields) != 2 {
+			return "", fmt.Errorf("usage: mute <duration>")
+		}
+		return c.mute(fields[1])
+	case "unmute":
+		return c.unmute()
+	case "status":
+		return c.status()
+	case "resend":
+		return c.resend(ctx)
+	case "help":
+		return "Commands: mute <duration>, unmute, status, resend, help", nil
+	default:
+		return "", fmt.Errorf("unknown command: %s (try \"help\")", fields[0])
+	}
+}
+
+// mute silences notifications for the given duration
+func (c *Controller) mute(raw string) (string, error) {
+	duration, err := time.ParseDuration(raw)
+	if err != nil || duration <= 0 {
+		return "", fmt.Errorf("invalid duration %q (e.g., 30m, 2h)", raw)
+	}
+
+	var mutedUntil time.Time
+	err = c.stateManager.Update(func(currentState *state.State) error {
+		c.stateManager.Mute(currentState, duration)
+		mutedUntil = *currentState.MutedUntil
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to mute: %w", err)
+	}
+
+	return fmt.Sprintf("Notifications muted until %s", mutedUntil.Format(time.RFC3339)), nil
+}
+
+// unmute clears any active mute
+func (c *Controller) unmute() (string, error) {
+	err := c.stateManager.Update(func(currentState *state.State) error {
+		c.stateManager.Unmute(currentState)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to unmute: %w", err)
+	}
+
+	return "Notifications unmuted", nil
+}
+
+// status summarizes the persisted state
+func (c *Controller) status() (string, error) {
+	currentState, err := c.stateManager.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load state: %w", err)
+	}
+
+	lines := []string{fmt.Sprintf("On call: %v", currentState.WasOnCall)}
+	if c.stateManager.IsMuted(currentState) {
+		lines = append(lines, fmt.Sprintf("Muted until: %s", currentState.MutedUntil.Format(time.RFC3339)))
+	} else {
+		lines = append(lines, "Muted: no")
+	}
+	if pause := c.stateManager.ActivePause(currentState); pause != nil {
+		lines = append(lines, fmt.Sprintf("Paused until: %s", pause.Until.Format(time.RFC3339)))
+	}
+	if last := currentState.LastNotification; last != nil {
+		lines = append(lines, fmt.Sprintf("Last notification: %s at %s", last.Event, last.SentAt.Format(time.RFC3339)))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// resend sends the most recent notification again
+func (c *Controller) resend(ctx context.Context) (string, error) {
+	event, err := Resend(ctx, c.stateManager, c.notifier())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Resent %s notification", event), nil
+}
+
+// Resend sends the most recent notification recorded in the state again with n, returning
+// its event, or ErrNothingToResend if no notification has been sent yet
+func Resend(ctx context.Context, stateManager *state.Manager, n notifier.Notifier) (notifier.NotificationEvent, error) {
+	currentState, err := stateManager.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load state: %w", err)
+	}
+
+	last := currentState.LastNotification
+	if last == nil {
+		return "", ErrNothingToResend
+	}
+
+	event := notifier.NotificationEvent(last.Event)
+	if err := n.NotifyWithEvent(ctx, event, last.ShiftStartTime); err != nil {
+		return "", fmt.Errorf("failed to resend %s notification: %w", last.Event, err)
+	}
+	return event, nil
+}
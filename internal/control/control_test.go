@@ -0,0 +1,127 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+type recordingNotifier struct {
+	events []notifier.NotificationEvent
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, message string) error {
+	return nil
+}
+
+func (r *recordingNotifier) NotifyWithEvent(ctx context.Context, event notifier.NotificationEvent, shiftStartTime time.Time) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+// notifierFunc returns a function resolving to n, as the controller expects
+func notifierFunc(n notifier.Notifier) func() notifier.Notifier {
+	return func() notifier.Notifier { return n }
+}
+
+func TestExecuteMuteAndStatus(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	controller := New(stateManager, notifierFunc(&recordingNotifier{}), "s3cret")
+
+	if _, err := controller.Execute(context.Background(), "s3cret mute 2h"); err != nil {
+		t.Fatalf("mute returned error: %v", err)
+	}
+
+	reply, err := controller.Execute(context.Background(), "s3cret STATUS")
+	if err != nil {
+		t.Fatalf("status returned error: %v", err)
+	}
+	if !strings.Contains(reply, "Muted until") {
+		t.Fatalf("expected status to report mute, got %q", reply)
+	}
+
+	if _, err := controller.Execute(context.Background(), "s3cret unmute"); err != nil {
+		t.Fatalf("unmute returned error: %v", err)
+	}
+	reply, _ = controller.Execute(context.Background(), "s3cret status")
+	if !strings.Contains(reply, "Muted: no") {
+		t.Fatalf("expected status to report unmuted, got %q", reply)
+	}
+}
+
+func TestExecuteResendsLastNotification(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	n := &recordingNotifier{}
+	controller := New(stateManager, notifierFunc(n), "s3cret")
+
+	if _, err := controller.Execute(context.Background(), "s3cret resend"); err == nil {
+		t.Fatalf("expected error when nothing has been sent")
+	}
+
+	if err := stateManager.Update(func(currentState *state.State) error {
+		stateManager.RecordNotificationSent(currentState, "ntfy", string(notifier.EventShiftStarted), time.Now())
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	if _, err := controller.Execute(context.Background(), "s3cret resend"); err != nil {
+		t.Fatalf("resend returned error: %v", err)
+	}
+	if len(n.events) != 1 || n.events[0] != notifier.EventShiftStarted {
+		t.Fatalf("expected shift started to be resent, got %v", n.events)
+	}
+}
+
+func TestExecuteRejectsUnknownCommand(t *testing.T) {
+	controller := New(state.NewManager(filepath.Join(t.TempDir(), "state.json")), notifierFunc(&recordingNotifier{}), "s3cret")
+	if _, err := controller.Execute(context.Background(), "s3cret reboot"); err == nil {
+		t.Fatalf("expected error for unknown command")
+	}
+}
+
+func TestExecuteRejectsUnauthenticatedCommand(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	controller := New(stateManager, notifierFunc(&recordingNotifier{}), "s3cret")
+
+	for _, command := range []string{"mute 2h", "wrong mute 2h", "S3CRET mute 2h", ""} {
+		if _, err := controller.Execute(context.Background(), command); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%q: expected ErrUnauthenticated, got %v", command, err)
+		}
+	}
+	loaded, err := stateManager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if stateManager.IsMuted(loaded) {
+		t.Fatalf("expected an unauthenticated command not to mute")
+	}
+}
+
+func TestExecuteUsesCurrentNotifier(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	if err := stateManager.Update(func(currentState *state.State) error {
+		stateManager.RecordNotificationSent(currentState, "ntfy", string(notifier.EventShiftStarted), time.Now())
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	startup, reloaded := &recordingNotifier{}, &recordingNotifier{}
+	current := startup
+	controller := New(stateManager, func() notifier.Notifier { return current }, "s3cret")
+
+	// A configuration reload replaces the notifier after the controller was created
+	current = reloaded
+	if _, err := controller.Execute(context.Background(), "s3cret resend"); err != nil {
+		t.Fatalf("resend returned error: %v", err)
+	}
+	if len(startup.events) != 0 || len(reloaded.events) != 1 {
+		t.Fatalf("expected the reloaded notifier to resend, got %v and %v", startup.events, reloaded.events)
+	}
+}
@@ -0,0 +1,185 @@
+// Package errorreport sends panics and persistent errors to Sentry, or a service that accepts
+// Sentry DSNs such as GlitchTip, so that crash loops are surfaced instead of buried in logs
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+)
+
+// reportTimeout bounds each report so an unreachable Sentry does not hold up checks or shutdown
+const reportTimeout = 10 * time.Second
+
+// Reporter sends events to the project a Sentry DSN names. A nil Reporter reports nothing, so
+// it can be used as is when no DSN is configured.
+type Reporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	serverName  string
+	client      *http.Client
+}
+
+// New creates a reporter for dsn, e.g. https://<key>@o0.ingest.sentry.io/<project>. Events
+// are tagged with the running version and, if not empty, environment.
+func New(dsn, environment string) (*Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User.Username() == "" {
+		return nil, errors.New("not a Sentry DSN (e.g., 'https://<key>@o0.ingest.sentry.io/<project>')")
+	}
+	project := path.Base(u.Path)
+	if project == "/" || project == "." {
+		return nil, errors.New("missing the project ID (e.g., 'https://<key>@o0.ingest.sentry.io/<project>')")
+	}
+	prefix := strings.TrimSuffix(u.Path, project)
+	serverName, _ := os.Hostname()
+	return &Reporter{
+		endpoint:    u.Scheme + "://" + u.Host + prefix + "api/" + project + "/envelope/",
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", version.UserAgent(), u.User.Username()),
+		environment: environment,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: reportTimeout, Transport: httpclient.WithUserAgent(nil)},
+	}, nil
+}
+
+// event is the subset of the Sentry event payload the notifier fills in
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Release     string            `json:"release"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Exception   *exceptions       `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Report sends msg as an error event. args are key-value pairs as for log/slog: an "error"
+// becomes the event's exception, grouped under msg, and the others become tags.
+func (r *Reporter) Report(ctx context.Context, msg string, args ...any) error {
+	if r == nil {
+		return nil
+	}
+	return r.send(ctx, r.newEvent("error", msg, args))
+}
+
+// Recover reports a panic in progress, then panics again so the process still crashes. It must
+// be deferred directly, e.g. defer reporter.Recover("user_id", userID).
+func (r *Reporter) Recover(args ...any) {
+	if r == nil {
+		return
+	}
+	value := recover()
+	if value == nil {
+		return
+	}
+	e := r.newEvent("fatal", "panic", args)
+	e.Exception = &exceptions{Values: []exception{{Type: "panic", Value: fmt.Sprint(value)}}}
+	e.Extra = map[string]string{"stack": string(debug.Stack())}
+	if err := r.send(context.Background(), e); err != nil {
+		slog.Warn("Failed to report panic", "error", err)
+	}
+	panic(value)
+}
+
+// newEvent builds an event tagged with the build and args
+func (r *Reporter) newEvent(level, msg string, args []any) *event {
+	info := version.Get()
+	e := &event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       level,
+		Platform:    "go",
+		Logger:      "pagerduty-oncall-notifier",
+		Release:     info.Version,
+		Environment: r.environment,
+		ServerName:  r.serverName,
+		Message:     msg,
+		Tags:        map[string]string{"commit": info.Commit, "go_version": info.GoVersion},
+	}
+	record := slog.NewRecord(time.Time{}, slog.LevelError, msg, 0)
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "error" {
+			e.Exception = &exceptions{Values: []exception{{Type: msg, Value: attr.Value.String()}}}
+		} else {
+			e.Tags[attr.Key] = attr.Value.String()
+		}
+		return true
+	})
+	return e
+}
+
+// send posts e to Sentry as an envelope
+func (r *Reporter) send(ctx context.Context, e *event) error {
+	header, err := json.Marshal(map[string]string{"event_id": e.EventID, "sent_at": e.Timestamp})
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteString("\n{\"type\":\"event\"}\n")
+	body.Write(payload)
+	body.WriteString("\n")
+
+	ctx, cancel := context.WithTimeout(ctx, reportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create error report: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send error report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newEventID returns a random event ID in the 32 hex digit form Sentry expects
+func newEventID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,96 @@
+package errorreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// receive starts a server that accepts envelopes, returning a DSN pointing at it and a function
+// that returns the event of the last envelope received
+func receive(t *testing.T) (string, func() event) {
+	t.Helper()
+	var last event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/envelope/" {
+			http.NotFound(w, r)
+			return
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=public") {
+			t.Errorf("unexpected X-Sentry-Auth header: %q", auth)
+		}
+		data, _ := io.ReadAll(r.Body)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 3 {
+			t.Errorf("expected an envelope header, item header, and event, got %q", data)
+			return
+		}
+		if err := json.Unmarshal([]byte(lines[2]), &last); err != nil {
+			t.Errorf("failed to parse event: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.Replace(server.URL, "://", "://public@", 1) + "/42", func() event { return last }
+}
+
+func TestReport(t *testing.T) {
+	dsn, last := receive(t)
+	reporter, err := New(dsn, "homelab")
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	err = reporter.Report(context.Background(), "PagerDuty checks failing", "error", errors.New("401 Unauthorized"), "user_id", "PABC123")
+	if err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+	e := last()
+	if e.Level != "error" || e.Environment != "homelab" || e.Release == "" || e.Tags["user_id"] != "PABC123" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.Exception == nil || e.Exception.Values[0].Type != "PagerDuty checks failing" || e.Exception.Values[0].Value != "401 Unauthorized" {
+		t.Fatalf("unexpected exception: %+v", e.Exception)
+	}
+}
+
+func TestRecoverReportsAndPanicsAgain(t *testing.T) {
+	dsn, last := receive(t)
+	reporter, err := New(dsn, "")
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected the panic to continue after being reported")
+			}
+		}()
+		defer reporter.Recover("user_id", "PABC123")
+		panic("boom")
+	}()
+	e := last()
+	if e.Level != "fatal" || e.Exception == nil || e.Exception.Values[0].Value != "boom" || !strings.Contains(e.Extra["stack"], "TestRecoverReportsAndPanicsAgain") {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
+
+func TestNilReporterReportsNothing(t *testing.T) {
+	var reporter *Reporter
+	if err := reporter.Report(context.Background(), "ignored", "error", errors.New("ignored")); err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+}
+
+func TestNewRejectsInvalidDSNs(t *testing.T) {
+	for _, dsn := range []string{"not a url", "https://o0.ingest.sentry.io/42", "https://key@o0.ingest.sentry.io/", "ftp://key@example.com/42"} {
+		if _, err := New(dsn, ""); err == nil {
+			t.Errorf("expected an error for %q", dsn)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package grafana writes annotations to Grafana's HTTP API, so that dashboards show who was on
+// call when looking back at incident graphs
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+// requestTimeout bounds each annotation so a slow Grafana does not hold up the next check
+const requestTimeout = 10 * time.Second
+
+var client = &http.Client{Timeout: requestTimeout, Transport: httpclient.WithUserAgent(nil)}
+
+// Annotation is an organisation-wide annotation at Time, found by its tags
+type Annotation struct {
+	Time time.Time
+	Tags []string
+	Text string
+}
+
+// Annotate creates annotation in the Grafana at baseURL, e.g. https://grafana.example.com,
+// authenticating with a service account token
+func Annotate(ctx context.Context, baseURL, token string, annotation Annotation) error {
+	body, err := json.Marshal(map[string]any{
+		"time": annotation.Time.UnixMilli(),
+		"tags": annotation.Tags,
+		"text": annotation.Text,
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/api/annotations"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Grafana annotation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Grafana annotation: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Grafana returned status %d", resp.StatusCode)
+	}
+	return nil
+}
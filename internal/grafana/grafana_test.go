@@ -0,0 +1,46 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAnnotate(t *testing.T) {
+	var received struct {
+		Time int64    `json:"time"`
+		Tags []string `json:"tags"`
+		Text string   `json:"text"`
+	}
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode annotation: %v", err)
+		}
+		if r.Header.Get("Authorization") != "Bearer glsa_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	at := time.Date(2030, time.March, 5, 9, 0, 0, 0, time.UTC)
+	annotation := Annotation{Time: at, Tags: []string{"oncall", "user:PUSER01"}, Text: "PUSER01 went on call"}
+	if err := Annotate(context.Background(), server.URL+"/", "glsa_token", annotation); err != nil {
+		t.Fatalf("Annotate returned error: %v", err)
+	}
+	if path != "/api/annotations" || auth != "Bearer glsa_token" {
+		t.Fatalf("expected an authenticated POST to /api/annotations, got %q with %q", path, auth)
+	}
+	if received.Time != at.UnixMilli() || !slices.Equal(received.Tags, annotation.Tags) || received.Text != annotation.Text {
+		t.Fatalf("unexpected annotation: %+v", received)
+	}
+
+	if err := Annotate(context.Background(), server.URL, "wrong", annotation); err == nil {
+		t.Fatalf("expected an error for a rejected token")
+	}
+}
@@ -0,0 +1,83 @@
+// Package health tracks whether on-call checks are succeeding
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultUnhealthyThreshold is the number of consecutive failed checks after which the
+// notifier reports itself unhealthy
+const DefaultUnhealthyThreshold = 3
+
+// Status is a snapshot of check health
+type Status struct {
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+}
+
+// Tracker records the outcome of each check
+type Tracker struct {
+	threshold int
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	lastError           string
+	lastSuccess         time.Time
+	lastFailure         time.Time
+}
+
+// NewTracker creates a tracker that turns unhealthy after threshold consecutive failures
+func NewTracker(threshold int) *Tracker {
+	if threshold <= 0 {
+		threshold = DefaultUnhealthyThreshold
+	}
+	return &Tracker{threshold: threshold}
+}
+
+// RecordSuccess records a successful check, returning true if this recovered from an unhealthy state
+func (t *Tracker) RecordSuccess() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recovered := t.consecutiveFailures >= t.threshold
+	t.consecutiveFailures = 0
+	t.lastError = ""
+	t.lastSuccess = time.Now().UTC()
+	return recovered
+}
+
+// RecordFailure records a failed check, returning true if this made the tracker unhealthy
+func (t *Tracker) RecordFailure(err error) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.consecutiveFailures++
+	t.lastError = err.Error()
+	t.lastFailure = time.Now().UTC()
+	return t.consecutiveFailures == t.threshold
+}
+
+// Status returns the current health snapshot
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{
+		Healthy:             t.consecutiveFailures < t.threshold,
+		ConsecutiveFailures: t.consecutiveFailures,
+		LastError:           t.lastError,
+	}
+	if !t.lastSuccess.IsZero() {
+		lastSuccess := t.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	if !t.lastFailure.IsZero() {
+		lastFailure := t.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	return status
+}
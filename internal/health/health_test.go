@@ -0,0 +1,41 @@
+package health
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrackerTurnsUnhealthyAfterThreshold(t *testing.T) {
+	tracker := NewTracker(2)
+
+	if tracker.RecordFailure(errors.New("boom")) {
+		t.Fatalf("expected tracker to stay healthy after one failure")
+	}
+	if !tracker.Status().Healthy {
+		t.Fatalf("expected healthy status after one failure")
+	}
+	if !tracker.RecordFailure(errors.New("boom again")) {
+		t.Fatalf("expected tracker to turn unhealthy at the threshold")
+	}
+
+	status := tracker.Status()
+	if status.Healthy || status.ConsecutiveFailures != 2 || status.LastError != "boom again" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestTrackerRecovers(t *testing.T) {
+	tracker := NewTracker(1)
+	tracker.RecordFailure(errors.New("boom"))
+
+	if !tracker.RecordSuccess() {
+		t.Fatalf("expected recovery to be reported")
+	}
+	status := tracker.Status()
+	if !status.Healthy || status.ConsecutiveFailures != 0 || status.LastError != "" || status.LastSuccess == nil {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if tracker.RecordSuccess() {
+		t.Fatalf("expected no recovery to be reported while already healthy")
+	}
+}
@@ -0,0 +1,37 @@
+// Package heartbeat pings an external monitor such as healthchecks.io or Dead Man's Snitch
+// after successful checks, so that it can alert when the notifier stops running
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+// pingTimeout bounds each heartbeat so a slow monitor does not hold up the next check
+const pingTimeout = 10 * time.Second
+
+var client = &http.Client{Timeout: pingTimeout, Transport: httpclient.WithUserAgent(nil)}
+
+// Ping sends a heartbeat as a GET request to url
+func Ping(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeat request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat returned status %d", resp.StatusCode)
+	}
+	return nil
+}
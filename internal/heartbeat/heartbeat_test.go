@@ -0,0 +1,30 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if err := Ping(context.Background(), server.URL+"/ping"); err != nil {
+		t.Fatalf("Ping returned error: %v", err)
+	}
+	if !strings.HasPrefix(userAgent, "pagerduty-oncall-notifier/") {
+		t.Fatalf("expected the notifier's User-Agent, got %q", userAgent)
+	}
+	if err := Ping(context.Background(), server.URL+"/missing"); err == nil {
+		t.Fatalf("expected an error for a 404 response")
+	}
+}
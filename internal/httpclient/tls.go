@@ -0,0 +1,60 @@
+// Package httpclient builds HTTP client settings shared by the outbound integrations
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSFiles holds the paths for client certificate and CA configuration
+type TLSFiles struct {
+	// CertFile and KeyFile hold a PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// CAFile holds PEM CA certificates trusted in addition to the system roots
+	CAFile string
+}
+
+// IsZero reports whether no TLS files are configured
+func (f TLSFiles) IsZero() bool {
+	return f.CertFile == "" && f.KeyFile == "" && f.CAFile == ""
+}
+
+// LoadTLSConfig builds a TLS config from the given files, returning nil if none are configured
+func LoadTLSConfig(files TLSFiles) (*tls.Config, error) {
+	if files.IsZero() {
+		return nil, nil
+	}
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be configured together")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if files.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if files.CAFile != "" {
+		pem, err := os.ReadFile(files.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", files.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a self-signed certificate and key to dir, returning their paths
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "notifier-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadTLSConfigReturnsNilWhenUnset(t *testing.T) {
+	cfg, err := LoadTLSConfig(TLSFiles{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg != nil {
+		t.Fatalf("expected nil config")
+	}
+}
+
+func TestLoadTLSConfigLoadsCertificateAndCA(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+
+	cfg, err := LoadTLSConfig(TLSFiles{CertFile: certFile, KeyFile: keyFile, CAFile: certFile})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("expected one client certificate, got %d", len(cfg.Certificates))
+	}
+	if cfg.RootCAs == nil {
+		t.Fatalf("expected custom root CAs")
+	}
+}
+
+func TestLoadTLSConfigRequiresCertAndKeyTogether(t *testing.T) {
+	certFile, _ := writeSelfSignedCert(t, t.TempDir())
+
+	if _, err := LoadTLSConfig(TLSFiles{CertFile: certFile}); err == nil {
+		t.Fatalf("expected an error when the key is missing")
+	}
+}
+
+func TestLoadTLSConfigRejectsEmptyCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	if _, err := LoadTLSConfig(TLSFiles{CAFile: caFile}); err == nil {
+		t.Fatalf("expected an error for a CA file without certificates")
+	}
+}
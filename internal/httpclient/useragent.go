@@ -0,0 +1,27 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+)
+
+// userAgentTransport identifies the notifier in the User-Agent of every request
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+// WithUserAgent wraps base, or http.DefaultTransport if nil, to send the notifier's User-Agent
+func WithUserAgent(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &userAgentTransport{base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", version.UserAgent())
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,27 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/version"
+)
+
+func TestWithUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: WithUserAgent(nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if userAgent != version.UserAgent() {
+		t.Errorf("expected User-Agent %q, got %q", version.UserAgent(), userAgent)
+	}
+}
@@ -0,0 +1,40 @@
+// Package logging sets up the notifier's structured logs, written with log/slog as text or JSON
+// so they can be searched and shipped to Loki, Elasticsearch, and similar
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats accepted by LOG_FORMAT
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats lists the valid LOG_FORMAT values
+var Formats = []string{FormatText, FormatJSON}
+
+// New returns a logger writing records at level or above to w in the given format
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Setup makes a logger writing to stderr the default, for both slog and the log package
+func Setup(level slog.Level, format string) {
+	slog.SetDefault(New(os.Stderr, level, format))
+}
+
+// ParseLevel parses a LOG_LEVEL value: debug, info, warn, or error
+func ParseLevel(raw string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(raw)))
+	return level, err
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewWritesJSONAtLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn, FormatJSON)
+
+	logger.Info("ignored")
+	logger.Warn("Failed to send notification", "event", "shift_started", "backend", "ntfy")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "Failed to send notification" || record["level"] != "WARN" || record["event"] != "shift_started" || record["backend"] != "ntfy" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+}
+
+func TestNewWritesText(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo, FormatText).Info("Check complete", "user_id", "PABC123")
+
+	if line := buf.String(); !strings.Contains(line, `msg="Check complete"`) || !strings.Contains(line, "user_id=PABC123") {
+		t.Fatalf("unexpected text record: %q", line)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for raw, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(raw); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("expected an error for an unknown level")
+	}
+}
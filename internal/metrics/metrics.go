@@ -0,0 +1,112 @@
+// Package metrics exports the on-call status of monitored users as Prometheus gauges, for
+// scraping or pushing to a Pushgateway
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+// Target is a monitored user whose on-call status is exported
+type Target struct {
+	UserID string
+	// ScheduleID is the monitored schedule, or empty in escalation policy and team mode, where
+	// the schedule of the current shift is exported instead
+	ScheduleID string
+	// Profile is the CONFIG_FILE profile the user is monitored in, if any
+	Profile      string
+	StateManager *state.Manager
+	Health       *health.Tracker
+}
+
+// ContentType is the media type of the Prometheus text format Write produces
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// gauge is a metric family in the Prometheus text format
+type gauge struct {
+	name, help string
+	samples    []string
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (g *gauge) add(labels map[string]string, value float64) {
+	var pairs []string
+	for _, key := range []string{"schedule", "user", "profile"} {
+		if value, ok := labels[key]; ok && value != "" {
+			pairs = append(pairs, key+`="`+labelEscaper.Replace(value)+`"`)
+		}
+	}
+	g.samples = append(g.samples, fmt.Sprintf("%s{%s} %g", g.name, strings.Join(pairs, ","), value))
+}
+
+// Write writes the gauges of every target in the Prometheus text format. Values are read from
+// each user's state, as of their last check, and only users that have been checked report their
+// status.
+func Write(w io.Writer, targets []Target) error {
+	oncall := &gauge{name: "pagerduty_oncall", help: "Whether the user is on call (1) or not (0) as of the last check"}
+	lastCheck := &gauge{name: "pagerduty_oncall_last_check_timestamp_seconds", help: "When the user's on-call status was last checked"}
+	shiftEnd := &gauge{name: "pagerduty_oncall_current_shift_end_timestamp_seconds", help: "When the user's current shift ends, while on call"}
+	nextShift := &gauge{name: "pagerduty_oncall_next_shift_start_timestamp_seconds", help: "When the user's next shift starts, if one is known"}
+	failures := &gauge{name: "pagerduty_oncall_check_consecutive_failures", help: "How many checks in a row have failed"}
+
+	for _, target := range targets {
+		labels := map[string]string{"user": target.UserID}
+		if target.Profile != "" {
+			labels["profile"] = target.Profile
+		}
+		failures.add(labels, float64(target.Health.Status().ConsecutiveFailures))
+
+		currentState, err := target.StateManager.Load()
+		if err != nil {
+			slog.Warn("Failed to load state for metrics", "user_id", target.UserID, "error", err)
+			continue
+		}
+		if currentState.LastCheckAt == nil {
+			continue
+		}
+		lastCheck.add(labels, unixSeconds(*currentState.LastCheckAt))
+		if currentState.NextShift != nil {
+			nextShift.add(labels, unixSeconds(currentState.NextShift.Start))
+		}
+		if currentState.WasOnCall && currentState.CurrentShiftEnd != nil {
+			shiftEnd.add(labels, unixSeconds(*currentState.CurrentShiftEnd))
+		}
+
+		schedule := target.ScheduleID
+		if schedule == "" {
+			schedule = currentState.CurrentShiftScheduleID
+		}
+		oncallLabels := map[string]string{"schedule": schedule}
+		for key, value := range labels {
+			oncallLabels[key] = value
+		}
+		value := 0.0
+		if currentState.WasOnCall {
+			value = 1
+		}
+		oncall.add(oncallLabels, value)
+	}
+
+	out := bufio.NewWriter(w)
+	for _, g := range []*gauge{oncall, lastCheck, shiftEnd, nextShift, failures} {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, sample := range g.samples {
+			fmt.Fprintln(out, sample)
+		}
+	}
+	return out.Flush()
+}
+
+// unixSeconds returns t as seconds since the epoch, the unit Prometheus timestamps use
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+func TestWriteExportsOnCallStatus(t *testing.T) {
+	dir := t.TempDir()
+	onCall := state.NewManager(filepath.Join(dir, "oncall.json"))
+	end := time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC)
+	err := onCall.Update(func(currentState *state.State) error {
+		onCall.RecordCheck(currentState)
+		onCall.RecordShiftStarted(currentState, end.AddDate(0, 0, -7), "PSCHED1")
+		onCall.RecordCurrentShift(currentState, &state.ShiftWindow{Start: end.AddDate(0, 0, -7), End: end})
+		currentState.WasOnCall = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	offCall := state.NewManager(filepath.Join(dir, "offcall.json"))
+	if err := offCall.Update(func(currentState *state.State) error { offCall.RecordCheck(currentState); return nil }); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	targets := []Target{
+		{UserID: "PUSER01", StateManager: onCall, Health: health.NewTracker(health.DefaultUnhealthyThreshold)},
+		{UserID: "PUSER02", ScheduleID: "PSCHED2", Profile: "platform", StateManager: offCall, Health: health.NewTracker(health.DefaultUnhealthyThreshold)},
+		{UserID: "PUSER03", StateManager: state.NewManager(filepath.Join(dir, "unchecked.json")), Health: health.NewTracker(health.DefaultUnhealthyThreshold)},
+	}
+
+	var out strings.Builder
+	if err := Write(&out, targets); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	body := out.String()
+	for _, want := range []string{
+		"# TYPE pagerduty_oncall gauge\n",
+		`pagerduty_oncall{schedule="PSCHED1",user="PUSER01"} 1` + "\n",
+		`pagerduty_oncall{schedule="PSCHED2",user="PUSER02",profile="platform"} 0` + "\n",
+		`pagerduty_oncall_current_shift_end_timestamp_seconds{user="PUSER01"} 1.7494596e+09` + "\n",
+		`pagerduty_oncall_check_consecutive_failures{user="PUSER03"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `pagerduty_oncall{user="PUSER03"}`) {
+		t.Errorf("expected no on-call status for a user that has not been checked:\n%s", body)
+	}
+}
+
+func TestPushReplacesJobMetrics(t *testing.T) {
+	var method, path, user, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		method, path = r.Method, r.URL.Path
+		user, _, _ = r.BasicAuth()
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	if err := stateManager.Update(func(currentState *state.State) error { stateManager.RecordCheck(currentState); return nil }); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	targets := []Target{{UserID: "PUSER01", StateManager: stateManager, Health: health.NewTracker(health.DefaultUnhealthyThreshold)}}
+
+	gatewayURL := strings.Replace(server.URL, "://", "://cron:secret@", 1) + "/"
+	if err := Push(context.Background(), gatewayURL, "oncall cron", targets); err != nil {
+		t.Fatalf("Push returned error: %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/oncall cron" || user != "cron" {
+		t.Fatalf("unexpected push: %s %s as %q", method, path, user)
+	}
+	if !strings.Contains(body, `pagerduty_oncall{user="PUSER01"} 0`) {
+		t.Fatalf("expected the on-call status in the push, got:\n%s", body)
+	}
+
+	if err := Push(context.Background(), server.URL+"/missing", "oncall", targets); err == nil {
+		t.Fatalf("expected an error for a 404 response")
+	}
+}
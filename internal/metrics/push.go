@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+// pushTimeout bounds each push so an unreachable Pushgateway does not hold up exiting
+const pushTimeout = 10 * time.Second
+
+var client = &http.Client{Timeout: pushTimeout, Transport: httpclient.WithUserAgent(nil)}
+
+// Push sends the gauges of targets to the Prometheus Pushgateway at gatewayURL, replacing the
+// metrics previously pushed for job. Credentials in gatewayURL's user info are sent as basic
+// auth.
+func Push(ctx context.Context, gatewayURL, job string, targets []Target) error {
+	var body bytes.Buffer
+	if err := Write(&body, targets); err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics push: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Pushgateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
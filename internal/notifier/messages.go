@@ -0,0 +1,829 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// markdownTemplate renders a Markdown notification body with the shift details as a bullet list
+var markdownTemplate = template.Must(template.New("markdown").Parse(`{{.Message}}
+{{- if .ShiftTimeLabel}}
+
+- **{{.ShiftTimeLabel}}:** {{.ShiftTime}}
+{{- if .Remaining}}
+- **{{.RemainingLabel}}:** {{.Remaining}}
+{{- end}}
+{{- end}}
+{{- if .Handoffs}}
+{{range .Handoffs}}
+- **{{.Label}}:** {{.Value}}
+{{- end}}
+{{- end}}
+`))
+
+// htmlTemplate renders an HTML notification body (as supported by Pushover) with the shift details in bold
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`{{.Message}}
+{{- if .ShiftTimeLabel}}
+
+<b>{{.ShiftTimeLabel}}:</b> {{.ShiftTime}}
+{{- if .Remaining}}
+<b>{{.RemainingLabel}}:</b> {{.Remaining}}
+{{- end}}
+{{- end}}
+{{- if .Handoffs}}
+{{range .Handoffs}}
+<b>{{.Label}}:</b> {{.Value}}
+{{- end}}
+{{- end}}`))
+
+// detailsTimeLayout is the layout used for times in formatted bodies
+const detailsTimeLayout = "Mon 02 Jan 15:04 MST"
+
+// windowStartLayout and windowEndLayout format a time range within a message, e.g. "Tue 09:00–Tue 17:00 UTC"
+const (
+	windowStartLayout = "Mon 15:04"
+	windowEndLayout   = "Mon 15:04 MST"
+)
+
+// detailsData is the data passed to markdownTemplate and htmlTemplate
+type detailsData struct {
+	Message        string
+	ShiftTimeLabel string
+	ShiftTime      string
+	RemainingLabel string
+	Remaining      string
+	Handoffs       []detailsItem
+}
+
+// detailsItem is a labelled line in formatted bodies
+type detailsItem struct {
+	Label string
+	Value string
+}
+
+// Locale identifies a built-in message catalog
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleGerman  Locale = "de"
+	LocaleFrench  Locale = "fr"
+	LocaleSpanish Locale = "es"
+	LocaleDutch   Locale = "nl"
+)
+
+// DefaultLocale is used when no locale is configured
+const DefaultLocale = LocaleEnglish
+
+// catalog holds the translated strings for a single locale
+type catalog struct {
+	shiftStartedTitle       string
+	shiftStartedBody        string
+	shiftStartedEndsBody    string // formatted with the shift end and length
+	upcomingTitle           string
+	upcomingBody            string // formatted with the time remaining
+	upcomingSoonBody        string
+	shiftEndingTitle        string
+	shiftEndingBody         string // formatted with the time remaining
+	shiftEndingSoonBody     string
+	shiftEndedTitle         string
+	shiftEndedBody          string
+	shiftOverriddenTitle    string
+	shiftOverriddenBody     string
+	coverageStartedTitle    string
+	coverageStartedBody     string
+	coverageWindowBody      string // formatted with the coverage start and end
+	coverageGapTitle        string
+	coverageGapBody         string
+	coverageGapWindowBody   string // formatted with the gap start and end
+	unexpectedOnCallTitle   string
+	unexpectedOnCallBody    string
+	unexpectedResponderBody string // formatted with the responder's name
+	scheduleChangedTitle    string
+	scheduleChangedBody     string
+	notifierDegradedTitle   string
+	notifierDegradedBody    string
+	dailySummaryTitle       string
+	dailySummaryOnCallBody  string
+	dailySummaryEndsBody    string // formatted with the shift end
+	dailySummaryNextBody    string // formatted with the next shift start
+	dailySummaryNoShiftBody string
+	unknownTitle            string
+	unknownBody             string
+	startedTitle            string
+	stoppedTitle            string
+	startedAtLabel          string
+	startsAtLabel           string
+	startsInLabel           string
+	endsAtLabel             string
+	endsInLabel             string
+	handoffFromLabel        string
+	handoffToLabel          string
+	openIncidentsLabel      string
+	userLabel               string
+	shiftAddedLabel         string
+	shiftRemovedLabel       string
+	shiftMovedLabel         string
+	noOpenIncidents         string
+	openIncidentsOldest     string // formatted with the count and age of the oldest
+	scheduleLinkTitle       string
+	onCallStatus            string
+	offCallStatus           string
+	nextShiftLabel          string
+
+	hour    string
+	hours   string
+	minute  string
+	minutes string
+	and     string
+}
+
+var catalogs = map[Locale]catalog{
+	LocaleEnglish: {
+		shiftStartedTitle:       "PagerDuty On-Call Shift Started",
+		shiftStartedBody:        "🚨 Your PagerDuty on-call shift has started!",
+		shiftStartedEndsBody:    "🚨 Your PagerDuty on-call shift has started! It ends %s (%s).",
+		upcomingTitle:           "PagerDuty On-Call Shift Upcoming",
+		upcomingBody:            "⏰ Your PagerDuty on-call shift starts in %s!",
+		upcomingSoonBody:        "⏰ Your PagerDuty on-call shift starts soon!",
+		shiftEndingTitle:        "PagerDuty On-Call Shift Ending",
+		shiftEndingBody:         "⏳ Your PagerDuty on-call shift ends in %s. Time to prepare your handoff notes!",
+		shiftEndingSoonBody:     "⏳ Your PagerDuty on-call shift ends soon. Time to prepare your handoff notes!",
+		shiftEndedTitle:         "PagerDuty On-Call Shift Ended",
+		shiftEndedBody:          "✅ Your PagerDuty on-call shift has ended. Enjoy the downtime!",
+		shiftOverriddenTitle:    "PagerDuty On-Call Shift Overridden",
+		shiftOverriddenBody:     "🔄 You've been removed from an upcoming PagerDuty on-call shift by an override.",
+		coverageStartedTitle:    "PagerDuty On-Call Coverage Started",
+		coverageStartedBody:     "🤝 You're now covering a PagerDuty on-call shift via an override!",
+		coverageWindowBody:      "🤝 You have accepted PagerDuty on-call coverage from %s–%s!",
+		coverageGapTitle:        "PagerDuty On-Call Coverage Gap",
+		coverageGapBody:         "⚠️ A PagerDuty schedule has a gap with nobody on call!",
+		coverageGapWindowBody:   "⚠️ Nobody is on call from %s–%s!",
+		unexpectedOnCallTitle:   "Unexpected PagerDuty On-Call Responder",
+		unexpectedOnCallBody:    "👀 Someone unexpected is on call in PagerDuty!",
+		unexpectedResponderBody: "👀 %s is on call in PagerDuty but is not an expected responder!",
+		scheduleChangedTitle:    "PagerDuty Schedule Changed",
+		scheduleChangedBody:     "📅 Your upcoming PagerDuty on-call shifts have changed.",
+		notifierDegradedTitle:   "PagerDuty Notifier Degraded",
+		notifierDegradedBody:    "🛑 The notifier cannot reach PagerDuty, so shift notifications may be missed. Check the API token and network.",
+		dailySummaryTitle:       "PagerDuty On-Call Daily Summary",
+		dailySummaryOnCallBody:  "📋 You are on call in PagerDuty today.",
+		dailySummaryEndsBody:    "📋 You are on call in PagerDuty today. Your shift ends %s.",
+		dailySummaryNextBody:    "📋 You are not on call in PagerDuty. Your next shift starts %s.",
+		dailySummaryNoShiftBody: "📋 You are not on call in PagerDuty, and have no shifts in the next 7 days.",
+		unknownTitle:            "PagerDuty Notification",
+		unknownBody:             "Unknown notification event",
+		startedTitle:            "PagerDuty Notifier Started",
+		stoppedTitle:            "PagerDuty Notifier Stopped",
+		startedAtLabel:          "Started",
+		startsAtLabel:           "Starts",
+		startsInLabel:           "Starts in",
+		endsAtLabel:             "Ends",
+		endsInLabel:             "Ends in",
+		handoffFromLabel:        "Taking over from",
+		handoffToLabel:          "Handing over to",
+		openIncidentsLabel:      "Open incidents",
+		noOpenIncidents:         "none",
+		openIncidentsOldest:     "%d, oldest %s",
+		userLabel:               "User",
+		shiftAddedLabel:         "Added",
+		shiftRemovedLabel:       "Removed",
+		shiftMovedLabel:         "Moved",
+		scheduleLinkTitle:       "Open PagerDuty schedule",
+		onCallStatus:            "On call",
+		offCallStatus:           "Off call",
+		nextShiftLabel:          "Next",
+		hour:                    "hour",
+		hours:                   "hours",
+		minute:                  "minute",
+		minutes:                 "minutes",
+		and:                     "and",
+	},
+	LocaleGerman: {
+		shiftStartedTitle:       "PagerDuty-Rufbereitschaft begonnen",
+		shiftStartedBody:        "🚨 Deine PagerDuty-Rufbereitschaft hat begonnen!",
+		shiftStartedEndsBody:    "🚨 Deine PagerDuty-Rufbereitschaft hat begonnen! Sie endet %s (%s).",
+		upcomingTitle:           "PagerDuty-Rufbereitschaft steht bevor",
+		upcomingBody:            "⏰ Deine PagerDuty-Rufbereitschaft beginnt in %s!",
+		upcomingSoonBody:        "⏰ Deine PagerDuty-Rufbereitschaft beginnt in Kürze!",
+		shiftEndingTitle:        "PagerDuty-Rufbereitschaft endet bald",
+		shiftEndingBody:         "⏳ Deine PagerDuty-Rufbereitschaft endet in %s. Zeit für die Übergabenotizen!",
+		shiftEndingSoonBody:     "⏳ Deine PagerDuty-Rufbereitschaft endet in Kürze. Zeit für die Übergabenotizen!",
+		shiftEndedTitle:         "PagerDuty-Rufbereitschaft beendet",
+		shiftEndedBody:          "✅ Deine PagerDuty-Rufbereitschaft ist beendet. Genieß die freie Zeit!",
+		shiftOverriddenTitle:    "PagerDuty-Rufbereitschaft überschrieben",
+		shiftOverriddenBody:     "🔄 Du wurdest durch eine Überschreibung aus einer bevorstehenden PagerDuty-Rufbereitschaft entfernt.",
+		coverageStartedTitle:    "PagerDuty-Vertretung begonnen",
+		coverageStartedBody:     "🤝 Du übernimmst jetzt per Überschreibung eine PagerDuty-Rufbereitschaft!",
+		coverageWindowBody:      "🤝 Du hast die PagerDuty-Rufbereitschaft von %s–%s übernommen!",
+		coverageGapTitle:        "Lücke in der PagerDuty-Rufbereitschaft",
+		coverageGapBody:         "⚠️ Ein PagerDuty-Dienstplan hat eine Lücke ohne Rufbereitschaft!",
+		coverageGapWindowBody:   "⚠️ Von %s–%s hat niemand Rufbereitschaft!",
+		unexpectedOnCallTitle:   "Unerwartete PagerDuty-Rufbereitschaft",
+		unexpectedOnCallBody:    "👀 Jemand Unerwartetes hat PagerDuty-Rufbereitschaft!",
+		unexpectedResponderBody: "👀 %s hat PagerDuty-Rufbereitschaft, ist aber nicht vorgesehen!",
+		scheduleChangedTitle:    "PagerDuty-Dienstplan geändert",
+		scheduleChangedBody:     "📅 Deine kommenden PagerDuty-Schichten haben sich geändert.",
+		notifierDegradedTitle:   "PagerDuty-Notifier gestört",
+		notifierDegradedBody:    "🛑 Der Notifier erreicht PagerDuty nicht, Benachrichtigungen zu Schichten können ausbleiben. Prüfe API-Token und Netzwerk.",
+		dailySummaryTitle:       "PagerDuty-Tagesübersicht",
+		dailySummaryOnCallBody:  "📋 Du hast heute PagerDuty-Rufbereitschaft.",
+		dailySummaryEndsBody:    "📋 Du hast heute PagerDuty-Rufbereitschaft. Sie endet %s.",
+		dailySummaryNextBody:    "📋 Du hast keine PagerDuty-Rufbereitschaft. Deine nächste beginnt %s.",
+		dailySummaryNoShiftBody: "📋 Du hast keine PagerDuty-Rufbereitschaft und in den nächsten 7 Tagen keine Schichten.",
+		unknownTitle:            "PagerDuty-Benachrichtigung",
+		unknownBody:             "Unbekanntes Benachrichtigungsereignis",
+		startedTitle:            "PagerDuty-Notifier gestartet",
+		stoppedTitle:            "PagerDuty-Notifier gestoppt",
+		startedAtLabel:          "Begonnen",
+		startsAtLabel:           "Beginnt",
+		startsInLabel:           "Beginnt in",
+		endsAtLabel:             "Endet",
+		endsInLabel:             "Endet in",
+		handoffFromLabel:        "Übernahme von",
+		handoffToLabel:          "Übergabe an",
+		openIncidentsLabel:      "Offene Incidents",
+		noOpenIncidents:         "keine",
+		openIncidentsOldest:     "%d, ältester seit %s",
+		userLabel:               "Benutzer",
+		shiftAddedLabel:         "Hinzugefügt",
+		shiftRemovedLabel:       "Entfernt",
+		shiftMovedLabel:         "Verschoben",
+		scheduleLinkTitle:       "PagerDuty-Dienstplan öffnen",
+		onCallStatus:            "Rufbereitschaft",
+		offCallStatus:           "Keine Rufbereitschaft",
+		nextShiftLabel:          "Nächste",
+		hour:                    "Stunde",
+		hours:                   "Stunden",
+		minute:                  "Minute",
+		minutes:                 "Minuten",
+		and:                     "und",
+	},
+	LocaleFrench: {
+		shiftStartedTitle:       "Astreinte PagerDuty commencée",
+		shiftStartedBody:        "🚨 Votre astreinte PagerDuty a commencé !",
+		shiftStartedEndsBody:    "🚨 Votre astreinte PagerDuty a commencé ! Elle se termine %s (%s).",
+		upcomingTitle:           "Astreinte PagerDuty à venir",
+		upcomingBody:            "⏰ Votre astreinte PagerDuty commence dans %s !",
+		upcomingSoonBody:        "⏰ Votre astreinte PagerDuty commence bientôt !",
+		shiftEndingTitle:        "Fin d'astreinte PagerDuty",
+		shiftEndingBody:         "⏳ Votre astreinte PagerDuty se termine dans %s. Pensez à préparer vos notes de passation !",
+		shiftEndingSoonBody:     "⏳ Votre astreinte PagerDuty se termine bientôt. Pensez à préparer vos notes de passation !",
+		shiftEndedTitle:         "Astreinte PagerDuty terminée",
+		shiftEndedBody:          "✅ Votre astreinte PagerDuty est terminée. Profitez de votre temps libre !",
+		shiftOverriddenTitle:    "Astreinte PagerDuty remplacée",
+		shiftOverriddenBody:     "🔄 Vous avez été retiré d'une astreinte PagerDuty à venir par un remplacement.",
+		coverageStartedTitle:    "Remplacement PagerDuty commencé",
+		coverageStartedBody:     "🤝 Vous assurez maintenant une astreinte PagerDuty en remplacement !",
+		coverageWindowBody:      "🤝 Vous avez accepté de remplacer l'astreinte PagerDuty de %s–%s !",
+		coverageGapTitle:        "Trou dans les astreintes PagerDuty",
+		coverageGapBody:         "⚠️ Un planning PagerDuty comporte une période sans personne d'astreinte !",
+		coverageGapWindowBody:   "⚠️ Personne n'est d'astreinte de %s–%s !",
+		unexpectedOnCallTitle:   "Astreinte PagerDuty inattendue",
+		unexpectedOnCallBody:    "👀 Une personne inattendue est d'astreinte sur PagerDuty !",
+		unexpectedResponderBody: "👀 %s est d'astreinte sur PagerDuty mais n'est pas prévu(e) !",
+		scheduleChangedTitle:    "Planning PagerDuty modifié",
+		scheduleChangedBody:     "📅 Vos prochaines astreintes PagerDuty ont changé.",
+		notifierDegradedTitle:   "Notificateur PagerDuty dégradé",
+		notifierDegradedBody:    "🛑 Le notificateur ne parvient pas à joindre PagerDuty, des notifications d'astreinte peuvent manquer. Vérifiez le jeton d'API et le réseau.",
+		dailySummaryTitle:       "Résumé quotidien PagerDuty",
+		dailySummaryOnCallBody:  "📋 Vous êtes d'astreinte PagerDuty aujourd'hui.",
+		dailySummaryEndsBody:    "📋 Vous êtes d'astreinte PagerDuty aujourd'hui. Elle se termine %s.",
+		dailySummaryNextBody:    "📋 Vous n'êtes pas d'astreinte PagerDuty. Votre prochaine astreinte commence %s.",
+		dailySummaryNoShiftBody: "📋 Vous n'êtes pas d'astreinte PagerDuty et n'avez aucune astreinte dans les 7 prochains jours.",
+		unknownTitle:            "Notification PagerDuty",
+		unknownBody:             "Événement de notification inconnu",
+		startedTitle:            "Notificateur PagerDuty démarré",
+		stoppedTitle:            "Notificateur PagerDuty arrêté",
+		startedAtLabel:          "Commencée",
+		startsAtLabel:           "Commence",
+		startsInLabel:           "Commence dans",
+		endsAtLabel:             "Se termine",
+		endsInLabel:             "Se termine dans",
+		handoffFromLabel:        "Prend le relais de",
+		handoffToLabel:          "Passe le relais à",
+		openIncidentsLabel:      "Incidents ouverts",
+		noOpenIncidents:         "aucun",
+		openIncidentsOldest:     "%d, le plus ancien depuis %s",
+		userLabel:               "Utilisateur",
+		shiftAddedLabel:         "Ajoutée",
+		shiftRemovedLabel:       "Supprimée",
+		shiftMovedLabel:         "Déplacée",
+		scheduleLinkTitle:       "Ouvrir le planning PagerDuty",
+		onCallStatus:            "D'astreinte",
+		offCallStatus:           "Pas d'astreinte",
+		nextShiftLabel:          "Prochaine",
+		hour:                    "heure",
+		hours:                   "heures",
+		minute:                  "minute",
+		minutes:                 "minutes",
+		and:                     "et",
+	},
+	LocaleSpanish: {
+		shiftStartedTitle:       "Guardia de PagerDuty iniciada",
+		shiftStartedBody:        "🚨 ¡Tu guardia de PagerDuty ha comenzado!",
+		shiftStartedEndsBody:    "🚨 ¡Tu guardia de PagerDuty ha comenzado! Termina %s (%s).",
+		upcomingTitle:           "Próxima guardia de PagerDuty",
+		upcomingBody:            "⏰ ¡Tu guardia de PagerDuty comienza en %s!",
+		upcomingSoonBody:        "⏰ ¡Tu guardia de PagerDuty comienza pronto!",
+		shiftEndingTitle:        "Fin de guardia de PagerDuty",
+		shiftEndingBody:         "⏳ Tu guardia de PagerDuty termina en %s. ¡Es hora de preparar las notas de traspaso!",
+		shiftEndingSoonBody:     "⏳ Tu guardia de PagerDuty termina pronto. ¡Es hora de preparar las notas de traspaso!",
+		shiftEndedTitle:         "Guardia de PagerDuty finalizada",
+		shiftEndedBody:          "✅ Tu guardia de PagerDuty ha terminado. ¡Disfruta del descanso!",
+		shiftOverriddenTitle:    "Guardia de PagerDuty reemplazada",
+		shiftOverriddenBody:     "🔄 Una sustitución te ha quitado de una próxima guardia de PagerDuty.",
+		coverageStartedTitle:    "Sustitución de PagerDuty iniciada",
+		coverageStartedBody:     "🤝 ¡Ahora cubres una guardia de PagerDuty mediante una sustitución!",
+		coverageWindowBody:      "🤝 ¡Has aceptado cubrir la guardia de PagerDuty de %s–%s!",
+		coverageGapTitle:        "Hueco en las guardias de PagerDuty",
+		coverageGapBody:         "⚠️ ¡Un calendario de PagerDuty tiene un hueco sin nadie de guardia!",
+		coverageGapWindowBody:   "⚠️ ¡Nadie está de guardia de %s–%s!",
+		unexpectedOnCallTitle:   "Guardia de PagerDuty inesperada",
+		unexpectedOnCallBody:    "👀 ¡Alguien inesperado está de guardia en PagerDuty!",
+		unexpectedResponderBody: "👀 ¡%s está de guardia en PagerDuty pero no es un responsable previsto!",
+		scheduleChangedTitle:    "Calendario de PagerDuty modificado",
+		scheduleChangedBody:     "📅 Tus próximas guardias de PagerDuty han cambiado.",
+		notifierDegradedTitle:   "Notificador de PagerDuty degradado",
+		notifierDegradedBody:    "🛑 El notificador no puede conectar con PagerDuty y podrían perderse avisos de guardia. Revisa el token de la API y la red.",
+		dailySummaryTitle:       "Resumen diario de PagerDuty",
+		dailySummaryOnCallBody:  "📋 Hoy estás de guardia en PagerDuty.",
+		dailySummaryEndsBody:    "📋 Hoy estás de guardia en PagerDuty. Termina %s.",
+		dailySummaryNextBody:    "📋 No estás de guardia en PagerDuty. Tu próxima guardia empieza %s.",
+		dailySummaryNoShiftBody: "📋 No estás de guardia en PagerDuty y no tienes guardias en los próximos 7 días.",
+		unknownTitle:            "Notificación de PagerDuty",
+		unknownBody:             "Evento de notificación desconocido",
+		startedTitle:            "Notificador de PagerDuty iniciado",
+		stoppedTitle:            "Notificador de PagerDuty detenido",
+		startedAtLabel:          "Iniciada",
+		startsAtLabel:           "Comienza",
+		startsInLabel:           "Comienza en",
+		endsAtLabel:             "Termina",
+		endsInLabel:             "Termina en",
+		handoffFromLabel:        "Relevas a",
+		handoffToLabel:          "Te releva",
+		openIncidentsLabel:      "Incidentes abiertos",
+		noOpenIncidents:         "ninguno",
+		openIncidentsOldest:     "%d, el más antiguo desde hace %s",
+		userLabel:               "Usuario",
+		shiftAddedLabel:         "Añadida",
+		shiftRemovedLabel:       "Eliminada",
+		shiftMovedLabel:         "Movida",
+		scheduleLinkTitle:       "Abrir el calendario de PagerDuty",
+		onCallStatus:            "De guardia",
+		offCallStatus:           "Sin guardia",
+		nextShiftLabel:          "Próxima",
+		hour:                    "hora",
+		hours:                   "horas",
+		minute:                  "minuto",
+		minutes:                 "minutos",
+		and:                     "y",
+	},
+	LocaleDutch: {
+		shiftStartedTitle:       "PagerDuty-dienst begonnen",
+		shiftStartedBody:        "🚨 Je PagerDuty-dienst is begonnen!",
+		shiftStartedEndsBody:    "🚨 Je PagerDuty-dienst is begonnen! Hij eindigt %s (%s).",
+		upcomingTitle:           "PagerDuty-dienst komt eraan",
+		upcomingBody:            "⏰ Je PagerDuty-dienst begint over %s!",
+		upcomingSoonBody:        "⏰ Je PagerDuty-dienst begint binnenkort!",
+		shiftEndingTitle:        "PagerDuty-dienst eindigt",
+		shiftEndingBody:         "⏳ Je PagerDuty-dienst eindigt over %s. Tijd om je overdrachtsnotities voor te bereiden!",
+		shiftEndingSoonBody:     "⏳ Je PagerDuty-dienst eindigt binnenkort. Tijd om je overdrachtsnotities voor te bereiden!",
+		shiftEndedTitle:         "PagerDuty-dienst beëindigd",
+		shiftEndedBody:          "✅ Je PagerDuty-dienst is afgelopen. Geniet van je vrije tijd!",
+		shiftOverriddenTitle:    "PagerDuty-dienst overgenomen",
+		shiftOverriddenBody:     "🔄 Je bent door een override uit een komende PagerDuty-dienst gehaald.",
+		coverageStartedTitle:    "PagerDuty-vervanging begonnen",
+		coverageStartedBody:     "🤝 Je neemt nu via een override een PagerDuty-dienst waar!",
+		coverageWindowBody:      "🤝 Je hebt de PagerDuty-dienst van %s–%s overgenomen!",
+		coverageGapTitle:        "Gat in PagerDuty-diensten",
+		coverageGapBody:         "⚠️ Een PagerDuty-rooster heeft een gat zonder dienst!",
+		coverageGapWindowBody:   "⚠️ Van %s–%s heeft niemand dienst!",
+		unexpectedOnCallTitle:   "Onverwachte PagerDuty-dienst",
+		unexpectedOnCallBody:    "👀 Er heeft iemand onverwachts PagerDuty-dienst!",
+		unexpectedResponderBody: "👀 %s heeft PagerDuty-dienst, maar wordt niet verwacht!",
+		scheduleChangedTitle:    "PagerDuty-rooster gewijzigd",
+		scheduleChangedBody:     "📅 Je komende PagerDuty-diensten zijn gewijzigd.",
+		notifierDegradedTitle:   "PagerDuty-notifier verstoord",
+		notifierDegradedBody:    "🛑 De notifier kan PagerDuty niet bereiken, dus meldingen over diensten kunnen uitblijven. Controleer de API-token en het netwerk.",
+		dailySummaryTitle:       "PagerDuty-dagoverzicht",
+		dailySummaryOnCallBody:  "📋 Je hebt vandaag PagerDuty-dienst.",
+		dailySummaryEndsBody:    "📋 Je hebt vandaag PagerDuty-dienst. Hij eindigt %s.",
+		dailySummaryNextBody:    "📋 Je hebt geen PagerDuty-dienst. Je volgende dienst begint %s.",
+		dailySummaryNoShiftBody: "📋 Je hebt geen PagerDuty-dienst en in de komende 7 dagen geen diensten.",
+		unknownTitle:            "PagerDuty-melding",
+		unknownBody:             "Onbekende meldingsgebeurtenis",
+		startedTitle:            "PagerDuty-notifier gestart",
+		stoppedTitle:            "PagerDuty-notifier gestopt",
+		startedAtLabel:          "Begonnen",
+		startsAtLabel:           "Begint",
+		startsInLabel:           "Begint over",
+		endsAtLabel:             "Eindigt",
+		endsInLabel:             "Eindigt over",
+		handoffFromLabel:        "Overname van",
+		handoffToLabel:          "Overdracht aan",
+		openIncidentsLabel:      "Open incidenten",
+		noOpenIncidents:         "geen",
+		openIncidentsOldest:     "%d, oudste %s",
+		userLabel:               "Gebruiker",
+		shiftAddedLabel:         "Toegevoegd",
+		shiftRemovedLabel:       "Verwijderd",
+		shiftMovedLabel:         "Verplaatst",
+		scheduleLinkTitle:       "PagerDuty-rooster openen",
+		onCallStatus:            "Dienst",
+		offCallStatus:           "Geen dienst",
+		nextShiftLabel:          "Volgende",
+		hour:                    "uur",
+		hours:                   "uur",
+		minute:                  "minuut",
+		minutes:                 "minuten",
+		and:                     "en",
+	},
+}
+
+// SupportedLocales returns the locales with a built-in message catalog
+func SupportedLocales() []Locale {
+	locales := make([]Locale, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+	return locales
+}
+
+// Messages renders notification titles and bodies for a locale
+type Messages struct {
+	locale  Locale
+	catalog catalog
+	// scheduleName and userName are added to titles and bodies when known
+	scheduleName string
+	userName     string
+	// location is the timezone times are shown in; nil shows them in UTC
+	location *time.Location
+}
+
+// NewMessages creates a message renderer for the given locale
+func NewMessages(locale Locale) (*Messages, error) {
+	c, ok := catalogs[locale]
+	if !ok {
+		return nil, fmt.Errorf("unsupported message locale: %s", locale)
+	}
+	return &Messages{locale: locale, catalog: c}, nil
+}
+
+// DefaultMessages returns the English message renderer
+func DefaultMessages() *Messages {
+	return &Messages{locale: DefaultLocale, catalog: catalogs[DefaultLocale]}
+}
+
+// Locale returns the locale used by the renderer
+func (m *Messages) Locale() Locale {
+	return m.locale
+}
+
+// WithNames returns a copy of the renderer that names the schedule in titles and the user in
+// bodies; empty names are left out
+func (m *Messages) WithNames(scheduleName, userName string) *Messages {
+	named := *m
+	named.scheduleName = scheduleName
+	named.userName = userName
+	return &named
+}
+
+// WithLocation returns a copy of the renderer that shows times in the given timezone
+func (m *Messages) WithLocation(location *time.Location) *Messages {
+	localized := *m
+	localized.location = location
+	return &localized
+}
+
+// localTime converts a time into the message timezone
+func (m *Messages) localTime(t time.Time) time.Time {
+	if m.location == nil {
+		return t.UTC()
+	}
+	return t.In(m.location)
+}
+
+// Title returns the notification title for an event, followed by the schedule name if known
+func (m *Messages) Title(event NotificationEvent) string {
+	title := m.eventTitle(event)
+	if m.scheduleName == "" {
+		return title
+	}
+	return fmt.Sprintf("%s: %s", title, m.scheduleName)
+}
+
+// eventTitle returns the localized title for an event
+func (m *Messages) eventTitle(event NotificationEvent) string {
+	switch event {
+	case EventShiftStarted:
+		return m.catalog.shiftStartedTitle
+	case EventUpcomingShift:
+		return m.catalog.upcomingTitle
+	case EventShiftEnding:
+		return m.catalog.shiftEndingTitle
+	case EventShiftEnded:
+		return m.catalog.shiftEndedTitle
+	case EventShiftOverridden:
+		return m.catalog.shiftOverriddenTitle
+	case EventCoverageStarted:
+		return m.catalog.coverageStartedTitle
+	case EventCoverageGap:
+		return m.catalog.coverageGapTitle
+	case EventUnexpectedOnCall:
+		return m.catalog.unexpectedOnCallTitle
+	case EventScheduleChanged:
+		return m.catalog.scheduleChangedTitle
+	case EventNotifierDegraded:
+		return m.catalog.notifierDegradedTitle
+	case EventDailySummary:
+		return m.catalog.dailySummaryTitle
+	default:
+		return m.catalog.unknownTitle
+	}
+}
+
+// Body returns the notification message for an event
+func (m *Messages) Body(event NotificationEvent, shiftStartTime time.Time) string {
+	return m.BodyAt(event, shiftStartTime, time.Now())
+}
+
+// BodyAt returns the notification message for an event as it should read when delivered at the given time
+// For shift ending reminders the time passed is the end of the shift rather than its start
+func (m *Messages) BodyAt(event NotificationEvent, shiftStartTime, deliverAt time.Time) string {
+	switch event {
+	case EventShiftStarted:
+		return m.catalog.shiftStartedBody
+	case EventUpcomingShift:
+		remaining := m.FormatDuration(shiftStartTime.Sub(deliverAt))
+		if remaining == "" {
+			return m.catalog.upcomingSoonBody
+		}
+		return fmt.Sprintf(m.catalog.upcomingBody, remaining)
+	case EventShiftEnding:
+		remaining := m.FormatDuration(shiftStartTime.Sub(deliverAt))
+		if remaining == "" {
+			return m.catalog.shiftEndingSoonBody
+		}
+		return fmt.Sprintf(m.catalog.shiftEndingBody, remaining)
+	case EventShiftEnded:
+		return m.catalog.shiftEndedBody
+	case EventShiftOverridden:
+		return m.catalog.shiftOverriddenBody
+	case EventCoverageStarted:
+		return m.catalog.coverageStartedBody
+	case EventCoverageGap:
+		return m.catalog.coverageGapBody
+	case EventUnexpectedOnCall:
+		return m.catalog.unexpectedOnCallBody
+	case EventScheduleChanged:
+		return m.catalog.scheduleChangedBody
+	case EventNotifierDegraded:
+		return m.catalog.notifierDegradedBody
+	case EventDailySummary:
+		// The summary is about the current shift if it has started, or else the next one
+		switch {
+		case shiftStartTime.IsZero():
+			return m.catalog.dailySummaryNoShiftBody
+		case shiftStartTime.After(deliverAt):
+			return fmt.Sprintf(m.catalog.dailySummaryNextBody, m.localTime(shiftStartTime).Format(windowEndLayout))
+		default:
+			return m.catalog.dailySummaryOnCallBody
+		}
+	default:
+		return m.catalog.unknownBody
+	}
+}
+
+// ShiftBodyAt returns the notification message for an event as it should read when delivered
+// at the given time, including the shift window or responder in messages that mention them,
+// the user's name, and any known handoffs and open incidents
+func (m *Messages) ShiftBodyAt(event NotificationEvent, shift Shift, deliverAt time.Time) string {
+	message := m.shiftMessage(event, shift, deliverAt)
+	for _, item := range m.shiftDetails(shift) {
+		message += fmt.Sprintf("\n%s: %s", item.Label, item.Value)
+	}
+	return message
+}
+
+// shiftMessage returns the notification message for a shift without the user, handoff, and incident details
+func (m *Messages) shiftMessage(event NotificationEvent, shift Shift, deliverAt time.Time) string {
+	if event == EventUnexpectedOnCall && shift.Responder != "" {
+		return fmt.Sprintf(m.catalog.unexpectedResponderBody, shift.Responder)
+	}
+	var windowBody string
+	switch event {
+	case EventCoverageStarted:
+		windowBody = m.catalog.coverageWindowBody
+	case EventCoverageGap:
+		windowBody = m.catalog.coverageGapWindowBody
+	}
+	if event == EventShiftStarted && !shift.End.IsZero() {
+		if length := m.FormatDuration(shift.End.Sub(shift.Start)); length != "" {
+			return fmt.Sprintf(m.catalog.shiftStartedEndsBody, m.localTime(shift.End).Format(windowEndLayout), length)
+		}
+	}
+	if event == EventDailySummary && !shift.Start.After(deliverAt) && !shift.Start.IsZero() && !shift.End.IsZero() {
+		return fmt.Sprintf(m.catalog.dailySummaryEndsBody, m.localTime(shift.End).Format(windowEndLayout))
+	}
+	if windowBody != "" && !shift.Start.IsZero() && !shift.End.IsZero() {
+		return fmt.Sprintf(windowBody, m.localTime(shift.Start).Format(windowStartLayout), m.localTime(shift.End).Format(windowEndLayout))
+	}
+	return m.BodyAt(event, shift.eventTime(event), deliverAt)
+}
+
+// Markdown returns the notification message for an event as a Markdown document
+// Shift times are rendered in bold as a bullet list below the message
+func (m *Messages) Markdown(event NotificationEvent, shiftStartTime time.Time) (string, error) {
+	return m.MarkdownAt(event, shiftStartTime, time.Now())
+}
+
+// MarkdownAt returns the Markdown notification message as it should read when delivered at the given time
+func (m *Messages) MarkdownAt(event NotificationEvent, shiftStartTime, deliverAt time.Time) (string, error) {
+	return m.ShiftMarkdownAt(event, Shift{Start: shiftStartTime}, deliverAt)
+}
+
+// ShiftMarkdownAt returns the Markdown notification message for a shift as it should read when delivered at the given time
+func (m *Messages) ShiftMarkdownAt(event NotificationEvent, shift Shift, deliverAt time.Time) (string, error) {
+	var buf bytes.Buffer
+	if err := markdownTemplate.Execute(&buf, m.details(event, shift, deliverAt)); err != nil {
+		return "", fmt.Errorf("failed to render markdown message: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// HTML returns the notification message for an event as HTML, with shift times in bold
+func (m *Messages) HTML(event NotificationEvent, shiftStartTime time.Time) (string, error) {
+	return m.ShiftHTML(event, Shift{Start: shiftStartTime})
+}
+
+// ShiftHTML returns the notification message for a shift as HTML, with shift times in bold
+func (m *Messages) ShiftHTML(event NotificationEvent, shift Shift) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, m.details(event, shift, time.Now())); err != nil {
+		return "", fmt.Errorf("failed to render html message: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ScheduleLinkTitle returns the title used for links to the PagerDuty schedule
+func (m *Messages) ScheduleLinkTitle() string {
+	return m.catalog.scheduleLinkTitle
+}
+
+// Status returns a short on-call status line and, if known, the start of the next shift
+func (m *Messages) Status(onCall bool, nextShiftStart *time.Time) (string, string) {
+	status := m.catalog.offCallStatus
+	if onCall {
+		status = m.catalog.onCallStatus
+	}
+	if nextShiftStart == nil {
+		return status, ""
+	}
+	return status, fmt.Sprintf("%s: %s", m.catalog.nextShiftLabel, m.localTime(*nextShiftStart).Format(detailsTimeLayout))
+}
+
+// details collects the message and shift details for formatted bodies
+func (m *Messages) details(event NotificationEvent, shift Shift, deliverAt time.Time) detailsData {
+	shiftStartTime := shift.Start
+	data := detailsData{Message: m.shiftMessage(event, shift, deliverAt), Handoffs: m.shiftDetails(shift)}
+
+	switch event {
+	case EventShiftStarted, EventCoverageStarted, EventUnexpectedOnCall:
+		data.ShiftTimeLabel = m.catalog.startedAtLabel
+		data.ShiftTime = m.localTime(shiftStartTime).Format(detailsTimeLayout)
+	case EventShiftOverridden, EventCoverageGap:
+		data.ShiftTimeLabel = m.catalog.startsAtLabel
+		data.ShiftTime = m.localTime(shiftStartTime).Format(detailsTimeLayout)
+	case EventUpcomingShift:
+		data.ShiftTimeLabel = m.catalog.startsAtLabel
+		data.ShiftTime = m.localTime(shiftStartTime).Format(detailsTimeLayout)
+		data.RemainingLabel = m.catalog.startsInLabel
+		data.Remaining = m.FormatDuration(shiftStartTime.Sub(deliverAt))
+	case EventDailySummary:
+		switch {
+		case shiftStartTime.After(deliverAt):
+			data.ShiftTimeLabel = m.catalog.startsAtLabel
+			data.ShiftTime = m.localTime(shiftStartTime).Format(detailsTimeLayout)
+			data.RemainingLabel = m.catalog.startsInLabel
+			data.Remaining = m.FormatDuration(shiftStartTime.Sub(deliverAt))
+		case !shiftStartTime.IsZero() && !shift.End.IsZero():
+			data.ShiftTimeLabel = m.catalog.endsAtLabel
+			data.ShiftTime = m.localTime(shift.End).Format(detailsTimeLayout)
+			data.RemainingLabel = m.catalog.endsInLabel
+			data.Remaining = m.FormatDuration(shift.End.Sub(deliverAt))
+		}
+	case EventShiftEnding:
+		shiftEndTime := shift.eventTime(event)
+		data.ShiftTimeLabel = m.catalog.endsAtLabel
+		data.ShiftTime = m.localTime(shiftEndTime).Format(detailsTimeLayout)
+		data.RemainingLabel = m.catalog.endsInLabel
+		data.Remaining = m.FormatDuration(shiftEndTime.Sub(deliverAt))
+	}
+
+	return data
+}
+
+// shiftDetails names the user and describes who the shift is handed over from and to, the open
+// incidents, and any schedule changes
+func (m *Messages) shiftDetails(shift Shift) []detailsItem {
+	var items []detailsItem
+	if m.userName != "" {
+		items = append(items, detailsItem{Label: m.catalog.userLabel, Value: m.userName})
+	}
+	if shift.Previous != nil {
+		items = append(items, m.handoffItem(m.catalog.handoffFromLabel, shift.Previous))
+	}
+	if shift.Next != nil {
+		items = append(items, m.handoffItem(m.catalog.handoffToLabel, shift.Next))
+	}
+	if shift.OpenIncidents != nil {
+		items = append(items, detailsItem{Label: m.catalog.openIncidentsLabel, Value: m.incidentSummary(shift.OpenIncidents)})
+	}
+	for _, change := range shift.Changes {
+		items = append(items, m.changeItem(change))
+	}
+	return items
+}
+
+// changeItem describes a schedule change, e.g. "Moved: Mon 09:00–Mon 17:00 UTC → Tue 09:00–Tue 17:00 UTC"
+func (m *Messages) changeItem(change ShiftChange) detailsItem {
+	window := m.formatWindow(change.Start, change.End)
+	switch change.Kind {
+	case ShiftAdded:
+		return detailsItem{Label: m.catalog.shiftAddedLabel, Value: window}
+	case ShiftRemoved:
+		return detailsItem{Label: m.catalog.shiftRemovedLabel, Value: window}
+	default:
+		return detailsItem{Label: m.catalog.shiftMovedLabel, Value: m.formatWindow(change.PreviousStart, change.PreviousEnd) + " → " + window}
+	}
+}
+
+// formatWindow formats a shift window in the message timezone, e.g. "Mon 09:00–Mon 17:00 UTC"
+func (m *Messages) formatWindow(start, end time.Time) string {
+	return m.localTime(start).Format(windowStartLayout) + "–" + m.localTime(end).Format(windowEndLayout)
+}
+
+// incidentSummary formats the open incident count and the age of the oldest, e.g. "3, oldest 2 hours"
+func (m *Messages) incidentSummary(summary *IncidentSummary) string {
+	if summary.Count == 0 {
+		return m.catalog.noOpenIncidents
+	}
+	if !summary.Oldest.IsZero() {
+		if age := m.FormatDuration(time.Since(summary.Oldest)); age != "" {
+			return fmt.Sprintf(m.catalog.openIncidentsOldest, summary.Count, age)
+		}
+	}
+	return fmt.Sprintf("%d", summary.Count)
+}
+
+// handoffItem formats a handoff as the other person's name and the handover time
+func (m *Messages) handoffItem(label string, handoff *Handoff) detailsItem {
+	value := handoff.Name
+	if !handoff.At.IsZero() {
+		value = fmt.Sprintf("%s (%s)", handoff.Name, m.localTime(handoff.At).Format(detailsTimeLayout))
+	}
+	return detailsItem{Label: label, Value: value}
+}
+
+// LifecycleTitle returns the title used for service start/stop messages
+func (m *Messages) LifecycleTitle(started bool) string {
+	if started {
+		return m.catalog.startedTitle
+	}
+	return m.catalog.stoppedTitle
+}
+
+// FormatDuration formats a duration as hours and minutes in the renderer's locale
+// Returns an empty string for durations shorter than a minute
+func (m *Messages) FormatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	h := m.plural(hours, m.catalog.hour, m.catalog.hours)
+	min := m.plural(minutes, m.catalog.minute, m.catalog.minutes)
+
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%s %s %s", h, m.catalog.and, min)
+	case hours > 0:
+		return h
+	case minutes > 0:
+		return min
+	default:
+		return ""
+	}
+}
+
+// plural formats a count with the singular or plural unit
+func (m *Messages) plural(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
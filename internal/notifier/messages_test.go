@@ -0,0 +1,232 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMessagesRejectsUnknownLocale(t *testing.T) {
+	if _, err := NewMessages(Locale("xx")); err == nil {
+		t.Fatalf("expected error for unsupported locale")
+	}
+}
+
+func TestFormatDurationPluralization(t *testing.T) {
+	messages := DefaultMessages()
+
+	cases := map[time.Duration]string{
+		time.Hour:                    "1 hour",
+		2 * time.Hour:                "2 hours",
+		time.Minute:                  "1 minute",
+		90 * time.Minute:             "1 hour and 30 minutes",
+		2*time.Hour + 61*time.Second: "2 hours and 1 minute",
+		30 * time.Second:             "",
+	}
+
+	for duration, expected := range cases {
+		if got := messages.FormatDuration(duration); got != expected {
+			t.Fatalf("FormatDuration(%v): expected %q, got %q", duration, expected, got)
+		}
+	}
+}
+
+func TestGermanUpcomingShiftBody(t *testing.T) {
+	messages, err := NewMessages(LocaleGerman)
+	if err != nil {
+		t.Fatalf("NewMessages returned error: %v", err)
+	}
+
+	body := messages.Body(EventUpcomingShift, time.Now().Add(2*time.Hour+30*time.Second))
+	if !strings.Contains(body, "2 Stunden") {
+		t.Fatalf("expected German duration in body, got %q", body)
+	}
+	if got := messages.Title(EventShiftStarted); got != "PagerDuty-Rufbereitschaft begonnen" {
+		t.Fatalf("unexpected German title: %s", got)
+	}
+}
+
+func TestShiftEndingMessagesCountDownToEnd(t *testing.T) {
+	messages := DefaultMessages()
+	now := time.Now()
+	shift := Shift{Start: now.Add(-8 * time.Hour), End: now.Add(time.Hour + 30*time.Second)}
+
+	body := messages.ShiftBodyAt(EventShiftEnding, shift, now)
+	if !strings.HasPrefix(body, "⏳ Your PagerDuty on-call shift ends in 1 hour.") {
+		t.Fatalf("expected time until shift end in body, got %q", body)
+	}
+	if got := messages.Body(EventShiftEnding, shift.End); !strings.Contains(got, "ends in 1 hour") {
+		t.Fatalf("expected Body to count down to the given end, got %q", got)
+	}
+
+	markdown, err := messages.ShiftMarkdownAt(EventShiftEnding, shift, now)
+	if err != nil {
+		t.Fatalf("ShiftMarkdownAt returned error: %v", err)
+	}
+	if !strings.Contains(markdown, "**Ends:**") || !strings.Contains(markdown, "**Ends in:** 1 hour") {
+		t.Fatalf("expected shift end details in markdown, got %q", markdown)
+	}
+}
+
+func TestMarkdownIncludesShiftDetails(t *testing.T) {
+	messages := DefaultMessages()
+	shiftStart := time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+
+	body, err := messages.Markdown(EventUpcomingShift, shiftStart)
+	if err != nil {
+		t.Fatalf("Markdown returned error: %v", err)
+	}
+	if !strings.Contains(body, "- **Starts:** Mon 04 Mar 09:00 UTC") {
+		t.Fatalf("expected bold shift start in body, got %q", body)
+	}
+	if !strings.Contains(body, "- **Starts in:** ") {
+		t.Fatalf("expected time remaining in body, got %q", body)
+	}
+
+	ended, err := messages.Markdown(EventShiftEnded, time.Now())
+	if err != nil {
+		t.Fatalf("Markdown returned error: %v", err)
+	}
+	if strings.Contains(ended, "- **") {
+		t.Fatalf("expected no shift details for shift ended, got %q", ended)
+	}
+}
+
+func TestCoverageBodyIncludesWindow(t *testing.T) {
+	messages := DefaultMessages()
+	shift := Shift{
+		Start: time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2030, time.March, 4, 17, 0, 0, 0, time.UTC),
+	}
+
+	body := messages.ShiftBodyAt(EventCoverageStarted, shift, time.Now())
+	if !strings.Contains(body, "from Mon 09:00–Mon 17:00 UTC") {
+		t.Fatalf("expected coverage window in body, got %q", body)
+	}
+	if got := messages.ShiftBodyAt(EventCoverageStarted, Shift{Start: shift.Start}, time.Now()); got != messages.Body(EventCoverageStarted, shift.Start) {
+		t.Fatalf("expected generic coverage body without an end, got %q", got)
+	}
+}
+
+func TestShiftStartedBodyIncludesEnd(t *testing.T) {
+	messages := DefaultMessages()
+	shift := Shift{
+		Start: time.Date(2030, time.March, 5, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2030, time.March, 8, 9, 0, 0, 0, time.UTC),
+	}
+
+	body := messages.ShiftBodyAt(EventShiftStarted, shift, time.Now())
+	if !strings.Contains(body, "It ends Fri 09:00 UTC (72 hours).") {
+		t.Fatalf("expected shift end and length in body, got %q", body)
+	}
+	if got := messages.ShiftBodyAt(EventShiftStarted, Shift{Start: shift.Start}, time.Now()); got != messages.Body(EventShiftStarted, shift.Start) {
+		t.Fatalf("expected generic shift started body without an end, got %q", got)
+	}
+}
+
+func TestDailySummaryBodies(t *testing.T) {
+	messages := DefaultMessages()
+	deliverAt := time.Date(2030, time.March, 5, 8, 0, 0, 0, time.UTC)
+	shift := Shift{
+		Start: time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2030, time.March, 6, 9, 0, 0, 0, time.UTC),
+	}
+
+	if body := messages.ShiftBodyAt(EventDailySummary, shift, deliverAt); !strings.Contains(body, "on call in PagerDuty today. Your shift ends Wed 09:00 UTC.") {
+		t.Fatalf("expected current shift end in body, got %q", body)
+	}
+	next := Shift{Start: time.Date(2030, time.March, 7, 9, 0, 0, 0, time.UTC)}
+	if body := messages.ShiftBodyAt(EventDailySummary, next, deliverAt); !strings.Contains(body, "not on call in PagerDuty. Your next shift starts Thu 09:00 UTC.") {
+		t.Fatalf("expected next shift start in body, got %q", body)
+	}
+	if body := messages.ShiftBodyAt(EventDailySummary, Shift{}, deliverAt); !strings.Contains(body, "no shifts in the next 7 days") {
+		t.Fatalf("expected no shifts body, got %q", body)
+	}
+}
+
+func TestWithLocationShowsLocalTimes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	messages := DefaultMessages().WithLocation(berlin)
+	shift := Shift{
+		Start: time.Date(2030, time.March, 4, 8, 0, 0, 0, time.UTC),
+		End:   time.Date(2030, time.March, 4, 16, 0, 0, 0, time.UTC),
+	}
+
+	body := messages.ShiftBodyAt(EventCoverageStarted, shift, time.Now())
+	if !strings.Contains(body, "from Mon 09:00–Mon 17:00 CET") {
+		t.Fatalf("expected coverage window in Berlin time, got %q", body)
+	}
+	if body := DefaultMessages().ShiftBodyAt(EventCoverageStarted, shift, time.Now()); !strings.Contains(body, "from Mon 08:00–Mon 16:00 UTC") {
+		t.Fatalf("expected coverage window in UTC by default, got %q", body)
+	}
+}
+
+func TestHandoffsInBodies(t *testing.T) {
+	messages := DefaultMessages()
+	shift := Shift{
+		Start:    time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2030, time.March, 4, 17, 0, 0, 0, time.UTC),
+		Previous: &Handoff{Name: "Jane Doe", At: time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)},
+		Next:     &Handoff{Name: "Bob Smith", At: time.Date(2030, time.March, 4, 17, 0, 0, 0, time.UTC)},
+	}
+
+	body := messages.ShiftBodyAt(EventShiftStarted, shift, time.Now())
+	if !strings.Contains(body, "\nTaking over from: Jane Doe (Mon 04 Mar 09:00 UTC)") {
+		t.Fatalf("expected previous responder in body, got %q", body)
+	}
+
+	markdown, err := messages.ShiftMarkdownAt(EventShiftStarted, shift, time.Now())
+	if err != nil {
+		t.Fatalf("ShiftMarkdownAt returned error: %v", err)
+	}
+	if !strings.Contains(markdown, "- **Handing over to:** Bob Smith (Mon 04 Mar 17:00 UTC)") {
+		t.Fatalf("expected next responder in markdown, got %q", markdown)
+	}
+	if strings.Count(markdown, "Jane Doe") != 1 {
+		t.Fatalf("expected previous responder once in markdown, got %q", markdown)
+	}
+
+	ended, err := messages.ShiftMarkdownAt(EventShiftEnded, Shift{Next: shift.Next}, time.Now())
+	if err != nil {
+		t.Fatalf("ShiftMarkdownAt returned error: %v", err)
+	}
+	if !strings.Contains(ended, "\n\n- **Handing over to:** Bob Smith") {
+		t.Fatalf("expected handoff list below shift ended message, got %q", ended)
+	}
+}
+
+func TestOpenIncidentsSummary(t *testing.T) {
+	messages := DefaultMessages()
+	shift := Shift{
+		Start:         time.Now(),
+		OpenIncidents: &IncidentSummary{Count: 3, Oldest: time.Now().Add(-2*time.Hour - 30*time.Second)},
+	}
+
+	body := messages.ShiftBodyAt(EventShiftStarted, shift, time.Now())
+	if !strings.Contains(body, "\nOpen incidents: 3, oldest 2 hours") {
+		t.Fatalf("expected incident summary in body, got %q", body)
+	}
+
+	body = messages.ShiftBodyAt(EventShiftStarted, Shift{Start: time.Now(), OpenIncidents: &IncidentSummary{}}, time.Now())
+	if !strings.Contains(body, "\nOpen incidents: none") {
+		t.Fatalf("expected no open incidents in body, got %q", body)
+	}
+}
+
+func TestNamesInTitlesAndBodies(t *testing.T) {
+	messages := DefaultMessages().WithNames("Primary Rota", "Jane Doe")
+
+	if got := messages.Title(EventShiftStarted); got != "PagerDuty On-Call Shift Started: Primary Rota" {
+		t.Fatalf("expected schedule name in title, got %q", got)
+	}
+	body := messages.ShiftBodyAt(EventShiftEnded, Shift{}, time.Now())
+	if !strings.HasSuffix(body, "\nUser: Jane Doe") {
+		t.Fatalf("expected user name in body, got %q", body)
+	}
+	if got := DefaultMessages().Title(EventShiftStarted); got != "PagerDuty On-Call Shift Started" {
+		t.Fatalf("expected the renderer to be copied, got %q", got)
+	}
+}
@@ -1,6 +1,8 @@
 package notifier
 
 import (
+	"context"
+	"fmt"
 	"time"
 )
 
@@ -11,10 +13,148 @@ const (
 	EventShiftStarted  NotificationEvent = "shift_started"
 	EventUpcomingShift NotificationEvent = "upcoming_shift"
 	EventShiftEnded    NotificationEvent = "shift_ended"
+	// EventShiftEnding is sent shortly before the user's current shift ends
+	EventShiftEnding NotificationEvent = "shift_ending"
+	// EventShiftOverridden is sent when an override removes the user from an upcoming shift
+	EventShiftOverridden NotificationEvent = "shift_overridden"
+	// EventCoverageStarted replaces EventShiftStarted when the user is on call because of an override
+	EventCoverageStarted NotificationEvent = "coverage_started"
+	// EventCoverageGap is sent when a monitored schedule has nobody on call in the coming hours
+	EventCoverageGap NotificationEvent = "coverage_gap"
+	// EventUnexpectedOnCall is sent when someone outside the expected users is on call
+	EventUnexpectedOnCall NotificationEvent = "unexpected_oncall"
+	// EventScheduleChanged is sent when the user's upcoming shifts are added, removed, or moved
+	EventScheduleChanged NotificationEvent = "schedule_changed"
+	// EventNotifierDegraded is sent when checks keep failing, e.g. because the API token expired
+	EventNotifierDegraded NotificationEvent = "notifier_degraded"
+	// EventDailySummary is sent once a day with the user's current or next shift
+	EventDailySummary NotificationEvent = "daily_summary"
 )
 
+// Events returns all notification events that can be configured
+func Events() []NotificationEvent {
+	return []NotificationEvent{EventShiftStarted, EventUpcomingShift, EventShiftEnding, EventShiftEnded, EventShiftOverridden, EventCoverageStarted, EventCoverageGap, EventUnexpectedOnCall, EventScheduleChanged, EventNotifierDegraded, EventDailySummary}
+}
+
+// ParseEvent converts a string into a known NotificationEvent
+func ParseEvent(s string) (NotificationEvent, error) {
+	for _, event := range Events() {
+		if string(event) == s {
+			return event, nil
+		}
+	}
+	return "", fmt.Errorf("unknown notification event: %s", s)
+}
+
+// EventOverrides maps notification events to backend-specific values
+type EventOverrides map[NotificationEvent]string
+
+// Get returns the override for an event, or fallback if none is configured
+func (o EventOverrides) Get(event NotificationEvent, fallback string) string {
+	if value, ok := o[event]; ok {
+		return value
+	}
+	return fallback
+}
+
+// DefaultTimeout bounds each request to a notification backend unless configured otherwise
+const DefaultTimeout = 30 * time.Second
+
+// timeoutOrDefault returns timeout, or DefaultTimeout if it is not set
+func timeoutOrDefault(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTimeout
+	}
+	return timeout
+}
+
 // Notifier defines the interface for notification backends
+// Sends are abandoned when ctx is cancelled, e.g. on shutdown
 type Notifier interface {
-	Notify(message string) error
-	NotifyWithEvent(event NotificationEvent, shiftStartTime time.Time) error
+	Notify(ctx context.Context, message string) error
+	NotifyWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime time.Time) error
+}
+
+// Shift describes the time window of an on-call shift; End is zero when unknown
+type Shift struct {
+	Start time.Time
+	End   time.Time
+	// Responder names who is on call when it is someone other than the monitored user
+	Responder string
+	// Previous and Next name who hands the shift over to the user and who takes over after
+	// it, when known
+	Previous *Handoff
+	Next     *Handoff
+	// OpenIncidents summarises the incidents waiting for the user when the shift starts
+	OpenIncidents *IncidentSummary
+	// Changes lists the edits to the user's upcoming shifts for schedule changed notifications
+	Changes []ShiftChange
+}
+
+// ShiftChangeKind says how a shift changed between two schedule snapshots
+type ShiftChangeKind string
+
+const (
+	ShiftAdded   ShiftChangeKind = "added"
+	ShiftRemoved ShiftChangeKind = "removed"
+	ShiftMoved   ShiftChangeKind = "moved"
+)
+
+// ShiftChange describes an upcoming shift that was added, removed, or moved. Start and End
+// are the shift as it is now, or as it was for removed shifts; PreviousStart and PreviousEnd
+// are only set for moved shifts.
+type ShiftChange struct {
+	Kind          ShiftChangeKind
+	Start         time.Time
+	End           time.Time
+	PreviousStart time.Time
+	PreviousEnd   time.Time
+}
+
+// IncidentSummary counts open incidents; Oldest is zero when there are none
+type IncidentSummary struct {
+	Count  int
+	Oldest time.Time
+}
+
+// Handoff names the other side of a shift handover and when it happens
+type Handoff struct {
+	Name string
+	At   time.Time
+}
+
+// Duration returns the length of the shift, or zero if either bound is unknown
+func (s Shift) Duration() time.Duration {
+	if s.Start.IsZero() || s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// eventTime returns the time a notification for the event refers to: the shift end for
+// shift ending and ended events, and the shift start otherwise
+func (s Shift) eventTime(event NotificationEvent) time.Time {
+	if (event == EventShiftEnded || event == EventShiftEnding) && !s.End.IsZero() {
+		return s.End
+	}
+	return s.Start
+}
+
+// ShiftNotifier is implemented by backends that can include full shift details in a notification
+type ShiftNotifier interface {
+	NotifyShift(ctx context.Context, event NotificationEvent, shift Shift) error
+}
+
+// ScheduledNotifier is implemented by backends that can hand a notification to the
+// server for delivery at a later time, and take it back before then by the returned ID
+type ScheduledNotifier interface {
+	ScheduleWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime, deliverAt time.Time) (string, error)
+	CancelScheduled(ctx context.Context, id string) error
+	MaxScheduleDelay() time.Duration
+}
+
+// StatusPublisher is implemented by backends that can display the current on-call status
+// outside of push notifications (e.g. a watch face or widget)
+type StatusPublisher interface {
+	PublishStatus(ctx context.Context, onCall bool, nextShiftStart *time.Time) error
 }
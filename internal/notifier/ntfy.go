@@ -2,9 +2,15 @@ package notifier
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
 )
 
 // NtfyNotifier sends notifications via ntfy.sh or self-hosted ntfy server
@@ -12,77 +18,188 @@ type NtfyNotifier struct {
 	serverURL string
 	topic     string
 	apiKey    string
+	messages  *Messages
+	opts      NtfyOptions
 	client    *http.Client
 }
 
+// NtfyOptions holds optional ntfy notifier settings
+type NtfyOptions struct {
+	// Priorities overrides the default priority for individual events
+	Priorities EventOverrides
+	// ActionsURL is the public base URL of the notifier's HTTP API; when set,
+	// shift notifications include Acknowledge and Snooze action buttons
+	ActionsURL string
+	// ActionsToken is sent as a Bearer token by the action buttons. Anyone who can read the
+	// topic sees it, so it should only allow acknowledging and snoozing.
+	ActionsToken string
+	// Tags overrides the default comma-separated tags for individual events; an empty value sends no tags
+	Tags EventOverrides
+	// Emails forwards individual events to an email address via the ntfy server
+	Emails EventOverrides
+	// Markdown enables Markdown formatting with shift details in the message body
+	Markdown bool
+	// Timeout bounds each request to the ntfy server; zero uses DefaultTimeout
+	Timeout time.Duration
+}
+
+// NtfyMaxScheduleDelay is the default maximum delay ntfy servers accept for scheduled delivery
+const NtfyMaxScheduleDelay = 72 * time.Hour
+
+// ntfySnoozeDuration is the snooze length offered by the Snooze action button
+const ntfySnoozeDuration = "1h"
+
+// NtfyPriorities lists the priority values accepted by ntfy
+var NtfyPriorities = []string{"min", "low", "default", "high", "max", "urgent", "1", "2", "3", "4", "5"}
+
 // NewNtfyNotifier creates a new ntfy notifier
-func NewNtfyNotifier(serverURL, topic, apiKey string) *NtfyNotifier {
+func NewNtfyNotifier(serverURL, topic, apiKey string, messages *Messages, opts NtfyOptions) *NtfyNotifier {
 	return &NtfyNotifier{
 		serverURL: serverURL,
 		topic:     topic,
 		apiKey:    apiKey,
-		client:    &http.Client{Timeout: 30 * time.Second},
+		messages:  messages,
+		opts:      opts,
+		client:    &http.Client{Timeout: timeoutOrDefault(opts.Timeout), Transport: httpclient.WithUserAgent(nil)},
 	}
 }
 
 // Notify sends a simple notification message
-func (n *NtfyNotifier) Notify(message string) error {
-	return n.NotifyWithEvent(EventShiftStarted, time.Now().UTC())
+func (n *NtfyNotifier) Notify(ctx context.Context, message string) error {
+	return n.NotifyWithEvent(ctx, EventShiftStarted, time.Now().UTC())
 }
 
 // NotifyWithEvent sends a notification with event-specific formatting
-func (n *NtfyNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTime time.Time) error {
-	var message, title string
+func (n *NtfyNotifier) NotifyWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime time.Time) error {
+	_, err := n.publish(ctx, event, Shift{Start: shiftStartTime}, time.Time{})
+	return err
+}
+
+// NotifyShift sends a notification including the shift window where the message uses it
+func (n *NtfyNotifier) NotifyShift(ctx context.Context, event NotificationEvent, shift Shift) error {
+	_, err := n.publish(ctx, event, shift, time.Time{})
+	return err
+}
+
+// ScheduleWithEvent publishes a notification that the ntfy server delivers at deliverAt,
+// returning the ID of the message ntfy holds until then
+func (n *NtfyNotifier) ScheduleWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime, deliverAt time.Time) (string, error) {
+	return n.publish(ctx, event, Shift{Start: shiftStartTime}, deliverAt)
+}
+
+// CancelScheduled deletes a scheduled message from the ntfy server before it is delivered
+func (n *NtfyNotifier) CancelScheduled(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/%s/%s", n.serverURL, n.topic, url.PathEscape(id))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if n.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", n.apiKey))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled ntfy notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy returned non-2xx status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MaxScheduleDelay returns how far in advance ntfy accepts scheduled messages
+func (n *NtfyNotifier) MaxScheduleDelay() time.Duration {
+	return NtfyMaxScheduleDelay
+}
+
+// publish sends a notification, delayed until deliverAt unless it is zero, and returns the ID
+// ntfy assigned to the message
+func (n *NtfyNotifier) publish(ctx context.Context, event NotificationEvent, shift Shift, deliverAt time.Time) (string, error) {
+	renderAt := time.Now()
+	if !deliverAt.IsZero() {
+		renderAt = deliverAt
+	}
+
+	message := n.messages.ShiftBodyAt(event, shift, renderAt)
+	if n.opts.Markdown {
+		markdown, err := n.messages.ShiftMarkdownAt(event, shift, renderAt)
+		if err != nil {
+			return "", err
+		}
+		message = markdown
+	}
+	title := n.messages.Title(event)
 	var priority, tags string
 
 	switch event {
 	case EventShiftStarted:
-		message = "🚨 Your PagerDuty on-call shift has started!"
-		title = "PagerDuty On-Call Shift Started"
 		priority = "urgent"
 		tags = "rotating_light,alarm_clock"
 	case EventUpcomingShift:
-		duration := time.Until(shiftStartTime)
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-
-		if hours > 0 {
-			if minutes > 0 {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours and %d minutes!", hours, minutes)
-			} else {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours!", hours)
-			}
-		} else if minutes > 0 {
-			message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d minutes!", minutes)
-		} else {
-			message = "⏰ Your PagerDuty on-call shift starts soon!"
-		}
-		title = "PagerDuty On-Call Shift Upcoming"
 		priority = "default"
 		tags = "alarm_clock,clock1"
+	case EventShiftEnding:
+		priority = "default"
+		tags = "hourglass_flowing_sand"
 	case EventShiftEnded:
-		message = "✅ Your PagerDuty on-call shift has ended. Enjoy the downtime!"
-		title = "PagerDuty On-Call Shift Ended"
 		priority = "default"
 		tags = "white_check_mark,beach_with_umbrella"
+	case EventCoverageStarted:
+		priority = "urgent"
+		tags = "rotating_light,handshake"
+	case EventCoverageGap:
+		priority = "high"
+		tags = "warning"
+	case EventUnexpectedOnCall:
+		priority = "high"
+		tags = "eyes"
+	case EventShiftOverridden:
+		priority = "high"
+		tags = "arrows_counterclockwise"
+	case EventScheduleChanged:
+		priority = "default"
+		tags = "calendar"
+	case EventNotifierDegraded:
+		priority = "high"
+		tags = "stop_sign"
+	case EventDailySummary:
+		priority = "low"
+		tags = "clipboard"
 	default:
-		message = "Unknown notification event"
-		title = "PagerDuty Notification"
 		priority = "default"
 		tags = "question"
 	}
 
 	url := fmt.Sprintf("%s/%s", n.serverURL, n.topic)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBufferString(message))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBufferString(message))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Title", title)
-	req.Header.Set("Priority", priority)
-	req.Header.Set("Tags", tags)
+	req.Header.Set("Priority", n.opts.Priorities.Get(event, priority))
+	if tags = n.opts.Tags.Get(event, tags); tags != "" {
+		req.Header.Set("Tags", tags)
+	}
+	if n.opts.Markdown {
+		req.Header.Set("Markdown", "yes")
+	}
+	if email := n.opts.Emails.Get(event, ""); email != "" {
+		req.Header.Set("Email", email)
+	}
+	if !deliverAt.IsZero() {
+		req.Header.Set("At", fmt.Sprintf("%d", deliverAt.Unix()))
+	}
+
+	// Attach action buttons to notifications that can be acknowledged or snoozed
+	if actions := n.actions(event); actions != "" {
+		req.Header.Set("Actions", actions)
+	}
 
 	// Add authentication if API key is provided
 	if n.apiKey != "" {
@@ -91,32 +208,63 @@ func (n *NtfyNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTime t
 
 	resp, err := n.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send ntfy notification: %w", err)
+		return "", fmt.Errorf("failed to send ntfy notification: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("ntfy returned non-2xx status: %d", resp.StatusCode)
+		return "", fmt.Errorf("ntfy returned non-2xx status: %d", resp.StatusCode)
 	}
 
-	return nil
+	// ntfy answers with the published message; the ID is only needed to cancel a scheduled one
+	var published struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&published)
+	return published.ID, nil
+}
+
+// actions builds the ntfy Actions header for an event, or returns an empty string if none apply
+func (n *NtfyNotifier) actions(event NotificationEvent) string {
+	if n.opts.ActionsURL == "" {
+		return ""
+	}
+	if event != EventShiftStarted && event != EventUpcomingShift && event != EventCoverageStarted {
+		return ""
+	}
+
+	baseURL := strings.TrimSuffix(n.opts.ActionsURL, "/")
+	auth := ""
+	if n.opts.ActionsToken != "" {
+		auth = fmt.Sprintf(", headers.Authorization=Bearer %s", n.opts.ActionsToken)
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf("http, Acknowledge, %s/api/v1/acknowledge, method=POST, clear=true%s", baseURL, auth),
+		fmt.Sprintf("http, Snooze %s, %s/api/v1/snooze?duration=%s, method=POST, clear=true%s", ntfySnoozeDuration, baseURL, ntfySnoozeDuration, auth),
+	}, "; ")
 }
 
 // SendBirthMessage sends a birth message (used for ntfy lifecycle)
-func (n *NtfyNotifier) SendBirthMessage() error {
-	return n.sendLifecycleMessage("Birth message", "PagerDuty Notifier Started", "white_check_mark")
+func (n *NtfyNotifier) SendBirthMessage(ctx context.Context) error {
+	return n.sendLifecycleMessage(ctx, "Birth message", n.messages.LifecycleTitle(true), "white_check_mark")
 }
 
 // SendWillMessage sends a will message (used for ntfy lifecycle)
-func (n *NtfyNotifier) SendWillMessage() error {
-	return n.sendLifecycleMessage("Will message", "PagerDuty Notifier Stopped", "x")
+func (n *NtfyNotifier) SendWillMessage(ctx context.Context) error {
+	return n.sendLifecycleMessage(ctx, "Will message", n.messages.LifecycleTitle(false), "x")
+}
+
+// SendReply sends a low-priority informational message, e.g. a reply to a remote command
+func (n *NtfyNotifier) SendReply(ctx context.Context, title, message string) error {
+	return n.sendLifecycleMessage(ctx, message, title, "speech_balloon")
 }
 
 // sendLifecycleMessage sends a lifecycle message for ntfy
-func (n *NtfyNotifier) sendLifecycleMessage(message, title, tags string) error {
+func (n *NtfyNotifier) sendLifecycleMessage(ctx context.Context, message, title, tags string) error {
 	url := fmt.Sprintf("%s/%s", n.serverURL, n.topic)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBufferString(message))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBufferString(message))
 	if err != nil {
 		return fmt.Errorf("failed to create lifecycle request: %w", err)
 	}
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Reconnect delays for the ntfy subscription stream
+const (
+	ntfySubscribeMinBackoff = 5 * time.Second
+	ntfySubscribeMaxBackoff = 5 * time.Minute
+)
+
+// ntfyStreamMessage is a single line of ntfy's JSON subscription stream
+type ntfyStreamMessage struct {
+	ID      string `json:"id"`
+	Event   string `json:"event"`
+	Topic   string `json:"topic"`
+	Message string `json:"message"`
+}
+
+// Subscribe streams messages published to topic and passes each message body to handle
+// It reconnects with backoff and only returns once the context is cancelled
+func (n *NtfyNotifier) Subscribe(ctx context.Context, topic string, handle func(message string)) {
+	SubscribeNtfy(ctx, func() *NtfyNotifier { return n }, topic, handle)
+}
+
+// SubscribeNtfy is Subscribe for a notifier that may be replaced, e.g. on a configuration
+// reload: current is called on every (re)connect, so new servers and credentials are used.
+func SubscribeNtfy(ctx context.Context, current func() *NtfyNotifier, topic string, handle func(message string)) {
+	backoff := ntfySubscribeMinBackoff
+	for {
+		connected, err := current().subscribeOnce(ctx, topic, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = ntfySubscribeMinBackoff
+		}
+		slog.Warn("Ntfy subscription interrupted", "topic", topic, "error", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > ntfySubscribeMaxBackoff {
+			backoff = ntfySubscribeMaxBackoff
+		}
+	}
+}
+
+// subscribeOnce reads the subscription stream until it ends
+// Returns whether the connection was established
+func (n *NtfyNotifier) subscribeOnce(ctx context.Context, topic string, handle func(message string)) (bool, error) {
+	url := fmt.Sprintf("%s/%s/json", n.serverURL, topic)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create subscription request: %w", err)
+	}
+
+	// Add authentication if API key is provided
+	if n.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", n.apiKey))
+	}
+
+	// The stream is long-lived, so the notifier's request timeout does not apply
+	client := &http.Client{Transport: n.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("subscription returned non-2xx status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var msg ntfyStreamMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			slog.Warn("Ignoring malformed ntfy stream line", "error", err)
+			continue
+		}
+		if msg.Event == "message" {
+			handle(msg.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, fmt.Errorf("failed to read subscription stream: %w", err)
+	}
+
+	return true, fmt.Errorf("subscription stream closed")
+}
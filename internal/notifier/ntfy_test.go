@@ -1,6 +1,8 @@
 package notifier
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -32,11 +34,11 @@ func TestNtfyNotifierSendsShiftStartedEvent(t *testing.T) {
 	}))
 	defer server.Close()
 
-	notifier := NewNtfyNotifier(server.URL, "alerts", "secret-key")
+	notifier := NewNtfyNotifier(server.URL, "alerts", "secret-key", DefaultMessages(), NtfyOptions{})
 	notifier.client = server.Client()
 
 	shiftStart := time.Now().UTC()
-	if err := notifier.NotifyWithEvent(EventShiftStarted, shiftStart); err != nil {
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, shiftStart); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
@@ -74,11 +76,185 @@ func TestNtfyNotifierPropagatesHTTPError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	notifier := NewNtfyNotifier(server.URL, "alerts", "")
+	notifier := NewNtfyNotifier(server.URL, "alerts", "", DefaultMessages(), NtfyOptions{})
 	notifier.client = server.Client()
 
-	err := notifier.NotifyWithEvent(EventShiftStarted, time.Now().UTC())
+	err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now().UTC())
 	if err == nil {
 		t.Fatalf("expected error when server returns non-2xx status")
 	}
 }
+
+func TestNtfyNotifierAppliesEventOverrides(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan ntfyRequestCapture, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captures <- ntfyRequestCapture{path: r.URL.Path, headers: r.Header.Clone()}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	opts := NtfyOptions{
+		Priorities: EventOverrides{EventUpcomingShift: "min"},
+		Emails:     EventOverrides{EventShiftStarted: "oncall@example.com"},
+		Tags:       EventOverrides{EventUpcomingShift: "hourglass,calendar"},
+	}
+	notifier := NewNtfyNotifier(server.URL, "alerts", "", DefaultMessages(), opts)
+	notifier.client = server.Client()
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, time.Now().UTC().Add(time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case capture := <-captures:
+		if got := capture.headers.Get("Priority"); got != "min" {
+			t.Fatalf("unexpected Priority header: %s", got)
+		}
+		if got := capture.headers.Get("Tags"); got != "hourglass,calendar" {
+			t.Fatalf("unexpected Tags header: %s", got)
+		}
+		if got := capture.headers.Get("Email"); got != "" {
+			t.Fatalf("expected no Email header for upcoming shift, got: %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive request")
+	}
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case capture := <-captures:
+		if got := capture.headers.Get("Email"); got != "oncall@example.com" {
+			t.Fatalf("unexpected Email header for shift started: %s", got)
+		}
+		if got := capture.headers.Get("Priority"); got != "urgent" {
+			t.Fatalf("expected the default Priority header for shift started, got: %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive request")
+	}
+}
+
+func TestNtfyNotifierAddsActionButtons(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan ntfyRequestCapture, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captures <- ntfyRequestCapture{path: r.URL.Path, headers: r.Header.Clone()}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	opts := NtfyOptions{ActionsURL: "https://notifier.example.com/", ActionsToken: "action-token"}
+	notifier := NewNtfyNotifier(server.URL, "alerts", "", DefaultMessages(), opts)
+	notifier.client = server.Client()
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftEnded, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	started := <-captures
+	expected := "http, Acknowledge, https://notifier.example.com/api/v1/acknowledge, method=POST, clear=true, headers.Authorization=Bearer action-token; " +
+		"http, Snooze 1h, https://notifier.example.com/api/v1/snooze?duration=1h, method=POST, clear=true, headers.Authorization=Bearer action-token"
+	if got := started.headers.Get("Actions"); got != expected {
+		t.Fatalf("unexpected Actions header: %s", got)
+	}
+
+	ended := <-captures
+	if got := ended.headers.Get("Actions"); got != "" {
+		t.Fatalf("expected no actions on shift ended, got: %s", got)
+	}
+}
+
+func TestNtfyNotifierSchedulesDelayedDelivery(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan ntfyRequestCapture, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		captures <- ntfyRequestCapture{path: r.URL.Path, body: string(payload), headers: r.Header.Clone()}
+		if r.Method == http.MethodPost {
+			io.WriteString(w, `{"id":"sCh3dul3d","event":"message"}`)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewNtfyNotifier(server.URL, "alerts", "", DefaultMessages(), NtfyOptions{})
+	notifier.client = server.Client()
+
+	shiftStart := time.Now().UTC().Add(10 * time.Hour).Truncate(time.Second)
+	deliverAt := shiftStart.Add(-2 * time.Hour)
+	id, err := notifier.ScheduleWithEvent(context.Background(), EventUpcomingShift, shiftStart, deliverAt)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "sCh3dul3d" {
+		t.Fatalf("expected the ID of the scheduled message, got %q", id)
+	}
+
+	select {
+	case capture := <-captures:
+		if got := capture.headers.Get("At"); got != fmt.Sprintf("%d", deliverAt.Unix()) {
+			t.Fatalf("unexpected At header: %s", got)
+		}
+		if capture.body != "⏰ Your PagerDuty on-call shift starts in 2 hours!" {
+			t.Fatalf("expected body relative to delivery time, got %q", capture.body)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive request")
+	}
+
+	if err := notifier.CancelScheduled(context.Background(), id); err != nil {
+		t.Fatalf("expected no error cancelling, got %v", err)
+	}
+	select {
+	case capture := <-captures:
+		if capture.path != "/alerts/sCh3dul3d" {
+			t.Fatalf("expected the scheduled message to be deleted, got %s", capture.path)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive cancel request")
+	}
+}
+
+func TestNtfyNotifierSubscribeDeliversMessages(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/control/json" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"id":"1","event":"open","topic":"control"}`+"\n")
+		_, _ = io.WriteString(w, `{"id":"2","event":"message","topic":"control","message":"mute 2h"}`+"\n")
+		_, _ = io.WriteString(w, `{"id":"3","event":"keepalive","topic":"control"}`+"\n")
+	}))
+	defer server.Close()
+
+	notifier := NewNtfyNotifier(server.URL, "alerts", "", DefaultMessages(), NtfyOptions{})
+	notifier.client = server.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	go notifier.Subscribe(ctx, "control", func(message string) {
+		received <- message
+	})
+
+	select {
+	case message := <-received:
+		if message != "mute 2h" {
+			t.Fatalf("unexpected message: %q", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive message")
+	}
+}
@@ -1,96 +1,222 @@
 package notifier
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+const (
+	pushoverAPIURL    = "https://api.pushover.net/1/messages.json"
+	pushoverGlanceURL = "https://api.pushover.net/1/glances.json"
+)
+
+// Emergency priority messages are retried until acknowledged or expired
+const (
+	pushoverEmergencyPriority = "2"
+	pushoverEmergencyRetry    = 60 * time.Second
+	pushoverEmergencyExpire   = time.Hour
 )
 
-const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+// PushoverTTLUntilShiftStart expires a notification when the shift starts
+const PushoverTTLUntilShiftStart = "until_start"
+
+// PushoverPriorities lists the priority values accepted by Pushover
+var PushoverPriorities = []string{"-2", "-1", "0", "1", "2"}
 
 // PushoverNotifier sends notifications via the Pushover API
 type PushoverNotifier struct {
 	appToken string
-	userKey  string
-	device   string
-	sound    string
+	userKeys []string
+	messages *Messages
+	opts     PushoverOptions
 	client   *http.Client
 	apiURL   string
+
+	glanceURL  string
+	glanceMu   sync.Mutex
+	lastGlance string
+}
+
+// PushoverOptions holds optional Pushover notifier settings
+type PushoverOptions struct {
+	// Device targets a single device instead of all of the user's devices
+	Device string
+	// Sound overrides the notification sound
+	Sound string
+	// Sounds overrides the sound for individual events, taking precedence over Sound
+	Sounds EventOverrides
+	// Priorities overrides the default priority for individual events
+	Priorities EventOverrides
+	// TTLs sets a time-to-live per event as a duration string, or PushoverTTLUntilShiftStart
+	// to expire upcoming shift reminders once the shift has begun
+	TTLs EventOverrides
+	// HTML enables HTML formatting with shift details in the message body
+	HTML bool
+	// URL is attached to notifications as a supplementary link (e.g. the PagerDuty schedule)
+	URL string
+	// Timeout bounds each request to the Pushover API; zero uses DefaultTimeout
+	Timeout time.Duration
 }
 
 // NewPushoverNotifier creates a new Pushover notifier
-func NewPushoverNotifier(appToken, userKey, device, sound string) *PushoverNotifier {
+// Each user or delivery group key in userKeys receives every notification
+func NewPushoverNotifier(appToken string, userKeys []string, messages *Messages, opts PushoverOptions) *PushoverNotifier {
 	return &PushoverNotifier{
 		appToken: appToken,
-		userKey:  userKey,
-		device:   device,
-		sound:    sound,
-		client:   &http.Client{Timeout: 30 * time.Second},
+		userKeys: userKeys,
+		messages: messages,
+		opts:     opts,
+		client:   &http.Client{Timeout: timeoutOrDefault(opts.Timeout), Transport: httpclient.WithUserAgent(nil)},
 		apiURL:   pushoverAPIURL,
+
+		glanceURL: pushoverGlanceURL,
 	}
 }
 
 // Notify sends a simple notification message
-func (p *PushoverNotifier) Notify(message string) error {
-	return p.NotifyWithEvent(EventShiftStarted, time.Now().UTC())
+func (p *PushoverNotifier) Notify(ctx context.Context, message string) error {
+	return p.NotifyWithEvent(ctx, EventShiftStarted, time.Now().UTC())
 }
 
 // NotifyWithEvent sends a notification with event-specific formatting
-func (p *PushoverNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTime time.Time) error {
-	var message, title string
-	priority := "0"
+func (p *PushoverNotifier) NotifyWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime time.Time) error {
+	return p.NotifyShift(ctx, event, Shift{Start: shiftStartTime})
+}
 
-	switch event {
-	case EventShiftStarted:
-		message = "🚨 Your PagerDuty on-call shift has started!"
-		title = "PagerDuty On-Call Shift Started"
-		priority = "1"
-	case EventUpcomingShift:
-		duration := time.Until(shiftStartTime)
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-
-		if hours > 0 {
-			if minutes > 0 {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours and %d minutes!", hours, minutes)
-			} else {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours!", hours)
-			}
-		} else if minutes > 0 {
-			message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d minutes!", minutes)
-		} else {
-			message = "⏰ Your PagerDuty on-call shift starts soon!"
+// NotifyShift sends a notification including the shift window where the message uses it
+func (p *PushoverNotifier) NotifyShift(ctx context.Context, event NotificationEvent, shift Shift) error {
+	shiftStartTime := shift.Start
+	message := p.messages.ShiftBodyAt(event, shift, time.Now())
+	if p.opts.HTML {
+		html, err := p.messages.ShiftHTML(event, shift)
+		if err != nil {
+			return err
 		}
-		title = "PagerDuty On-Call Shift Upcoming"
-		priority = "0"
-	case EventShiftEnded:
-		message = "✅ Your PagerDuty on-call shift has ended. Enjoy the downtime!"
-		title = "PagerDuty On-Call Shift Ended"
-		priority = "0"
-	default:
-		message = "Unknown notification event"
-		title = "PagerDuty Notification"
-		priority = "0"
+		message = html
 	}
+	title := p.messages.Title(event)
+	priority := "0"
+	if event == EventShiftStarted || event == EventCoverageStarted || event == EventNotifierDegraded {
+		priority = "1"
+	}
+	if event == EventDailySummary {
+		// A routine digest should not make a sound
+		priority = "-1"
+	}
+	priority = p.opts.Priorities.Get(event, priority)
 
 	values := url.Values{}
 	values.Set("token", p.appToken)
-	values.Set("user", p.userKey)
 	values.Set("message", message)
 	values.Set("title", title)
 	values.Set("priority", priority)
-	values.Set("timestamp", fmt.Sprintf("%d", shiftStartTime.Unix()))
+	values.Set("timestamp", fmt.Sprintf("%d", shift.eventTime(event).Unix()))
+
+	// Emergency priority requires retry and expire parameters
+	if priority == pushoverEmergencyPriority {
+		values.Set("retry", fmt.Sprintf("%d", int(pushoverEmergencyRetry.Seconds())))
+		values.Set("expire", fmt.Sprintf("%d", int(pushoverEmergencyExpire.Seconds())))
+	}
 
-	if p.device != "" {
-		values.Set("device", p.device)
+	if ttl := p.ttl(event, shiftStartTime); ttl > 0 && priority != pushoverEmergencyPriority {
+		values.Set("ttl", fmt.Sprintf("%d", int(ttl.Seconds())))
+	}
+	if p.opts.HTML {
+		values.Set("html", "1")
+	}
+	if p.opts.URL != "" {
+		values.Set("url", p.opts.URL)
+		values.Set("url_title", p.messages.ScheduleLinkTitle())
+	}
+	if p.opts.Device != "" {
+		values.Set("device", p.opts.Device)
 	}
-	if p.sound != "" {
-		values.Set("sound", p.sound)
+	if sound := p.opts.Sounds.Get(event, p.opts.Sound); sound != "" {
+		values.Set("sound", sound)
 	}
 
-	resp, err := p.client.PostForm(p.apiURL, values)
+	// Send to each recipient separately so one invalid key doesn't block the others
+	var errs []error
+	for _, userKey := range p.userKeys {
+		values.Set("user", userKey)
+		if err := p.send(ctx, values); err != nil {
+			errs = append(errs, fmt.Errorf("recipient %s: %w", maskKey(userKey), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublishStatus updates the Pushover Glances widget with the current on-call status
+// The update is skipped if the displayed status has not changed since the last call
+func (p *PushoverNotifier) PublishStatus(ctx context.Context, onCall bool, nextShiftStart *time.Time) error {
+	title, text := p.messages.Status(onCall, nextShiftStart)
+
+	p.glanceMu.Lock()
+	defer p.glanceMu.Unlock()
+
+	glance := title + "\n" + text
+	if glance == p.lastGlance {
+		return nil
+	}
+
+	values := url.Values{}
+	values.Set("token", p.appToken)
+	values.Set("title", title)
+	values.Set("text", text)
+	if p.opts.Device != "" {
+		values.Set("device", p.opts.Device)
+	}
+
+	var errs []error
+	for _, userKey := range p.userKeys {
+		values.Set("user", userKey)
+		resp, err := p.postForm(ctx, p.glanceURL, values)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to update pushover glance: %w", err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf("pushover glances returned non-2xx status: %d", resp.StatusCode))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	p.lastGlance = glance
+	return nil
+}
+
+// ttl returns the configured time-to-live for an event, or 0 if none applies
+func (p *PushoverNotifier) ttl(event NotificationEvent, shiftStartTime time.Time) time.Duration {
+	raw := p.opts.TTLs.Get(event, "")
+	if raw == "" {
+		return 0
+	}
+	if raw == PushoverTTLUntilShiftStart {
+		return time.Until(shiftStartTime).Round(time.Second)
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0
+	}
+	return ttl
+}
+
+// send posts a single message to the Pushover API
+func (p *PushoverNotifier) send(ctx context.Context, values url.Values) error {
+	resp, err := p.postForm(ctx, p.apiURL, values)
 	if err != nil {
 		return fmt.Errorf("failed to send pushover notification: %w", err)
 	}
@@ -103,3 +229,21 @@ func (p *PushoverNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTi
 
 	return nil
 }
+
+// postForm posts values as a form to target, aborting when ctx is cancelled
+func (p *PushoverNotifier) postForm(ctx context.Context, target string, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return p.client.Do(req)
+}
+
+// maskKey shortens a user key for logging
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return key
+	}
+	return key[:4] + "…"
+}
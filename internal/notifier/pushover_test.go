@@ -0,0 +1,198 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPushoverTestServer returns a server that captures submitted Pushover forms
+func newPushoverTestServer(t *testing.T, captures chan url.Values) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		captures <- r.PostForm
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestPushoverNotifierSendsShiftStartedEvent(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 1)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	opts := PushoverOptions{Sound: "siren", Sounds: EventOverrides{EventUpcomingShift: "magic"}}
+	notifier := NewPushoverNotifier("app-token", []string{"user-key"}, DefaultMessages(), opts)
+	notifier.client = server.Client()
+	notifier.apiURL = server.URL
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	form := <-captures
+	if got := form.Get("token"); got != "app-token" {
+		t.Fatalf("unexpected token: %s", got)
+	}
+	if got := form.Get("user"); got != "user-key" {
+		t.Fatalf("unexpected user: %s", got)
+	}
+	if got := form.Get("priority"); got != "1" {
+		t.Fatalf("unexpected priority: %s", got)
+	}
+	if got := form.Get("sound"); got != "siren" {
+		t.Fatalf("unexpected sound: %s", got)
+	}
+	if form.Has("html") || form.Has("url") {
+		t.Fatalf("expected no html or url fields by default")
+	}
+}
+
+func TestPushoverNotifierSendsHTMLWithScheduleLink(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 1)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	opts := PushoverOptions{HTML: true, URL: "https://example.pagerduty.com/schedules/P123"}
+	notifier := NewPushoverNotifier("app-token", []string{"user-key"}, DefaultMessages(), opts)
+	notifier.client = server.Client()
+	notifier.apiURL = server.URL
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, time.Now().UTC().Add(2*time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	form := <-captures
+	if got := form.Get("sound"); got != "" {
+		t.Fatalf("expected no sound without overrides, got: %s", got)
+	}
+	if got := form.Get("html"); got != "1" {
+		t.Fatalf("unexpected html flag: %s", got)
+	}
+	if !strings.Contains(form.Get("message"), "<b>Starts:</b>") {
+		t.Fatalf("expected bold shift start in message, got %q", form.Get("message"))
+	}
+	if got := form.Get("url"); got != opts.URL {
+		t.Fatalf("unexpected url: %s", got)
+	}
+	if got := form.Get("url_title"); got != "Open PagerDuty schedule" {
+		t.Fatalf("unexpected url_title: %s", got)
+	}
+}
+
+func TestPushoverNotifierAppliesPerEventSound(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 1)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	opts := PushoverOptions{Sound: "siren", Sounds: EventOverrides{EventUpcomingShift: "magic"}}
+	notifier := NewPushoverNotifier("app-token", []string{"user-key"}, DefaultMessages(), opts)
+	notifier.client = server.Client()
+	notifier.apiURL = server.URL
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, time.Now().UTC().Add(time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := (<-captures).Get("sound"); got != "magic" {
+		t.Fatalf("unexpected sound: %s", got)
+	}
+}
+
+func TestPushoverNotifierSendsToEveryUserKey(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 2)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	notifier := NewPushoverNotifier("app-token", []string{"primary-key", "shadow-key"}, DefaultMessages(), PushoverOptions{})
+	notifier.client = server.Client()
+	notifier.apiURL = server.URL
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	first, second := <-captures, <-captures
+	if first.Get("user") != "primary-key" || second.Get("user") != "shadow-key" {
+		t.Fatalf("unexpected recipients: %s, %s", first.Get("user"), second.Get("user"))
+	}
+}
+
+func TestPushoverNotifierSetsTTLUntilShiftStart(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 2)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	opts := PushoverOptions{TTLs: EventOverrides{
+		EventUpcomingShift: PushoverTTLUntilShiftStart,
+		EventShiftEnded:    "12h",
+	}}
+	notifier := NewPushoverNotifier("app-token", []string{"user-key"}, DefaultMessages(), opts)
+	notifier.client = server.Client()
+	notifier.apiURL = server.URL
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, time.Now().UTC().Add(2*time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := (<-captures).Get("ttl"); got != "7200" {
+		t.Fatalf("unexpected ttl for upcoming shift: %s", got)
+	}
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftEnded, time.Now().UTC()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := (<-captures).Get("ttl"); got != "43200" {
+		t.Fatalf("unexpected ttl for shift ended: %s", got)
+	}
+}
+
+func TestPushoverNotifierPublishesGlanceOnChange(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan url.Values, 3)
+	server := newPushoverTestServer(t, captures)
+	defer server.Close()
+
+	notifier := NewPushoverNotifier("app-token", []string{"user-key"}, DefaultMessages(), PushoverOptions{})
+	notifier.client = server.Client()
+	notifier.glanceURL = server.URL
+
+	nextShift := time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+	if err := notifier.PublishStatus(context.Background(), false, &nextShift); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	form := <-captures
+	if form.Get("title") != "Off call" || form.Get("text") != "Next: Mon 04 Mar 09:00 UTC" {
+		t.Fatalf("unexpected glance: %q / %q", form.Get("title"), form.Get("text"))
+	}
+
+	// Unchanged status is not sent again
+	if err := notifier.PublishStatus(context.Background(), false, &nextShift); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := notifier.PublishStatus(context.Background(), true, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := (<-captures).Get("title"); got != "On call" {
+		t.Fatalf("unexpected glance title: %s", got)
+	}
+	if len(captures) != 0 {
+		t.Fatalf("expected unchanged status to be skipped")
+	}
+}
@@ -2,77 +2,151 @@ package notifier
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"text/template"
 	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+// WebhookFormat selects the shape of the webhook payload
+type WebhookFormat string
+
+const (
+	WebhookFormatDefault WebhookFormat = "default"
+	WebhookFormatSlack   WebhookFormat = "slack"
+	WebhookFormatDiscord WebhookFormat = "discord"
+	// WebhookFormatCloudEvents wraps the default payload in a CloudEvents 1.0 structured-mode envelope
+	WebhookFormatCloudEvents WebhookFormat = "cloudevents"
+)
+
+// WebhookEncoding selects how the webhook request body is encoded
+type WebhookEncoding string
+
+const (
+	WebhookEncodingJSON WebhookEncoding = "json"
+	WebhookEncodingForm WebhookEncoding = "form"
 )
 
+// WebhookMethods lists the supported webhook HTTP methods
+var WebhookMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+
+// WebhookEncodings lists the supported webhook body encodings
+var WebhookEncodings = []string{string(WebhookEncodingJSON), string(WebhookEncodingForm)}
+
+// WebhookSchemaVersion is the version of the default webhook payload, bumped on breaking changes
+const WebhookSchemaVersion = 2
+
+// WebhookFormats lists the supported webhook payload formats
+var WebhookFormats = []string{string(WebhookFormatDefault), string(WebhookFormatSlack), string(WebhookFormatDiscord), string(WebhookFormatCloudEvents)}
+
+// discordColors maps events to Discord embed sidebar colours
+var discordColors = map[NotificationEvent]int{
+	EventShiftStarted:     0xE01E5A, // red
+	EventUpcomingShift:    0xECB22E, // amber
+	EventShiftEnding:      0xE8912D, // orange
+	EventShiftEnded:       0x2EB67D, // green
+	EventShiftOverridden:  0x36C5F0, // blue
+	EventCoverageStarted:  0xE01E5A, // red
+	EventCoverageGap:      0xF2711C, // orange
+	EventUnexpectedOnCall: 0x9B59B6, // purple
+	EventScheduleChanged:  0x36C5F0, // blue
+	EventNotifierDegraded: 0x95A5A6, // grey
+	EventDailySummary:     0x36C5F0, // blue
+}
+
 // WebhookNotifier sends notifications via HTTP webhook
 type WebhookNotifier struct {
 	webhookURL string
+	messages   *Messages
+	opts       WebhookOptions
 	client     *http.Client
 }
 
+// WebhookOptions holds optional webhook notifier settings
+type WebhookOptions struct {
+	// Format selects the payload shape; defaults to WebhookFormatDefault
+	Format WebhookFormat
+	// Method is the HTTP method used for requests; defaults to POST
+	Method string
+	// Encoding selects a JSON or form-encoded body; form encoding flattens the default payload
+	Encoding WebhookEncoding
+	// URLs overrides the destination per event; events without an entry use the default URL
+	URLs EventOverrides
+	// TLSConfig configures client certificates and trusted CAs for the webhook endpoint
+	TLSConfig *tls.Config
+	// Template replaces the built-in payload formats with a custom body; see ParseWebhookTemplate
+	Template *template.Template
+	// ScheduleID, ScheduleName, UserID and UserName are included in the default payload and template data
+	ScheduleID   string
+	ScheduleName string
+	UserID       string
+	UserName     string
+	// Timeout bounds each webhook request; zero uses DefaultTimeout
+	Timeout time.Duration
+}
+
 // NewWebhookNotifier creates a new webhook notifier
-func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+func NewWebhookNotifier(webhookURL string, messages *Messages, opts WebhookOptions) *WebhookNotifier {
+	if opts.Format == "" {
+		opts.Format = WebhookFormatDefault
+	}
+	if opts.Method == "" {
+		opts.Method = http.MethodPost
+	}
+	if opts.Encoding == "" {
+		opts.Encoding = WebhookEncodingJSON
+	}
+	client := &http.Client{Timeout: timeoutOrDefault(opts.Timeout), Transport: httpclient.WithUserAgent(nil)}
+	if opts.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = opts.TLSConfig
+		client.Transport = httpclient.WithUserAgent(transport)
+	}
 	return &WebhookNotifier{
 		webhookURL: webhookURL,
-		client:     &http.Client{Timeout: 30 * time.Second},
+		messages:   messages,
+		opts:       opts,
+		client:     client,
 	}
 }
 
 // Notify sends a simple notification message
-func (w *WebhookNotifier) Notify(message string) error {
-	return w.NotifyWithEvent(EventShiftStarted, time.Now().UTC())
+func (w *WebhookNotifier) Notify(ctx context.Context, message string) error {
+	return w.NotifyWithEvent(ctx, EventShiftStarted, time.Now().UTC())
 }
 
 // NotifyWithEvent sends a notification with event-specific formatting
-func (w *WebhookNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTime time.Time) error {
-	var message string
-	var eventType string
+func (w *WebhookNotifier) NotifyWithEvent(ctx context.Context, event NotificationEvent, shiftStartTime time.Time) error {
+	return w.NotifyShift(ctx, event, Shift{Start: shiftStartTime})
+}
 
-	switch event {
-	case EventShiftStarted:
-		message = "🚨 Your PagerDuty on-call shift has started!"
-		eventType = "oncall_shift_started"
-	case EventUpcomingShift:
-		duration := time.Until(shiftStartTime)
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-
-		if hours > 0 {
-			if minutes > 0 {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours and %d minutes!", hours, minutes)
-			} else {
-				message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d hours!", hours)
-			}
-		} else if minutes > 0 {
-			message = fmt.Sprintf("⏰ Your PagerDuty on-call shift starts in %d minutes!", minutes)
-		} else {
-			message = "⏰ Your PagerDuty on-call shift starts soon!"
-		}
-		eventType = "oncall_shift_upcoming"
-	case EventShiftEnded:
-		message = "✅ Your PagerDuty on-call shift has ended. Enjoy the downtime!"
-		eventType = "oncall_shift_ended"
-	default:
-		message = "Unknown notification event"
-		eventType = "unknown"
+// NotifyShift sends a notification including the full shift details
+func (w *WebhookNotifier) NotifyShift(ctx context.Context, event NotificationEvent, shift Shift) error {
+	webhookURL := w.opts.URLs.Get(event, w.webhookURL)
+	if webhookURL == "" {
+		slog.Debug("No webhook URL configured, skipping", "event", event)
+		return nil
 	}
 
-	payload := map[string]interface{}{
-		"message":   message,
-		"timestamp": shiftStartTime.Format(time.RFC3339),
-		"event":     eventType,
+	data, err := w.body(event, shift)
+	if err != nil {
+		return err
 	}
 
-	data, err := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, w.opts.Method, webhookURL, bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		return fmt.Errorf("failed to create webhook request: %w", err)
 	}
+	req.Header.Set("Content-Type", w.contentType())
 
-	resp, err := w.client.Post(w.webhookURL, "application/json", bytes.NewBuffer(data))
+	resp, err := w.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send webhook: %w", err)
 	}
@@ -84,3 +158,246 @@ func (w *WebhookNotifier) NotifyWithEvent(event NotificationEvent, shiftStartTim
 
 	return nil
 }
+
+// body renders the request body using the custom template or the configured format
+func (w *WebhookNotifier) body(event NotificationEvent, shift Shift) ([]byte, error) {
+	if w.opts.Template != nil {
+		return w.renderTemplate(event, shift)
+	}
+
+	if w.opts.Encoding == WebhookEncodingForm {
+		return []byte(formValues("", w.defaultPayload(event, shift)).Encode()), nil
+	}
+
+	var payload interface{}
+	switch w.opts.Format {
+	case WebhookFormatSlack:
+		payload = w.slackPayload(event, shift)
+	case WebhookFormatDiscord:
+		payload = w.discordPayload(event, shift)
+	case WebhookFormatCloudEvents:
+		payload = w.cloudEventPayload(event, shift)
+	default:
+		payload = w.defaultPayload(event, shift)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return data, nil
+}
+
+// contentType returns the Content-Type header for the configured encoding and format
+func (w *WebhookNotifier) contentType() string {
+	switch {
+	case w.opts.Encoding == WebhookEncodingForm:
+		return "application/x-www-form-urlencoded"
+	case w.opts.Template == nil && w.opts.Format == WebhookFormatCloudEvents:
+		return cloudEventsContentType
+	default:
+		return "application/json"
+	}
+}
+
+// formValues flattens a payload into form fields, joining nested keys with underscores
+// (e.g. schedule.id becomes schedule_id)
+func formValues(prefix string, payload map[string]interface{}) url.Values {
+	values := url.Values{}
+	for key, value := range payload {
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			for nestedKey, nestedValues := range formValues(key, nested) {
+				values[nestedKey] = nestedValues
+			}
+			continue
+		}
+		values.Set(key, fmt.Sprint(value))
+	}
+	return values
+}
+
+// webhookEventType returns the event name used in webhook payloads
+func webhookEventType(event NotificationEvent) string {
+	switch event {
+	case EventShiftStarted:
+		return "oncall_shift_started"
+	case EventUpcomingShift:
+		return "oncall_shift_upcoming"
+	case EventShiftEnding:
+		return "oncall_shift_ending"
+	case EventShiftEnded:
+		return "oncall_shift_ended"
+	case EventShiftOverridden:
+		return "oncall_shift_overridden"
+	case EventCoverageStarted:
+		return "oncall_coverage_started"
+	case EventCoverageGap:
+		return "oncall_coverage_gap"
+	case EventUnexpectedOnCall:
+		return "oncall_unexpected_responder"
+	case EventScheduleChanged:
+		return "oncall_schedule_changed"
+	case EventNotifierDegraded:
+		return "notifier_degraded"
+	case EventDailySummary:
+		return "oncall_daily_summary"
+	default:
+		return "unknown"
+	}
+}
+
+// referencesShiftStart reports whether messages for the event are about the start of a shift
+func referencesShiftStart(event NotificationEvent) bool {
+	switch event {
+	case EventShiftStarted, EventUpcomingShift, EventShiftOverridden, EventCoverageStarted, EventCoverageGap, EventUnexpectedOnCall, EventScheduleChanged:
+		return true
+	default:
+		return false
+	}
+}
+
+// defaultPayload builds the notifier's own JSON payload
+func (w *WebhookNotifier) defaultPayload(event NotificationEvent, shift Shift) map[string]interface{} {
+	eventType := webhookEventType(event)
+	timestamp := shift.eventTime(event)
+
+	shiftDetails := map[string]interface{}{}
+	if !shift.Start.IsZero() {
+		shiftDetails["start"] = shift.Start.UTC().Format(time.RFC3339)
+	}
+	if !shift.End.IsZero() {
+		shiftDetails["end"] = shift.End.UTC().Format(time.RFC3339)
+	}
+	if duration := shift.Duration(); duration > 0 {
+		shiftDetails["duration_seconds"] = int64(duration.Seconds())
+	}
+
+	payload := map[string]interface{}{
+		"schema_version": WebhookSchemaVersion,
+		"message":        w.messages.ShiftBodyAt(event, shift, time.Now()),
+		"timestamp":      timestamp.Format(time.RFC3339),
+		"event":          eventType,
+		"schedule": map[string]interface{}{
+			"id":   w.opts.ScheduleID,
+			"name": w.opts.ScheduleName,
+		},
+		"user": map[string]interface{}{
+			"id":   w.opts.UserID,
+			"name": w.opts.UserName,
+		},
+		"shift": shiftDetails,
+	}
+	if shift.Responder != "" {
+		payload["responder"] = map[string]interface{}{"name": shift.Responder}
+	}
+	if handoff := handoffPayload(shift); len(handoff) > 0 {
+		payload["handoff"] = handoff
+	}
+	if incidents := shift.OpenIncidents; incidents != nil {
+		openIncidents := map[string]interface{}{"count": incidents.Count}
+		if !incidents.Oldest.IsZero() {
+			openIncidents["oldest_created_at"] = incidents.Oldest.UTC().Format(time.RFC3339)
+		}
+		payload["open_incidents"] = openIncidents
+	}
+	if len(shift.Changes) > 0 {
+		payload["changes"] = changesPayload(shift.Changes)
+	}
+	return payload
+}
+
+// changesPayload describes the edits to the user's upcoming shifts
+func changesPayload(changes []ShiftChange) []map[string]interface{} {
+	entries := make([]map[string]interface{}, 0, len(changes))
+	for _, change := range changes {
+		entry := map[string]interface{}{
+			"kind":  string(change.Kind),
+			"start": change.Start.UTC().Format(time.RFC3339),
+			"end":   change.End.UTC().Format(time.RFC3339),
+		}
+		if change.Kind == ShiftMoved {
+			entry["previous_start"] = change.PreviousStart.UTC().Format(time.RFC3339)
+			entry["previous_end"] = change.PreviousEnd.UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// handoffPayload describes who the shift is handed over from and to
+func handoffPayload(shift Shift) map[string]interface{} {
+	handoff := map[string]interface{}{}
+	for key, h := range map[string]*Handoff{"previous": shift.Previous, "next": shift.Next} {
+		if h == nil {
+			continue
+		}
+		entry := map[string]interface{}{"name": h.Name}
+		if !h.At.IsZero() {
+			entry["at"] = h.At.UTC().Format(time.RFC3339)
+		}
+		handoff[key] = entry
+	}
+	return handoff
+}
+
+// slackPayload builds a payload for Slack incoming webhooks, with a plain text fallback and Block Kit blocks
+func (w *WebhookNotifier) slackPayload(event NotificationEvent, shift Shift) map[string]interface{} {
+	shiftStartTime := shift.Start
+	message := w.messages.ShiftBodyAt(event, shift, time.Now())
+
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": w.messages.Title(event)},
+		},
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": message},
+		},
+	}
+
+	// Let Slack render the shift start in each reader's own timezone
+	if referencesShiftStart(event) {
+		fallback := w.messages.localTime(shiftStartTime).Format(detailsTimeLayout)
+		blocks = append(blocks, map[string]interface{}{
+			"type": "context",
+			"elements": []map[string]interface{}{
+				{
+					"type": "mrkdwn",
+					"text": fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", shiftStartTime.Unix(), fallback),
+				},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"text":   message,
+		"blocks": blocks,
+	}
+}
+
+// discordPayload builds a payload for Discord webhooks, with the message as content and a single embed
+func (w *WebhookNotifier) discordPayload(event NotificationEvent, shift Shift) map[string]interface{} {
+	shiftStartTime := shift.Start
+	message := w.messages.ShiftBodyAt(event, shift, time.Now())
+
+	embed := map[string]interface{}{
+		"title":       w.messages.Title(event),
+		"description": message,
+	}
+	if color, ok := discordColors[event]; ok {
+		embed["color"] = color
+	}
+	// Discord renders the embed timestamp in each reader's own timezone
+	if referencesShiftStart(event) {
+		embed["timestamp"] = shiftStartTime.UTC().Format(time.RFC3339)
+	}
+
+	return map[string]interface{}{
+		"content": message,
+		"embeds":  []map[string]interface{}{embed},
+	}
+}
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsTypePrefix is prepended to the event name to form the CloudEvents type
+	cloudEventsTypePrefix = "org.a7d.oncall."
+	// cloudEventsSource identifies this notifier as the event producer
+	cloudEventsSource = "pagerduty-oncall-notifier"
+)
+
+// cloudEventPayload wraps the default payload in a CloudEvents 1.0 structured-mode envelope
+func (w *WebhookNotifier) cloudEventPayload(event NotificationEvent, shift Shift) map[string]interface{} {
+	source := cloudEventsSource
+	if w.opts.ScheduleID != "" {
+		source += "/schedules/" + w.opts.ScheduleID
+	}
+
+	envelope := map[string]interface{}{
+		"specversion":     cloudEventsSpecVersion,
+		"id":              newCloudEventID(),
+		"source":          source,
+		"type":            cloudEventsTypePrefix + string(event),
+		"time":            time.Now().UTC().Format(time.RFC3339),
+		"datacontenttype": "application/json",
+		"data":            w.defaultPayload(event, shift),
+	}
+	if w.opts.UserID != "" {
+		envelope["subject"] = w.opts.UserID
+	}
+	return envelope
+}
+
+// newCloudEventID returns a random identifier, unique per delivered event
+func newCloudEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
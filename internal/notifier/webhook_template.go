@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// WebhookTemplateData is the data available to custom webhook templates
+type WebhookTemplateData struct {
+	SchemaVersion int
+	Event         string // e.g. shift_started
+	EventType     string // e.g. oncall_shift_started, as in the default payload
+	Title         string
+	Message       string
+	Timestamp     time.Time
+	Schedule      WebhookEntity
+	User          WebhookEntity
+	Shift         Shift
+}
+
+// WebhookEntity identifies a PagerDuty object in webhook template data
+type WebhookEntity struct {
+	ID   string
+	Name string
+}
+
+// webhookTemplateFuncs are the helper functions available to custom webhook templates
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, quoting and escaping strings
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// rfc3339 formats a time in UTC, or returns an empty string for the zero time
+	"rfc3339": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+	// unix returns a time as seconds since the epoch
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+	// seconds returns a duration in whole seconds
+	"seconds": func(d time.Duration) int64 {
+		return int64(d.Seconds())
+	},
+}
+
+// ParseWebhookTemplate parses a custom webhook body template
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate renders the custom template, checking the result is valid JSON
+func (w *WebhookNotifier) renderTemplate(event NotificationEvent, shift Shift) ([]byte, error) {
+	data := WebhookTemplateData{
+		SchemaVersion: WebhookSchemaVersion,
+		Event:         string(event),
+		EventType:     webhookEventType(event),
+		Title:         w.messages.Title(event),
+		Message:       w.messages.ShiftBodyAt(event, shift, time.Now()),
+		Timestamp:     shift.eventTime(event),
+		Schedule:      WebhookEntity{ID: w.opts.ScheduleID, Name: w.opts.ScheduleName},
+		User:          WebhookEntity{ID: w.opts.UserID, Name: w.opts.UserName},
+		Shift:         shift,
+	}
+
+	var buf bytes.Buffer
+	if err := w.opts.Template.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not produce valid JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,382 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newWebhookTestServer(t *testing.T, captures chan map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		captures <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebhookNotifierSendsDefaultPayload(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{})
+	shiftStart := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, shiftStart); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if payload["event"] != "oncall_shift_started" {
+		t.Fatalf("unexpected event: %v", payload["event"])
+	}
+	if payload["timestamp"] != "2024-01-15T10:30:00Z" {
+		t.Fatalf("unexpected timestamp: %v", payload["timestamp"])
+	}
+	if payload["schema_version"] != float64(WebhookSchemaVersion) {
+		t.Fatalf("unexpected schema version: %v", payload["schema_version"])
+	}
+	if _, ok := payload["blocks"]; ok {
+		t.Fatalf("default payload should not contain blocks")
+	}
+}
+
+func TestWebhookNotifierIncludesShiftMetadata(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	opts := WebhookOptions{
+		ScheduleID:   "PSCHED1",
+		ScheduleName: "Primary",
+		UserID:       "PUSER1",
+		UserName:     "Alex Example",
+	}
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), opts)
+	shift := Shift{
+		Start: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC),
+	}
+	if err := notifier.NotifyShift(context.Background(), EventShiftEnded, shift); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if payload["timestamp"] != "2024-01-15T17:00:00Z" {
+		t.Fatalf("expected shift end as timestamp, got %v", payload["timestamp"])
+	}
+	schedule := payload["schedule"].(map[string]interface{})
+	if schedule["id"] != "PSCHED1" || schedule["name"] != "Primary" {
+		t.Fatalf("unexpected schedule: %v", schedule)
+	}
+	user := payload["user"].(map[string]interface{})
+	if user["id"] != "PUSER1" || user["name"] != "Alex Example" {
+		t.Fatalf("unexpected user: %v", user)
+	}
+	shiftDetails := payload["shift"].(map[string]interface{})
+	if shiftDetails["start"] != "2024-01-15T09:00:00Z" || shiftDetails["end"] != "2024-01-15T17:00:00Z" {
+		t.Fatalf("unexpected shift window: %v", shiftDetails)
+	}
+	if shiftDetails["duration_seconds"] != float64(8*60*60) {
+		t.Fatalf("unexpected shift duration: %v", shiftDetails["duration_seconds"])
+	}
+}
+
+func TestWebhookNotifierSendsSlackPayload(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{Format: WebhookFormatSlack})
+	shiftStart := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, shiftStart); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if payload["text"] != "🚨 Your PagerDuty on-call shift has started!" {
+		t.Fatalf("unexpected text: %v", payload["text"])
+	}
+	blocks, ok := payload["blocks"].([]interface{})
+	if !ok || len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %v", payload["blocks"])
+	}
+	context := blocks[2].(map[string]interface{})
+	if context["type"] != "context" {
+		t.Fatalf("expected context block, got %v", context["type"])
+	}
+	element := context["elements"].([]interface{})[0].(map[string]interface{})
+	if !strings.HasPrefix(element["text"].(string), "<!date^1705314600^") {
+		t.Fatalf("unexpected date element: %v", element["text"])
+	}
+}
+
+func TestWebhookNotifierSlackShiftEndedOmitsStartTime(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{Format: WebhookFormatSlack})
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftEnded, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if blocks := payload["blocks"].([]interface{}); len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+}
+
+func TestWebhookNotifierSendsDiscordPayload(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{Format: WebhookFormatDiscord})
+	shiftStart := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, shiftStart); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if payload["content"] == "" {
+		t.Fatalf("expected content to be set")
+	}
+	embeds, ok := payload["embeds"].([]interface{})
+	if !ok || len(embeds) != 1 {
+		t.Fatalf("expected a single embed, got %v", payload["embeds"])
+	}
+	embed := embeds[0].(map[string]interface{})
+	if embed["title"] != "PagerDuty On-Call Shift Upcoming" {
+		t.Fatalf("unexpected embed title: %v", embed["title"])
+	}
+	if embed["timestamp"] != "2024-01-15T10:30:00Z" {
+		t.Fatalf("unexpected embed timestamp: %v", embed["timestamp"])
+	}
+	if embed["color"] != float64(0xECB22E) {
+		t.Fatalf("unexpected embed color: %v", embed["color"])
+	}
+}
+
+func TestWebhookNotifierRendersCustomTemplate(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	tmpl, err := ParseWebhookTemplate(`{"summary": {{ json .Message }}, "kind": "{{ .Event }}", "starts": "{{ rfc3339 .Shift.Start }}", "who": {{ json .User.Name }}}`)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{Template: tmpl, UserName: `Alex "AJ" Example`})
+	shiftStart := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := notifier.NotifyWithEvent(context.Background(), EventUpcomingShift, shiftStart); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	payload := <-captures
+	if payload["kind"] != "upcoming_shift" {
+		t.Fatalf("unexpected kind: %v", payload["kind"])
+	}
+	if payload["starts"] != "2024-01-15T10:30:00Z" {
+		t.Fatalf("unexpected start: %v", payload["starts"])
+	}
+	if payload["who"] != `Alex "AJ" Example` {
+		t.Fatalf("unexpected user name: %v", payload["who"])
+	}
+	if _, ok := payload["summary"].(string); !ok {
+		t.Fatalf("expected summary to be a string, got %v", payload["summary"])
+	}
+}
+
+func TestWebhookNotifierRejectsInvalidTemplateOutput(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := ParseWebhookTemplate(`{"summary": {{ .Message }}}`)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	notifier := NewWebhookNotifier("http://127.0.0.1:0", DefaultMessages(), WebhookOptions{Template: tmpl})
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now()); err == nil {
+		t.Fatalf("expected an error for invalid JSON output")
+	}
+}
+
+func TestWebhookNotifierGivesUpOnSlowEndpoints(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{Timeout: 50 * time.Millisecond})
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now()); err == nil {
+		t.Fatalf("expected an error when the endpoint does not respond within the timeout")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier = NewWebhookNotifier(server.URL, DefaultMessages(), WebhookOptions{})
+	if err := notifier.NotifyWithEvent(ctx, EventShiftStarted, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled send to fail with context.Canceled, got %v", err)
+	}
+}
+
+func TestWebhookNotifierUsesPerEventURLs(t *testing.T) {
+	t.Parallel()
+
+	defaultCaptures := make(chan map[string]interface{}, 1)
+	defaultServer := newWebhookTestServer(t, defaultCaptures)
+	endedCaptures := make(chan map[string]interface{}, 1)
+	endedServer := newWebhookTestServer(t, endedCaptures)
+
+	opts := WebhookOptions{URLs: EventOverrides{EventShiftEnded: endedServer.URL}}
+	notifier := NewWebhookNotifier(defaultServer.URL, DefaultMessages(), opts)
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftEnded, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if payload := <-endedCaptures; payload["event"] != "oncall_shift_ended" {
+		t.Fatalf("unexpected event on override URL: %v", payload["event"])
+	}
+
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if payload := <-defaultCaptures; payload["event"] != "oncall_shift_started" {
+		t.Fatalf("unexpected event on default URL: %v", payload["event"])
+	}
+}
+
+func TestWebhookNotifierSkipsEventsWithoutURL(t *testing.T) {
+	t.Parallel()
+
+	captures := make(chan map[string]interface{}, 1)
+	server := newWebhookTestServer(t, captures)
+
+	notifier := NewWebhookNotifier("", DefaultMessages(), WebhookOptions{URLs: EventOverrides{EventShiftStarted: server.URL}})
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftEnded, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case payload := <-captures:
+		t.Fatalf("expected no request, got %v", payload)
+	default:
+	}
+}
+
+func TestWebhookNotifierSendsFormEncodedBodyWithMethod(t *testing.T) {
+	t.Parallel()
+
+	type capture struct {
+		method      string
+		contentType string
+		form        url.Values
+	}
+	captures := make(chan capture, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		captures <- capture{method: r.Method, contentType: r.Header.Get("Content-Type"), form: r.PostForm}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opts := WebhookOptions{Method: http.MethodPut, Encoding: WebhookEncodingForm, ScheduleID: "PSCHED1"}
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), opts)
+	shiftStart := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, shiftStart); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := <-captures
+	if got.method != http.MethodPut {
+		t.Fatalf("unexpected method: %s", got.method)
+	}
+	if got.contentType != "application/x-www-form-urlencoded" {
+		t.Fatalf("unexpected content type: %s", got.contentType)
+	}
+	if got.form.Get("event") != "oncall_shift_started" {
+		t.Fatalf("unexpected event: %s", got.form.Get("event"))
+	}
+	if got.form.Get("schedule_id") != "PSCHED1" {
+		t.Fatalf("unexpected schedule_id: %s", got.form.Get("schedule_id"))
+	}
+	if got.form.Get("shift_start") != "2024-01-15T10:30:00Z" {
+		t.Fatalf("unexpected shift_start: %s", got.form.Get("shift_start"))
+	}
+}
+
+func TestWebhookNotifierSendsCloudEvent(t *testing.T) {
+	t.Parallel()
+
+	contentTypes := make(chan string, 1)
+	captures := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes <- r.Header.Get("Content-Type")
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		captures <- payload
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	opts := WebhookOptions{Format: WebhookFormatCloudEvents, ScheduleID: "PSCHED1", UserID: "PUSER1"}
+	notifier := NewWebhookNotifier(server.URL, DefaultMessages(), opts)
+	if err := notifier.NotifyWithEvent(context.Background(), EventShiftStarted, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if contentType := <-contentTypes; contentType != "application/cloudevents+json" {
+		t.Fatalf("unexpected content type: %s", contentType)
+	}
+	payload := <-captures
+	if payload["specversion"] != "1.0" {
+		t.Fatalf("unexpected specversion: %v", payload["specversion"])
+	}
+	if payload["type"] != "org.a7d.oncall.shift_started" {
+		t.Fatalf("unexpected type: %v", payload["type"])
+	}
+	if payload["source"] != "pagerduty-oncall-notifier/schedules/PSCHED1" {
+		t.Fatalf("unexpected source: %v", payload["source"])
+	}
+	if payload["subject"] != "PUSER1" {
+		t.Fatalf("unexpected subject: %v", payload["subject"])
+	}
+	if id, _ := payload["id"].(string); len(id) != 32 {
+		t.Fatalf("unexpected id: %v", payload["id"])
+	}
+	data, ok := payload["data"].(map[string]interface{})
+	if !ok || data["event"] != "oncall_shift_started" {
+		t.Fatalf("unexpected data: %v", payload["data"])
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+// ShiftLister returns the monitored user's shifts that overlap [since, until), earliest first
+type ShiftLister func(ctx context.Context, since, until time.Time) ([]pagerduty.UpcomingShift, error)
+
+// HandleCalendar serves the monitored user's shifts over the coming weeks as an iCalendar feed
+// on GET /calendar.ics, for calendar apps to subscribe to. Requests must carry token, which
+// only grants access to the feed. Calendar apps cannot send an Authorization header, so it is
+// usually given as the "token" query parameter instead.
+func (s *Server) HandleCalendar(token, userID string, weeks int, list ShiftLister) {
+	calendar := &calendarFeed{userID: userID, weeks: weeks, list: list}
+	s.mux.HandleFunc("GET /calendar.ics", authorizeCalendar(token, calendar.handle))
+}
+
+// authorizeCalendar wraps a handler to require token as the "token" query parameter or the
+// Bearer token. An empty token lets nothing through.
+func authorizeCalendar(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matches := subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) == 1 || bearerMatches(r, token)
+		if token == "" || !matches {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+type calendarFeed struct {
+	userID string
+	weeks  int
+	list   ShiftLister
+}
+
+// handle looks the shifts up in PagerDuty on every request, so the feed is as fresh as the
+// calendar app's refresh interval
+func (c *calendarFeed) handle(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().UTC()
+	shifts, err := c.list(r.Context(), since, since.AddDate(0, 0, 7*c.weeks))
+	if err != nil {
+		slog.Warn("Failed to list shifts for calendar", "error", err)
+		writeError(w, http.StatusBadGateway, "failed to list shifts")
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="oncall.ics"`)
+	if err := writeCalendar(w, c.userID, since, shifts); err != nil {
+		slog.Warn("Failed to write HTTP response", "error", err)
+	}
+}
+
+// icsTimeFormat is the UTC date-time form of iCalendar (RFC 5545)
+const icsTimeFormat = "20060102T150405Z"
+
+// icsEscaper escapes TEXT values as RFC 5545 requires
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// writeCalendar writes shifts as the events of an iCalendar. Each event's UID is derived from
+// the shift, so calendar apps update rather than duplicate it on refresh.
+func writeCalendar(w io.Writer, userID string, now time.Time, shifts []pagerduty.UpcomingShift) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(out, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//pagerduty-oncall-notifier//On-call shifts//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", "On call")
+	line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	for _, shift := range shifts {
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%s-%s-%d@pagerduty-oncall-notifier", userID, shift.ScheduleID, shift.StartTime.Unix()))
+		line("DTSTAMP", now.UTC().Format(icsTimeFormat))
+		line("DTSTART", shift.StartTime.UTC().Format(icsTimeFormat))
+		line("DTEND", shift.EndTime.UTC().Format(icsTimeFormat))
+		line("SUMMARY", "On call")
+		if shift.ScheduleID != "" {
+			line("DESCRIPTION", icsEscaper.Replace("PagerDuty schedule "+shift.ScheduleID))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+// writeFolded writes a content line ending in CRLF, folding it into continuation lines of at
+// most 75 octets as RFC 5545 requires
+func writeFolded(out *bufio.Writer, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		// Do not split a UTF-8 sequence
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		out.WriteString(content[:cut] + "\r\n ")
+		content = content[cut:]
+		limit = 74 // The leading space counts
+	}
+	out.WriteString(content + "\r\n")
+}
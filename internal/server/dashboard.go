@@ -0,0 +1,22 @@
+package server
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+)
+
+// dashboardPage is the status page served at the root of the HTTP API. It reads everything it
+// shows from the API, so it is served without the API token and asks for one if needed.
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// handleDashboard serves the status page
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	if _, err := w.Write(dashboardPage); err != nil {
+		slog.Warn("Failed to write HTTP response", "error", err)
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// NewDebug creates a server exposing the Go runtime profiles of net/http/pprof under
+// /debug/pprof/, to diagnose memory or goroutine leaks in a running notifier. It has no
+// authentication, so addr should only be reachable from the host or cluster.
+func NewDebug(addr string) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	return newServer(addr, mux)
+}
+
+// newServer creates a server for mux alone, without the control API
+func newServer(addr string, mux *http.ServeMux) *Server {
+	return &Server{
+		addr: addr,
+		mux:  mux,
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/control"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+)
+
+// gRPC status codes returned by the service
+const (
+	grpcOK                 = 0
+	grpcInvalidArgument    = 3
+	grpcResourceExhausted  = 8
+	grpcFailedPrecondition = 9
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+	grpcUnauthenticated    = 16
+)
+
+// maxGRPCMessageSize bounds request messages, which are all a few bytes long
+const maxGRPCMessageSize = 4096
+
+// grpcError is an RPC failure with the gRPC status code to report it with
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e *grpcError) Error() string {
+	return e.message
+}
+
+// grpcMethod handles a unary RPC, decoding its request from and encoding its response to protobuf
+type grpcMethod func(ctx context.Context, request []byte) (protoEncoder, error)
+
+// Resender sends the most recent notification again, returning its event
+type Resender func(ctx context.Context) (notifier.NotificationEvent, error)
+
+// HandleGRPC serves the notifier.v1.NotifierService gRPC service defined in
+// proto/notifier/v1/notifier.proto next to the HTTP API, over HTTP/2 without TLS (h2c) or
+// behind a TLS-terminating proxy. forceCheck schedules an immediate check and resend sends
+// the last notification again. Calls are authorized with the API token in the
+// "authorization" metadata, like the HTTP API.
+func (s *Server) HandleGRPC(forceCheck func(), resend Resender) {
+	methods := map[string]grpcMethod{
+		"GetStatus": s.grpcGetStatus,
+		"Mute":      s.grpcMute,
+		"Unmute":    s.grpcUnmute,
+		"ForceCheck": func(ctx context.Context, request []byte) (protoEncoder, error) {
+			if err := decodeProto(request, ignoreField); err != nil {
+				return nil, malformedRequest(err)
+			}
+			slog.Info("Check requested via gRPC API")
+			forceCheck()
+			var response protoEncoder
+			now := time.Now().UTC()
+			response.timestamp(1, &now)
+			return response, nil
+		},
+		"Resend": func(ctx context.Context, request []byte) (protoEncoder, error) {
+			if err := decodeProto(request, ignoreField); err != nil {
+				return nil, malformedRequest(err)
+			}
+			event, err := resend(ctx)
+			if errors.Is(err, control.ErrNothingToResend) {
+				return nil, &grpcError{grpcFailedPrecondition, err.Error()}
+			}
+			if err != nil {
+				slog.Warn("Failed to resend notification", "error", err)
+				// Backend errors can include webhook URLs with secrets in them, so they are only logged
+				return nil, &grpcError{grpcUnavailable, "failed to resend notification, see the notifier's logs"}
+			}
+			slog.Info("Notification resent via gRPC API", "event", event)
+			var response protoEncoder
+			response.string(1, string(event))
+			return response, nil
+		},
+	}
+	// gRPC requests need a CORS preflight, as browsers cannot send their content type with a
+	// plain form, so they are safe from cross-site request forgery without protect
+	s.mux.HandleFunc("POST /notifier.v1.NotifierService/{method}", func(w http.ResponseWriter, r *http.Request) {
+		s.serveGRPC(w, r, methods[r.PathValue("method")])
+	})
+
+	// gRPC runs over HTTP/2, which net/http only accepts without TLS when enabled
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	s.httpServer.Protocols = &protocols
+}
+
+// serveGRPC answers a unary gRPC call with method, reporting the outcome in the grpc-status and
+// grpc-message trailers
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request, method grpcMethod) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/grpc" && !strings.HasPrefix(contentType, "application/grpc+proto") {
+		writeError(w, http.StatusUnsupportedMediaType, "gRPC requests must be sent with Content-Type: application/grpc")
+		return
+	}
+
+	response, err := s.callGRPC(r, method)
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	code, message := grpcOK, ""
+	if err == nil {
+		frame := make([]byte, 5, 5+len(response))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(response)))
+		if _, err := w.Write(append(frame, response...)); err != nil {
+			slog.Warn("Failed to write gRPC response", "error", err)
+		}
+	} else {
+		var rpcErr *grpcError
+		if !errors.As(err, &rpcErr) {
+			slog.Error("gRPC call failed", "method", r.PathValue("method"), "error", err)
+			rpcErr = &grpcError{grpcInternal, "internal error, see the notifier's logs"}
+		}
+		code, message = rpcErr.code, rpcErr.message
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", grpcPercentEncode(message))
+	}
+}
+
+// callGRPC authorizes the call, reads its single request message, and runs method
+func (s *Server) callGRPC(r *http.Request, method grpcMethod) (protoEncoder, error) {
+	if s.apiToken != "" && !bearerMatches(r, s.apiToken) {
+		return nil, &grpcError{grpcUnauthenticated, "unauthorized"}
+	}
+	if method == nil {
+		return nil, &grpcError{grpcUnimplemented, fmt.Sprintf("unknown method %s", r.PathValue("method"))}
+	}
+
+	// A message is prefixed with a compression flag and its length
+	var prefix [5]byte
+	if _, err := io.ReadFull(r.Body, prefix[:]); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "missing request message"}
+	}
+	if prefix[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxGRPCMessageSize {
+		return nil, &grpcError{grpcResourceExhausted, fmt.Sprintf("request message larger than %d bytes", maxGRPCMessageSize)}
+	}
+	request := make([]byte, length)
+	if _, err := io.ReadFull(r.Body, request); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "truncated request message"}
+	}
+	return method(r.Context(), request)
+}
+
+// grpcGetStatus returns the notifier's view of the monitored user, like GET /api/v1/status
+func (s *Server) grpcGetStatus(ctx context.Context, request []byte) (protoEncoder, error) {
+	if err := decodeProto(request, ignoreField); err != nil {
+		return nil, malformedRequest(err)
+	}
+	status, err := s.status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var response protoEncoder
+	response.bool(1, status.OnCall)
+	response.timestamp(2, status.CurrentShiftStart)
+	response.timestamp(3, status.CurrentShiftEnd)
+	if next := status.NextShift; next != nil {
+		response.timestamp(4, &next.Start)
+		response.timestamp(5, &next.End)
+	}
+	response.timestamp(6, status.LastCheckAt)
+	if last := status.LastNotification; last != nil {
+		var notification protoEncoder
+		notification.string(1, last.Event)
+		notification.timestamp(2, &last.ShiftStartTime)
+		notification.string(3, last.Backend)
+		notification.timestamp(4, &last.SentAt)
+		response.message(7, notification)
+	}
+	response.bool(8, status.Muted)
+	response.timestamp(9, status.MutedUntil)
+	response.timestamp(10, status.PausedUntil)
+	response.bool(11, status.Healthy)
+	return response, nil
+}
+
+// grpcMute mutes notifications for the requested duration, or DefaultSnoozeDuration
+func (s *Server) grpcMute(ctx context.Context, request []byte) (protoEncoder, error) {
+	duration := DefaultSnoozeDuration
+	err := decodeProto(request, func(field, wireType int, v uint64, b []byte) error {
+		if field != 1 || wireType != wireBytes {
+			return nil
+		}
+		d, err := decodeDuration(b)
+		if err != nil {
+			return err
+		}
+		duration = d
+		return nil
+	})
+	if err != nil {
+		return nil, malformedRequest(err)
+	}
+	if duration <= 0 {
+		return nil, &grpcError{grpcInvalidArgument, "duration must be positive"}
+	}
+
+	mutedUntil, err := s.mute(duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mute notifications: %w", err)
+	}
+	slog.Info("Notifications muted via gRPC API", "until", mutedUntil.Format(time.RFC3339))
+
+	var response protoEncoder
+	response.timestamp(1, &mutedUntil)
+	return response, nil
+}
+
+// grpcUnmute clears any mute, leaving pauses alone like DELETE /api/v1/mute
+func (s *Server) grpcUnmute(ctx context.Context, request []byte) (protoEncoder, error) {
+	if err := decodeProto(request, ignoreField); err != nil {
+		return nil, malformedRequest(err)
+	}
+	if err := s.unmute(); err != nil {
+		return nil, fmt.Errorf("failed to unmute notifications: %w", err)
+	}
+	slog.Info("Notifications unmuted via gRPC API")
+	return protoEncoder{}, nil
+}
+
+// ignoreField skips the fields of a request that has none, or ones added in later versions
+func ignoreField(field, wireType int, v uint64, b []byte) error {
+	return nil
+}
+
+// malformedRequest reports a request message that could not be decoded
+func malformedRequest(err error) error {
+	return &grpcError{grpcInvalidArgument, fmt.Sprintf("invalid request: %v", err)}
+}
+
+// grpcPercentEncode encodes a grpc-message trailer, which must be printable ASCII
+func grpcPercentEncode(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
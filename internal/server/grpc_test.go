@@ -0,0 +1,208 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/control"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+// startGRPC serves srv over HTTP/2 without TLS using its own http.Server, so the test sees
+// the protocols HandleGRPC enabled
+func startGRPC(t *testing.T, srv *Server) string {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = srv.httpServer
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+// grpcCall makes a unary call the way a gRPC client does, returning the grpc-status trailer
+// and the fields of the response message
+func grpcCall(t *testing.T, url, token, method string, request protoEncoder) (int, map[int]protoField) {
+	t.Helper()
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(request)))
+	req, err := http.NewRequest(http.MethodPost, url+"/notifier.v1.NotifierService/"+method, bytes.NewReader(append(body, request...)))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read %s response: %v", method, err)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", resp.Proto)
+	}
+
+	code, err := strconv.Atoi(resp.Trailer.Get("Grpc-Status"))
+	if err != nil {
+		t.Fatalf("missing grpc-status trailer: %v", resp.Trailer)
+	}
+	if code != grpcOK {
+		return code, nil
+	}
+	if len(data) < 5 || int(binary.BigEndian.Uint32(data[1:5])) != len(data)-5 {
+		t.Fatalf("malformed %s response frame: %x", method, data)
+	}
+	return code, protoFieldsOf(t, data[5:])
+}
+
+// protoField is a decoded field of a message
+type protoField struct {
+	v uint64
+	b []byte
+}
+
+func protoFieldsOf(t *testing.T, data []byte) map[int]protoField {
+	t.Helper()
+	fields := map[int]protoField{}
+	if err := decodeProto(data, func(field, wireType int, v uint64, b []byte) error {
+		fields[field] = protoField{v, b}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	return fields
+}
+
+// timestampOf decodes a google.protobuf.Timestamp field
+func timestampOf(t *testing.T, field protoField) time.Time {
+	t.Helper()
+	fields := protoFieldsOf(t, field.b)
+	return time.Unix(int64(fields[1].v), int64(fields[2].v)).UTC()
+}
+
+func TestGRPCStatusAndMute(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	stateManager := state.NewManager(path)
+	shiftStart := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	if err := stateManager.Save(&state.State{
+		WasOnCall:         true,
+		CurrentShiftStart: &shiftStart,
+		LastNotification:  &state.NotificationRecord{Event: "shift_started", ShiftStartTime: shiftStart, Backend: "ntfy", SentAt: shiftStart},
+	}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	srv := New(":0", "api-token", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	srv.HandleGRPC(func() {}, func(ctx context.Context) (notifier.NotificationEvent, error) { return "", nil })
+	url := startGRPC(t, srv)
+
+	if code, _ := grpcCall(t, url, "", "GetStatus", nil); code != grpcUnauthenticated {
+		t.Fatalf("expected UNAUTHENTICATED without the token, got %d", code)
+	}
+
+	code, status := grpcCall(t, url, "api-token", "GetStatus", nil)
+	if code != grpcOK {
+		t.Fatalf("GetStatus failed with %d", code)
+	}
+	if status[1].v != 1 || !timestampOf(t, status[2]).Equal(shiftStart) {
+		t.Fatalf("expected to be on call since %v, got %+v", shiftStart, status)
+	}
+	if last := protoFieldsOf(t, status[7].b); string(last[1].b) != "shift_started" || string(last[3].b) != "ntfy" {
+		t.Fatalf("unexpected last notification: %+v", last)
+	}
+	if _, muted := status[8]; muted {
+		t.Fatalf("expected not to be muted")
+	}
+
+	var duration protoEncoder
+	duration.varint(1, int64((2 * time.Hour).Seconds()))
+	var mute protoEncoder
+	mute.message(1, duration)
+	code, response := grpcCall(t, url, "api-token", "Mute", mute)
+	if code != grpcOK {
+		t.Fatalf("Mute failed with %d", code)
+	}
+	if remaining := time.Until(timestampOf(t, response[1])); remaining < time.Hour || remaining > 2*time.Hour {
+		t.Fatalf("unexpected mute duration: %v", remaining)
+	}
+	loaded, err := stateManager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !stateManager.IsMuted(loaded) {
+		t.Fatalf("expected notifications to be muted")
+	}
+	if _, status = grpcCall(t, url, "api-token", "GetStatus", nil); status[8].v != 1 {
+		t.Fatalf("expected the status to report the mute, got %+v", status)
+	}
+
+	if code, _ := grpcCall(t, url, "api-token", "Unmute", nil); code != grpcOK {
+		t.Fatalf("Unmute failed with %d", code)
+	}
+	if loaded, _ = stateManager.Load(); stateManager.IsMuted(loaded) {
+		t.Fatalf("expected notifications to be unmuted")
+	}
+
+	duration = nil
+	duration.varint(1, -60)
+	mute = nil
+	mute.message(1, duration)
+	if code, _ := grpcCall(t, url, "api-token", "Mute", mute); code != grpcInvalidArgument {
+		t.Fatalf("expected INVALID_ARGUMENT for a negative duration, got %d", code)
+	}
+	if code, _ := grpcCall(t, url, "api-token", "Mute", protoEncoder{0xff}); code != grpcInvalidArgument {
+		t.Fatalf("expected INVALID_ARGUMENT for a malformed request, got %d", code)
+	}
+}
+
+func TestGRPCForceCheckAndResend(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	checks := 0
+	var resendErr error
+	srv.HandleGRPC(func() { checks++ }, func(ctx context.Context) (notifier.NotificationEvent, error) {
+		if resendErr != nil {
+			return "", resendErr
+		}
+		return notifier.EventShiftStarted, nil
+	})
+	url := startGRPC(t, srv)
+
+	code, response := grpcCall(t, url, "", "ForceCheck", nil)
+	if code != grpcOK || checks != 1 {
+		t.Fatalf("expected ForceCheck to schedule a check, got status %d and %d checks", code, checks)
+	}
+	if requestedAt := timestampOf(t, response[1]); time.Since(requestedAt) > time.Minute {
+		t.Fatalf("unexpected requested_at: %v", requestedAt)
+	}
+
+	code, response = grpcCall(t, url, "", "Resend", nil)
+	if code != grpcOK || string(response[1].b) != "shift_started" {
+		t.Fatalf("expected the shift_started notification to be resent, got status %d and %+v", code, response)
+	}
+	resendErr = control.ErrNothingToResend
+	if code, _ := grpcCall(t, url, "", "Resend", nil); code != grpcFailedPrecondition {
+		t.Fatalf("expected FAILED_PRECONDITION with nothing to resend, got %d", code)
+	}
+
+	if code, _ := grpcCall(t, url, "", "Restart", nil); code != grpcUnimplemented {
+		t.Fatalf("expected UNIMPLEMENTED for an unknown method, got %d", code)
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/metrics"
+)
+
+// NewMetrics creates a server exporting the on-call status of targets as Prometheus gauges on
+// GET /metrics
+func NewMetrics(addr string, targets []metrics.Target) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		if err := metrics.Write(w, targets); err != nil {
+			slog.Warn("Failed to write metrics", "error", err)
+		}
+	})
+	return newServer(addr, mux)
+}
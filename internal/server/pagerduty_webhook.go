@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBodySize bounds the PagerDuty webhook payloads that are read
+const maxWebhookBodySize = 1 << 20
+
+// pagerDutyWebhook receives verified PagerDuty V3 webhook events
+type pagerDutyWebhook struct {
+	secret  string
+	trigger func(eventType string)
+}
+
+// HandlePagerDutyWebhooks accepts PagerDuty V3 webhook events on POST /webhooks/pagerduty.
+// Requests must be signed with secret; trigger is called with the type of each verified event.
+func (s *Server) HandlePagerDutyWebhooks(secret string, trigger func(eventType string)) {
+	webhook := &pagerDutyWebhook{secret: secret, trigger: trigger}
+	s.mux.HandleFunc("POST /webhooks/pagerduty", webhook.handle)
+}
+
+// handle verifies the signature of a webhook delivery and passes its event type on
+func (p *pagerDutyWebhook) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	if !validSignature(p.secret, body, r.Header.Get("X-PagerDuty-Signature")) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	var payload struct {
+		Event struct {
+			EventType string `json:"event_type"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Event.EventType == "" {
+		writeError(w, http.StatusBadRequest, "invalid event")
+		return
+	}
+
+	slog.Info("Received PagerDuty webhook event", "event_type", payload.Event.EventType)
+	p.trigger(payload.Event.EventType)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature reports whether any of the v1 signatures in the header is the HMAC-SHA256
+// of the body with the secret. Several signatures are sent while a secret is being rotated.
+func validSignature(secret string, body []byte, header string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range strings.Split(header, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(signature), "v1=")
+		if !ok {
+			continue
+		}
+		decoded, err := hex.DecodeString(value)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+func TestPagerDutyWebhookVerifiesSignature(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "api-token", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	var received []string
+	srv.HandlePagerDutyWebhooks("webhook-secret", func(eventType string) { received = append(received, eventType) })
+
+	body := `{"event":{"id":"01ABC","event_type":"incident.escalated"}}`
+	mac := hmac.New(sha256.New, []byte("webhook-secret"))
+	mac.Write([]byte(body))
+	signature := "v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	cases := []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"v1=deadbeef", http.StatusUnauthorized},
+		// One of several signatures may match while the secret is rotated
+		{"v1=deadbeef," + signature, http.StatusAccepted},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/pagerduty", strings.NewReader(body))
+		req.Header.Set("X-PagerDuty-Signature", tc.header)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("signature %q: expected status %d, got %d", tc.header, tc.want, rec.Code)
+		}
+	}
+
+	if len(received) != 1 || received[0] != "incident.escalated" {
+		t.Fatalf("expected one verified event, got %v", received)
+	}
+}
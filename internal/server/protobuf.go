@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// The gRPC service only exchanges a handful of small messages, so they are encoded by hand in
+// the Protocol Buffers wire format (https://protobuf.dev/programming-guides/encoding/) rather
+// than with generated code. Field numbers must match proto/notifier/v1/notifier.proto.
+
+// Wire types of protobuf fields
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errMalformedMessage is returned for input that is not a valid protobuf message
+var errMalformedMessage = errors.New("malformed protobuf message")
+
+// protoEncoder appends fields to an encoded message. Like proto3, fields with their zero value
+// are left out.
+type protoEncoder []byte
+
+func (e *protoEncoder) tag(field, wireType int) {
+	*e = binary.AppendUvarint(*e, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *protoEncoder) varint(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	*e = binary.AppendUvarint(*e, uint64(v))
+}
+
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.varint(field, 1)
+	}
+}
+
+func (e *protoEncoder) string(field int, v string) {
+	if v == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	*e = binary.AppendUvarint(*e, uint64(len(v)))
+	*e = append(*e, v...)
+}
+
+// message appends an embedded message, which is encoded even when empty so it reads as set
+func (e *protoEncoder) message(field int, m protoEncoder) {
+	e.tag(field, wireBytes)
+	*e = binary.AppendUvarint(*e, uint64(len(m)))
+	*e = append(*e, m...)
+}
+
+// timestamp appends t as a google.protobuf.Timestamp, leaving it unset when t is nil or zero
+func (e *protoEncoder) timestamp(field int, t *time.Time) {
+	if t == nil || t.IsZero() {
+		return
+	}
+	var ts protoEncoder
+	ts.varint(1, t.Unix())
+	ts.varint(2, int64(t.Nanosecond()))
+	e.message(field, ts)
+}
+
+// decodeProto calls fn with each field of an encoded message. Varint and fixed-size fields
+// are passed in v, length-delimited fields in b.
+func decodeProto(data []byte, fn func(field, wireType int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 || key>>3 == 0 {
+			return errMalformedMessage
+		}
+		data = data[n:]
+		field, wireType := int(key>>3), int(key&7)
+
+		var v uint64
+		var b []byte
+		switch wireType {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errMalformedMessage
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errMalformedMessage
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errMalformedMessage
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return errMalformedMessage
+			}
+			b, data = data[n:n+int(length)], data[n+int(length):]
+		default:
+			// Groups are deprecated and not used by any of the messages
+			return errMalformedMessage
+		}
+		if err := fn(field, wireType, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeDuration decodes a google.protobuf.Duration
+func decodeDuration(data []byte) (time.Duration, error) {
+	var seconds, nanos int64
+	err := decodeProto(data, func(field, wireType int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wireType == wireVarint:
+			seconds = int64(v)
+		case field == 2 && wireType == wireVarint:
+			nanos = int64(int32(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if seconds > math.MaxInt64/int64(time.Second) || seconds < math.MinInt64/int64(time.Second) {
+		return 0, errors.New("duration out of range")
+	}
+	return time.Duration(seconds)*time.Second + time.Duration(nanos), nil
+}
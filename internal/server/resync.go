@@ -0,0 +1,21 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// HandleResync accepts POST /api/v1/resync, calling resync to discard cached shift data and
+// check PagerDuty again straight away, e.g. right after editing a schedule. The check runs in
+// the background, so the request returns before it completes.
+func (s *Server) HandleResync(resync func()) {
+	s.mux.HandleFunc("POST /api/v1/resync", protect(s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("Resync requested via HTTP API")
+		resync()
+		writeJSON(w, http.StatusAccepted, map[string]string{
+			"status":       "resync scheduled",
+			"requested_at": time.Now().UTC().Format(time.RFC3339),
+		})
+	})))
+}
@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+)
+
+// DefaultSnoozeDuration is used when a snooze request does not specify a duration
+const DefaultSnoozeDuration = time.Hour
+
+// Server exposes the notifier's HTTP control API
+type Server struct {
+	addr         string
+	apiToken     string
+	actionToken  string
+	stateManager *state.Manager
+	health       *health.Tracker
+	mux          *http.ServeMux
+	httpServer   *http.Server
+}
+
+// New creates a new HTTP API server
+// If apiToken is non-empty, requests must carry it as a Bearer token. An empty apiToken lets
+// every request through, which the configuration only allows with HTTP_API_ALLOW_UNAUTHENTICATED.
+func New(addr, apiToken string, stateManager *state.Manager, tracker *health.Tracker) *Server {
+	s := &Server{
+		addr:         addr,
+		apiToken:     apiToken,
+		stateManager: stateManager,
+		health:       tracker,
+	}
+
+	mux := http.NewServeMux()
+	s.mux = mux
+	// Acknowledge and snooze back the ntfy action buttons, so they also accept the action token
+	mux.HandleFunc("POST /api/v1/acknowledge", protect(s.authorizeAction(s.handleAcknowledge)))
+	mux.HandleFunc("POST /api/v1/mute", protect(s.authorize(s.handleSnooze)))
+	mux.HandleFunc("DELETE /api/v1/mute", protect(s.authorize(s.handleUnmute)))
+	mux.HandleFunc("POST /api/v1/snooze", protect(s.authorizeAction(s.handleSnooze)))
+	mux.HandleFunc("GET /api/v1/status", s.authorize(s.handleStatus))
+	mux.HandleFunc("GET /api/v1/notifications", s.authorize(s.handleNotifications))
+	// Health is left unauthenticated so container and load balancer probes can reach it
+	mux.HandleFunc("GET /api/v1/health", s.handleHealth)
+	mux.HandleFunc("GET /{$}", s.handleDashboard)
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the server's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Run serves the API until the context is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("http server failed: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.httpServer.Shutdown(shutdownCtx)
+	}
+}
+
+// AcceptActionToken lets token authorize the acknowledge and snooze endpoints called by ntfy
+// action buttons, and nothing else. The token is sent with every notification, so anyone who
+// can read the ntfy topic learns it; it must not be the API token.
+func (s *Server) AcceptActionToken(token string) {
+	s.actionToken = token
+}
+
+// authorize wraps a handler with Bearer token authentication when an API token is configured
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.apiToken != "" && !bearerMatches(r, s.apiToken) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorizeAction is authorize for the endpoints behind ntfy action buttons, which also
+// accept the action token
+func (s *Server) authorizeAction(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.apiToken != "" && !bearerMatches(r, s.apiToken) && (s.actionToken == "" || !bearerMatches(r, s.actionToken)) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// crossOriginProtection rejects cross-origin requests made by browsers, using the
+// Sec-Fetch-Site and Origin headers they send; other clients send neither and are let through
+var crossOriginProtection = http.NewCrossOriginProtection()
+
+// protect guards a handler that changes something against cross-site request forgery, which
+// matters most when the API runs without a token: a web page must not be able to mute the
+// notifier by posting a form to it. Cross-origin browser requests are rejected, and a request
+// body must be JSON, which needs a CORS preflight that the API never allows.
+func protect(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := crossOriginProtection.Check(r); err != nil {
+			writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return
+		}
+		if r.ContentLength != 0 {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "request body must be sent with Content-Type: application/json")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// bearerMatches reports whether r carries token as its Bearer token
+func bearerMatches(r *http.Request, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+// handleAcknowledge records that the current notification was acknowledged
+func (s *Server) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	var acknowledgedAt time.Time
+	err := s.stateManager.Update(func(currentState *state.State) error {
+		s.stateManager.RecordAcknowledgement(currentState)
+		acknowledgedAt = *currentState.LastAcknowledgedAt
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to record acknowledgement", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update state")
+		return
+	}
+
+	slog.Info("Notification acknowledged via HTTP API")
+	writeJSON(w, http.StatusOK, map[string]string{
+		"acknowledged_at": acknowledgedAt.Format(time.RFC3339),
+	})
+}
+
+// handleSnooze mutes notifications for the duration given in the "duration" query parameter
+// or JSON body field
+func (s *Server) handleSnooze(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object such as {\"duration\": \"1h\"}")
+		return
+	}
+	if raw := r.URL.Query().Get("duration"); raw != "" {
+		request.Duration = raw
+	}
+
+	duration := DefaultSnoozeDuration
+	if raw := request.Duration; raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "duration must be a positive duration (e.g., 30m, 1h)")
+			return
+		}
+		duration = d
+	}
+
+	mutedUntil, err := s.mute(duration)
+	if err != nil {
+		slog.Error("Failed to snooze notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update state")
+		return
+	}
+
+	slog.Info("Notifications snoozed via HTTP API", "until", mutedUntil.Format(time.RFC3339))
+	writeJSON(w, http.StatusOK, map[string]string{
+		"muted_until": mutedUntil.Format(time.RFC3339),
+	})
+}
+
+// handleUnmute clears any mute. Pauses are left alone, as they are planned ahead with
+// notifier pause.
+func (s *Server) handleUnmute(w http.ResponseWriter, r *http.Request) {
+	if err := s.unmute(); err != nil {
+		slog.Error("Failed to unmute notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update state")
+		return
+	}
+
+	slog.Info("Notifications unmuted via HTTP API")
+	writeJSON(w, http.StatusOK, map[string]bool{"muted": false})
+}
+
+// mute silences notifications for duration and returns when the mute ends
+func (s *Server) mute(duration time.Duration) (time.Time, error) {
+	var mutedUntil time.Time
+	err := s.stateManager.Update(func(currentState *state.State) error {
+		s.stateManager.Mute(currentState, duration)
+		mutedUntil = *currentState.MutedUntil
+		return nil
+	})
+	return mutedUntil, err
+}
+
+// unmute clears any mute
+func (s *Server) unmute() error {
+	return s.stateManager.Update(func(currentState *state.State) error {
+		s.stateManager.Unmute(currentState)
+		return nil
+	})
+}
+
+// Status is the notifier's view of the monitored user, as returned by GET /api/v1/status
+type Status struct {
+	OnCall            bool                      `json:"on_call"`
+	CurrentShiftStart *time.Time                `json:"current_shift_start,omitempty"`
+	CurrentShiftEnd   *time.Time                `json:"current_shift_end,omitempty"`
+	NextShift         *state.ShiftWindow        `json:"next_shift,omitempty"`
+	LastCheckAt       *time.Time                `json:"last_check_at,omitempty"`
+	LastNotification  *state.NotificationRecord `json:"last_notification,omitempty"`
+	Muted             bool                      `json:"muted"`
+	MutedUntil        *time.Time                `json:"muted_until,omitempty"`
+	PausedUntil       *time.Time                `json:"paused_until,omitempty"`
+	Healthy           bool                      `json:"healthy"`
+}
+
+// handleStatus reports the on-call status, shift times, and mute state as of the last check
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.status()
+	if err != nil {
+		slog.Error("Failed to load state", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load state")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// status builds the Status from the persisted state and the health tracker
+func (s *Server) status() (Status, error) {
+	currentState, err := s.stateManager.Load()
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		OnCall:           currentState.WasOnCall,
+		NextShift:        currentState.NextShift,
+		LastCheckAt:      currentState.LastCheckAt,
+		LastNotification: currentState.LastNotification,
+		Healthy:          s.health.Status().Healthy,
+	}
+	if currentState.WasOnCall {
+		status.CurrentShiftStart = currentState.CurrentShiftStart
+		status.CurrentShiftEnd = currentState.CurrentShiftEnd
+	}
+	if s.stateManager.IsMuted(currentState) {
+		status.Muted = true
+		status.MutedUntil = currentState.MutedUntil
+	}
+	if pause := s.stateManager.ActivePause(currentState); pause != nil {
+		status.Muted = true
+		status.PausedUntil = &pause.Until
+	}
+	return status, nil
+}
+
+// DefaultNotificationsLimit is the number of notifications listed when a request does not
+// specify a limit
+const DefaultNotificationsLimit = 10
+
+// handleNotifications lists the notifications recently sent, newest first, up to the number
+// given in the "limit" query parameter
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultNotificationsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > state.MaxNotificationHistory {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", state.MaxNotificationHistory))
+			return
+		}
+		limit = n
+	}
+
+	currentState, err := s.stateManager.Load()
+	if err != nil {
+		slog.Error("Failed to load state", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load state")
+		return
+	}
+
+	notifications := []state.NotificationRecord{}
+	for i := len(currentState.NotificationHistory) - 1; i >= 0 && len(notifications) < limit; i-- {
+		notifications = append(notifications, currentState.NotificationHistory[i])
+	}
+	writeJSON(w, http.StatusOK, notifications)
+}
+
+// handleHealth reports whether on-call checks are succeeding, with 503 when they keep failing
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := s.health.Status()
+	code := http.StatusOK
+	if !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, status)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write HTTP response", "error", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,410 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/health"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/metrics"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/state"
+	"github.com/a7d-corp/pagerduty-oncall-notifier/pkg/pagerduty"
+)
+
+func TestSnoozeMutesNotifications(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snooze?duration=2h", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	loaded, err := stateManager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !stateManager.IsMuted(loaded) {
+		t.Fatalf("expected notifications to be muted")
+	}
+	if remaining := time.Until(*loaded.MutedUntil); remaining < time.Hour || remaining > 2*time.Hour {
+		t.Fatalf("unexpected mute duration: %v", remaining)
+	}
+}
+
+func TestSnoozeRejectsInvalidDuration(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snooze?duration=soon", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
+
+func TestMuteAndUnmute(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	srv := New(":0", "", state.NewManager(path), health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/mute", strings.NewReader(`{"duration":"2h"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	// The mute is persisted, so a restarted notifier still honours it
+	restarted := state.NewManager(path)
+	loaded, err := restarted.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !restarted.IsMuted(loaded) || time.Until(*loaded.MutedUntil) < time.Hour {
+		t.Fatalf("expected notifications to be muted for 2h, got %v", loaded.MutedUntil)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/mute", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	if loaded, _ = restarted.Load(); restarted.IsMuted(loaded) {
+		t.Fatalf("expected notifications to be unmuted")
+	}
+}
+
+func TestStateChangesRejectCrossSiteRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	srv := New(":0", "", state.NewManager(path), health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		body    string
+		headers map[string]string
+		want    int
+	}{
+		{"cross-site fetch", http.MethodPost, "", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"foreign origin", http.MethodDelete, "", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"form post", http.MethodPost, "duration=2h", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType},
+		{"text body", http.MethodPost, `{"duration":"2h"}`, map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"dashboard", http.MethodPost, `{"duration":"2h"}`, map[string]string{"Sec-Fetch-Site": "same-origin", "Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+		{"script without body", http.MethodDelete, "", nil, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/api/v1/mute", strings.NewReader(tc.body))
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestAcknowledgeRequiresToken(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "secret", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/acknowledge", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized without token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/acknowledge", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	loaded, err := stateManager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.LastAcknowledgedAt == nil {
+		t.Fatalf("expected acknowledgement to be recorded")
+	}
+}
+
+func TestActionTokenOnlyAllowsActions(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "secret", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	srv.AcceptActionToken("action")
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/api/v1/acknowledge", http.StatusOK},
+		{http.MethodPost, "/api/v1/snooze?duration=1h", http.StatusOK},
+		{http.MethodPost, "/api/v1/mute", http.StatusUnauthorized},
+		{http.MethodDelete, "/api/v1/mute", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/status", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer action")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s with the action token: expected status %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestHealthReportsFailingChecks(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	tracker := health.NewTracker(1)
+	srv := New(":0", "secret", stateManager, tracker)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected healthy status without token, got %d", rec.Code)
+	}
+
+	tracker.RecordFailure(errors.New("pagerduty unreachable"))
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected unhealthy status, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "pagerduty unreachable") {
+		t.Fatalf("expected last error in body, got %s", rec.Body.String())
+	}
+}
+
+func TestStatusReportsLastCheck(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	next := state.ShiftWindow{Start: time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), End: time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC)}
+	err := stateManager.Update(func(currentState *state.State) error {
+		stateManager.RecordCheck(currentState)
+		stateManager.RecordNextShift(currentState, &next)
+		stateManager.RecordNotificationSent(currentState, "ntfy", "shift_ended", next.Start.AddDate(0, 0, -7))
+		stateManager.Mute(currentState, time.Hour)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to parse status: %v", err)
+	}
+	if status.OnCall || status.NextShift == nil || !status.NextShift.Start.Equal(next.Start) || status.LastCheckAt == nil {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if !status.Muted || status.MutedUntil == nil || status.LastNotification == nil || status.LastNotification.Event != "shift_ended" || !status.Healthy {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestMetricsServesGauges(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := NewMetrics(":0", []metrics.Target{{UserID: "PUSER01", StateManager: stateManager, Health: health.NewTracker(1)}})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `pagerduty_oncall_check_consecutive_failures{user="PUSER01"} 0`) {
+		t.Fatalf("expected metrics in body, got %s", rec.Body.String())
+	}
+}
+
+func TestDebugServesProfiles(t *testing.T) {
+	srv := NewDebug(":0")
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/pprof/heap"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: unexpected status %d", path, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the control API to be absent from the debug server, got status %d", rec.Code)
+	}
+}
+
+func TestDashboardIsServedWithoutToken(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "secret", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "api/v1/status") {
+		t.Fatalf("expected the dashboard, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected only the root path to serve the dashboard, got status %d", rec.Code)
+	}
+}
+
+func TestNotificationsListsNewestFirst(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	err := stateManager.Update(func(currentState *state.State) error {
+		for _, event := range []string{"upcoming_shift", "shift_started", "shift_ended"} {
+			stateManager.RecordNotificationSent(currentState, "ntfy", event, start)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/notifications?limit=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	var notifications []state.NotificationRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &notifications); err != nil {
+		t.Fatalf("failed to parse notifications: %v", err)
+	}
+	if len(notifications) != 2 || notifications[0].Event != "shift_ended" || notifications[1].Event != "shift_started" {
+		t.Fatalf("unexpected notifications: %+v", notifications)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/notifications?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid limit to be rejected, got status %d", rec.Code)
+	}
+}
+
+func TestCalendarListsShifts(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "secret", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	start := time.Date(2030, 6, 2, 9, 0, 0, 0, time.UTC)
+	var listedUntil time.Time
+	srv.HandleCalendar("calendar", "PUSER01", 2, func(ctx context.Context, since, until time.Time) ([]pagerduty.UpcomingShift, error) {
+		listedUntil = until
+		return []pagerduty.UpcomingShift{{StartTime: start, EndTime: start.AddDate(0, 0, 7), ScheduleID: "PSCHED1"}}, nil
+	})
+
+	// The API token does not open the feed, so it never ends up in a calendar URL
+	for _, path := range []string{"/calendar.ics", "/calendar.ics?token=wrong", "/calendar.ics?token=secret"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected the request to be rejected, got status %d", path, rec.Code)
+		}
+	}
+
+	// The calendar token opens nothing but the feed
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer calendar")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the calendar token to be rejected by the API, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/calendar.ics?token=calendar", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:PUSER01-PSCHED1-1906621200@pagerduty-oncall-notifier\r\n",
+		"DTSTART:20300602T090000Z\r\nDTEND:20300609T090000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in calendar:\n%s", want, body)
+		}
+	}
+	if weeks := time.Until(listedUntil).Hours() / 24 / 7; weeks < 1.9 || weeks > 2 {
+		t.Errorf("expected two weeks of shifts to be listed, got %.1f", weeks)
+	}
+}
+
+func TestTestNotifySendsRequestedEvent(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	var sent []notifier.NotificationEvent
+	srv.HandleTestNotifications(func(ctx context.Context, event notifier.NotificationEvent) error {
+		sent = append(sent, event)
+		return nil
+	})
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{"", http.StatusOK},
+		{`{"event":"upcoming_shift"}`, http.StatusOK},
+		{`{"event":"lunch"}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/test-notify", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("body %q: expected status %d, got %d", tc.body, tc.want, rec.Code)
+		}
+	}
+	if want := []notifier.NotificationEvent{notifier.EventShiftStarted, notifier.EventUpcomingShift}; !slices.Equal(sent, want) {
+		t.Fatalf("expected events %v, got %v", want, sent)
+	}
+}
+
+func TestResyncRequiresToken(t *testing.T) {
+	stateManager := state.NewManager(filepath.Join(t.TempDir(), "state.json"))
+	srv := New(":0", "secret", stateManager, health.NewTracker(health.DefaultUnhealthyThreshold))
+	resyncs := 0
+	srv.HandleResync(func() { resyncs++ })
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/resync", nil))
+	if rec.Code != http.StatusUnauthorized || resyncs != 0 {
+		t.Fatalf("expected an unauthorized request to be rejected, got %d with %d resyncs", rec.Code, resyncs)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/resync", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || resyncs != 1 {
+		t.Fatalf("expected the resync to be accepted, got %d with %d resyncs", rec.Code, resyncs)
+	}
+}
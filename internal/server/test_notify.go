@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/notifier"
+)
+
+// TestNotifier sends a sample notification for event through the configured backend
+type TestNotifier func(ctx context.Context, event notifier.NotificationEvent) error
+
+// HandleTestNotifications accepts POST /api/v1/test-notify with a JSON body such as
+// {"event": "upcoming_shift"}, sending a sample notification with send. Without a body a
+// shift_started notification is sent.
+func (s *Server) HandleTestNotifications(send TestNotifier) {
+	s.mux.HandleFunc("POST /api/v1/test-notify", protect(s.authorize(func(w http.ResponseWriter, r *http.Request) {
+		handleTestNotify(w, r, send)
+	})))
+}
+
+// handleTestNotify sends the requested test notification and reports whether the backend
+// accepted it
+func handleTestNotify(w http.ResponseWriter, r *http.Request, send TestNotifier) {
+	var request struct {
+		Event string `json:"event"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object such as {\"event\": \"shift_started\"}")
+		return
+	}
+	event := notifier.EventShiftStarted
+	if request.Event != "" {
+		parsed, err := notifier.ParseEvent(request.Event)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		event = parsed
+	}
+
+	slog.Info("Sending test notification via HTTP API", "event", event)
+	if err := send(r.Context(), event); err != nil {
+		slog.Warn("Failed to send test notification", "event", event, "error", err)
+		// Backend errors can include webhook URLs with secrets in them, so they are only logged
+		writeError(w, http.StatusBadGateway, "failed to send test notification, see the notifier's logs")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"event":   string(event),
+		"sent_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,127 @@
+// Package slack sets the Slack profile status of the user a user token belongs to, so that
+// colleagues can see at a glance who is carrying the pager
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/a7d-corp/pagerduty-oncall-notifier/internal/httpclient"
+)
+
+// requestTimeout bounds each status update so a slow Slack does not hold up the next check
+const requestTimeout = 10 * time.Second
+
+// apiURL is the Slack Web API base URL, replaced in tests
+var apiURL = "https://slack.com/api"
+
+var client = &http.Client{Timeout: requestTimeout, Transport: httpclient.WithUserAgent(nil)}
+
+// Status is a Slack profile status. Slack clears it by itself at Expiration, unless that is zero.
+type Status struct {
+	Text       string
+	Emoji      string
+	Expiration time.Time
+}
+
+// profile is the status part of a Slack user profile
+type profile struct {
+	StatusText       string `json:"status_text"`
+	StatusEmoji      string `json:"status_emoji"`
+	StatusExpiration int64  `json:"status_expiration"`
+}
+
+// GetStatus returns the profile status of the user token's owner. The token needs the
+// users.profile:read scope.
+func GetStatus(ctx context.Context, token string) (Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/users.profile.get", nil)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to create Slack status request: %w", err)
+	}
+	var result struct {
+		Profile profile `json:"profile"`
+	}
+	if err := call(req, token, &result); err != nil {
+		return Status{}, fmt.Errorf("failed to get Slack status: %w", err)
+	}
+	status := Status{Text: result.Profile.StatusText, Emoji: result.Profile.StatusEmoji}
+	if result.Profile.StatusExpiration != 0 {
+		status.Expiration = time.Unix(result.Profile.StatusExpiration, 0)
+	}
+	return status, nil
+}
+
+// SetStatus sets the profile status of the user token's owner. The token needs the
+// users.profile:write scope.
+func SetStatus(ctx context.Context, token string, status Status) error {
+	var expiration int64
+	if !status.Expiration.IsZero() {
+		expiration = status.Expiration.Unix()
+	}
+	body, err := json.Marshal(map[string]any{
+		"profile": profile{
+			StatusText:       status.Text,
+			StatusEmoji:      status.Emoji,
+			StatusExpiration: expiration,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/users.profile.set", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack status request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if err := call(req, token, nil); err != nil {
+		return fmt.Errorf("failed to set Slack status: %w", err)
+	}
+	return nil
+}
+
+// call sends a Web API request authenticated with token and decodes the response into result,
+// if not nil
+func call(req *http.Request, token string, result any) error {
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("Slack returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read Slack response: %w", err)
+	}
+	// Slack reports most failures, such as a missing scope, in the body of a 200 response
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to decode Slack response: %w", err)
+	}
+	if !status.OK {
+		return fmt.Errorf("Slack returned error: %s", status.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("failed to decode Slack response: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClearStatus removes the profile status of the user token's owner
+func ClearStatus(ctx context.Context, token string) error {
+	return SetStatus(ctx, token, Status{})
+}
@@ -0,0 +1,65 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetStatus(t *testing.T) {
+	var profile map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxp-token" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		if r.URL.Path == "/users.profile.get" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "profile": profile})
+			return
+		}
+		var body struct {
+			Profile map[string]any `json:"profile"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		profile = body.Profile
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	apiURL = server.URL
+
+	until := time.Date(2030, time.March, 8, 9, 0, 0, 0, time.UTC)
+	status := Status{Text: "On call until Fri 09:00", Emoji: ":pager:", Expiration: until}
+	if err := SetStatus(context.Background(), "xoxp-token", status); err != nil {
+		t.Fatalf("SetStatus returned error: %v", err)
+	}
+	if profile["status_text"] != status.Text || profile["status_emoji"] != status.Emoji || profile["status_expiration"] != float64(until.Unix()) {
+		t.Fatalf("unexpected profile: %v", profile)
+	}
+	got, err := GetStatus(context.Background(), "xoxp-token")
+	if err != nil {
+		t.Fatalf("GetStatus returned error: %v", err)
+	}
+	if got.Text != status.Text || got.Emoji != status.Emoji || !got.Expiration.Equal(until) {
+		t.Fatalf("expected GetStatus to return %+v, got %+v", status, got)
+	}
+
+	if err := ClearStatus(context.Background(), "xoxp-token"); err != nil {
+		t.Fatalf("ClearStatus returned error: %v", err)
+	}
+	if profile["status_text"] != "" || profile["status_emoji"] != "" || profile["status_expiration"] != float64(0) {
+		t.Fatalf("expected the status to be cleared, got %v", profile)
+	}
+
+	// Slack answers API errors with 200 and ok set to false
+	if err := SetStatus(context.Background(), "wrong", status); err == nil {
+		t.Fatalf("expected an error for a rejected token")
+	}
+	if _, err := GetStatus(context.Background(), "wrong"); err == nil {
+		t.Fatalf("expected an error for a rejected token")
+	}
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+// encryptionAlgorithm identifies the envelope format written by EncryptedStore
+const encryptionAlgorithm = "aes-256-gcm"
+
+// encryptedEnvelope is the JSON document an EncryptedStore saves in place of the state. It is
+// itself JSON so that it can be kept by any store, including the jsonb column of PostgresStore.
+type encryptedEnvelope struct {
+	Encrypted  string `json:"encrypted"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewStateCipher creates the AES-256-GCM cipher used by EncryptedStore from a 32-byte key
+func NewStateCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("state encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedStore encrypts the state before handing it to another store. State saved before
+// encryption was enabled is still loaded, and is encrypted on the next save.
+type EncryptedStore struct {
+	store Store
+	aead  cipher.AEAD
+}
+
+// NewEncryptedStore creates a store that encrypts state with aead (see NewStateCipher) and
+// keeps it in store
+func NewEncryptedStore(store Store, aead cipher.AEAD) *EncryptedStore {
+	return &EncryptedStore{store: store, aead: aead}
+}
+
+// Load implements Store
+func (s *EncryptedStore) Load() ([]byte, error) {
+	data, err := s.store.Load()
+	if err != nil || data == nil {
+		return data, err
+	}
+
+	var envelope encryptedEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if envelope.Encrypted == "" {
+		// Saved in plain text before encryption was enabled
+		return data, nil
+	}
+	if envelope.Encrypted != encryptionAlgorithm {
+		return nil, fmt.Errorf("unsupported state encryption %q", envelope.Encrypted)
+	}
+	plaintext, err := s.aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt state (wrong STATE_ENCRYPTION_KEY?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// Save implements Store
+func (s *EncryptedStore) Save(data []byte) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate state nonce: %w", err)
+	}
+	envelope, err := json.MarshalIndent(encryptedEnvelope{
+		Encrypted:  encryptionAlgorithm,
+		Nonce:      nonce,
+		Ciphertext: s.aead.Seal(nil, nonce, data, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted state: %w", err)
+	}
+	return s.store.Save(envelope)
+}
+
+// Lock implements LockingStore by locking the underlying store, if it supports locking
+func (s *EncryptedStore) Lock() (func(), error) {
+	if locker, ok := s.store.(LockingStore); ok {
+		return locker.Lock()
+	}
+	return func() {}, nil
+}
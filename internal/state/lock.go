@@ -0,0 +1,79 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned by LockFile when another process holds the lock
+var ErrLocked = errors.New("state file is locked by another process")
+
+// FileLock is an advisory lock held on a state file, released by Close or when the process exits
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile takes an exclusive advisory lock for the state file at path, so that two
+// notifiers pointed at the same file cannot interleave writes. The lock is held on a
+// separate path+".lock" file, as saves replace the state file itself. LockFile does not
+// wait: if another process holds the lock it fails with an error wrapping ErrLocked.
+func LockFile(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+
+	locked, err := tryLock(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
+	}
+	if !locked {
+		holder := ""
+		if data, err := os.ReadFile(lockPath); err == nil {
+			if pid := strings.TrimSpace(string(data)); pid != "" {
+				holder = " (pid " + pid + ")"
+			}
+		}
+		f.Close()
+		return nil, fmt.Errorf("%w%s: %s; is another notifier running with the same STATE_FILE_PATH?", ErrLocked, holder, path)
+	}
+
+	// Record the holder to make the error above more helpful
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &FileLock{file: f}, nil
+}
+
+// Close releases the lock
+func (l *FileLock) Close() error {
+	return l.file.Close()
+}
+
+// Lock implements LockingStore. It takes a blocking flock on a path+".update.lock" file for
+// the duration of an update; this is a different file from the one LockFile holds for the
+// lifetime of the notifier, so that subcommands can update the state while it runs.
+func (s *FileStore) Lock() (func(), error) {
+	lockPath := s.Path + ".update.lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+	if err := waitLock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// tryLock is a no-op where flock is unavailable; the lock is not enforced
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// waitLock is a no-op where flock is unavailable; the lock is not enforced
+func waitLock(f *os.File) error {
+	return nil
+}
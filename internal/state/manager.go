@@ -3,42 +3,117 @@ package state
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 )
 
 // State represents the persisted on-call state
 type State struct {
-	WasOnCall                   bool       `json:"was_on_call"`
-	LastAdvanceNotificationSent *time.Time `json:"last_advance_notification_sent,omitempty"`
+	WasOnCall                       bool                 `json:"was_on_call"`
+	LastAdvanceNotificationSent     *time.Time           `json:"last_advance_notification_sent,omitempty"`
+	AdvanceNotificationSentFor      *time.Time           `json:"advance_notification_sent_for,omitempty"`
+	AdvanceNotificationScheduledFor *time.Time           `json:"advance_notification_scheduled_for,omitempty"`
+	ScheduledAdvanceNotifications   []ScheduledDelivery  `json:"scheduled_advance_notifications,omitempty"`
+	LastShiftEndingNotification     *time.Time           `json:"last_shift_ending_notification,omitempty"`
+	LastDailySummary                *time.Time           `json:"last_daily_summary,omitempty"`
+	MutedUntil                      *time.Time           `json:"muted_until,omitempty"`
+	Pauses                          []PauseWindow        `json:"pauses,omitempty"`
+	LastCheckAt                     *time.Time           `json:"last_check_at,omitempty"`
+	LastAcknowledgedAt              *time.Time           `json:"last_acknowledged_at,omitempty"`
+	LastNotification                *NotificationRecord  `json:"last_notification,omitempty"`
+	NotificationHistory             []NotificationRecord `json:"notification_history,omitempty"`
+	CurrentShiftStart               *time.Time           `json:"current_shift_start,omitempty"`
+	CurrentShiftScheduleID          string               `json:"current_shift_schedule_id,omitempty"`
+	CurrentShiftEnd                 *time.Time           `json:"current_shift_end,omitempty"`
+	NextShift                       *ShiftWindow         `json:"next_shift,omitempty"`
+	KnownUpcomingShift              *ShiftWindow         `json:"known_upcoming_shift,omitempty"`
+	LastCoverageGap                 *ShiftWindow         `json:"last_coverage_gap,omitempty"`
+	AlertedOnCalls                  []OnCallRecord       `json:"alerted_on_calls,omitempty"`
+	ShiftSnapshot                   *ShiftSnapshot       `json:"shift_snapshot,omitempty"`
+	SlackStatusText                 string               `json:"slack_status_text,omitempty"`
+	SlackStatusEnd                  *time.Time           `json:"slack_status_end,omitempty"`
 }
 
+// ShiftWindow is the time window of an on-call shift
+type ShiftWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ScheduledDelivery is a notification handed to the backend to be delivered at DeliverAt
+type ScheduledDelivery struct {
+	ID        string    `json:"id"`
+	DeliverAt time.Time `json:"deliver_at"`
+}
+
+// PauseWindow is a period notifications are suppressed for, e.g. while someone else covers
+// the user's leave
+type PauseWindow struct {
+	From  time.Time `json:"from"`
+	Until time.Time `json:"until"`
+}
+
+// ShiftSnapshot records the user's upcoming shifts up to Until as last seen
+type ShiftSnapshot struct {
+	Until  time.Time     `json:"until"`
+	Shifts []ShiftWindow `json:"shifts"`
+}
+
+// ShiftChange describes an edit to the user's upcoming shifts: Added is set for a new shift,
+// Removed for a shift that disappeared, and both when a shift moved
+type ShiftChange struct {
+	Added   *ShiftWindow
+	Removed *ShiftWindow
+}
+
+// OnCallRecord identifies an on-call assignment by user and start time
+type OnCallRecord struct {
+	UserID string    `json:"user_id"`
+	Start  time.Time `json:"start"`
+}
+
+// NotificationRecord describes a notification that was sent
+type NotificationRecord struct {
+	Event          string    `json:"event"`
+	ShiftStartTime time.Time `json:"shift_start_time"`
+	Backend        string    `json:"backend,omitempty"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// MaxNotificationHistory is the number of sent notifications kept in the state; older
+// entries are pruned as new ones are recorded
+const MaxNotificationHistory = 50
+
 // Manager handles state persistence and transition detection
 type Manager struct {
-	filePath string
+	store Store
+	mu    sync.Mutex
 }
 
-// NewManager creates a new state manager
+// NewManager creates a new state manager that persists state to a JSON file
 func NewManager(filePath string) *Manager {
+	return NewManagerWithStore(&FileStore{Path: filePath})
+}
+
+// NewManagerWithStore creates a new state manager that persists state to the given store
+func NewManagerWithStore(store Store) *Manager {
 	return &Manager{
-		filePath: filePath,
+		store: store,
 	}
 }
 
-// Load loads the state from disk, returning default state if file doesn't exist
+// Load loads the state from the store, returning default state if none has been saved yet
 func (m *Manager) Load() (*State, error) {
-	// Check if file exists
-	if _, err := os.Stat(m.filePath); os.IsNotExist(err) {
+	data, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
 		// Return default state (not on-call)
 		return &State{WasOnCall: false}, nil
 	}
 
-	data, err := os.ReadFile(m.filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read state file: %w", err)
-	}
-
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
@@ -47,24 +122,41 @@ func (m *Manager) Load() (*State, error) {
 	return &state, nil
 }
 
-// Save persists the state to disk
+// Save persists the state to the store
 func (m *Manager) Save(state *State) error {
-	// Ensure directory exists
-	dir := filepath.Dir(m.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create state directory: %w", err)
-	}
-
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(m.filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+	return m.store.Save(data)
+}
+
+// Update loads the state, applies fn, and saves the result while holding the manager's lock,
+// and the store's lock if it is a LockingStore so that other processes cannot lose the update
+// The state is not saved if fn returns an error
+func (m *Manager) Update(fn func(state *State) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if locker, ok := m.store.(LockingStore); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
 
-	return nil
+	state, err := m.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	return m.Save(state)
 }
 
 // HasTransitionToOnCall checks if there was a transition from not-on-call to on-call
@@ -79,23 +171,38 @@ func (m *Manager) HasTransitionToOffCall(previousState *State, currentlyOnCall b
 
 // ShouldSendAdvanceNotification checks if an advance notification should be sent
 // Returns true if:
-// - The shift starts within the advance notification window
-// - No advance notification has been sent yet, or the last one was for a different shift
-func (m *Manager) ShouldSendAdvanceNotification(state *State, shiftStartTime time.Time, advanceTime time.Duration) bool {
-	if advanceTime <= 0 {
-		return false
-	}
-
+// - The shift starts within one of the advance notification windows
+// - No advance notification has been sent for a shift starting at shiftStartTime within the
+// narrowest of those windows yet, so each advance time sends one reminder per shift
+func (m *Manager) ShouldSendAdvanceNotification(state *State, shiftStartTime time.Time, advanceTimes ...time.Duration) bool {
 	now := time.Now().UTC()
 	timeUntilShift := shiftStartTime.Sub(now)
+	if timeUntilShift <= 0 {
+		return false
+	}
 
-	// Check if shift is within the advance notification window
-	if timeUntilShift <= 0 || timeUntilShift > advanceTime {
+	// Find the narrowest window the shift is in; after downtime, the wider ones are not caught up
+	var window time.Duration
+	for _, advanceTime := range advanceTimes {
+		if advanceTime >= timeUntilShift && (window == 0 || advanceTime < window) {
+			window = advanceTime
+		}
+	}
+	if window == 0 {
 		return false
 	}
 
-	// Check if we've already sent an advance notification for this shift
-	// We'll consider it a different shift if more than 24 hours have passed since the last notification
+	// Check if we've already sent an advance notification for this shift within the window
+	if state.AdvanceNotificationSentFor != nil {
+		if !state.AdvanceNotificationSentFor.Equal(shiftStartTime) {
+			return true
+		}
+		return state.LastAdvanceNotificationSent == nil ||
+			state.LastAdvanceNotificationSent.Before(shiftStartTime.Add(-window))
+	}
+
+	// State saved by older versions only records when the last notification was sent;
+	// consider it a different shift if more than 24 hours have passed since then
 	if state.LastAdvanceNotificationSent != nil {
 		timeSinceLastNotification := now.Sub(*state.LastAdvanceNotificationSent)
 		if timeSinceLastNotification < 24*time.Hour {
@@ -106,8 +213,361 @@ func (m *Manager) ShouldSendAdvanceNotification(state *State, shiftStartTime tim
 	return true
 }
 
-// RecordAdvanceNotificationSent updates the state to record when an advance notification was sent
-func (m *Manager) RecordAdvanceNotificationSent(state *State) {
+// ShouldScheduleAdvanceNotification checks if an advance notification should be handed to the backend for delayed delivery
+// Returns true if:
+// - The delivery time (shift start minus advance time) is in the future but no more than maxDelay away
+// - No advance notification has been scheduled for this shift yet
+func (m *Manager) ShouldScheduleAdvanceNotification(state *State, shiftStartTime time.Time, advanceTime, maxDelay time.Duration) bool {
+	if advanceTime <= 0 {
+		return false
+	}
+
+	if m.IsAdvanceNotificationScheduled(state, shiftStartTime) {
+		return false
+	}
+
+	untilDelivery := time.Until(shiftStartTime.Add(-advanceTime))
+	return untilDelivery > 0 && untilDelivery <= maxDelay
+}
+
+// IsAdvanceNotificationScheduled checks if an advance notification has already been scheduled for the shift
+func (m *Manager) IsAdvanceNotificationScheduled(state *State, shiftStartTime time.Time) bool {
+	return state.AdvanceNotificationScheduledFor != nil && state.AdvanceNotificationScheduledFor.Equal(shiftStartTime)
+}
+
+// RecordAdvanceNotificationScheduled updates the state to record the shift advance notifications
+// were scheduled for, and the deliveries that can be cancelled if they are no longer wanted
+func (m *Manager) RecordAdvanceNotificationScheduled(state *State, shiftStartTime time.Time, deliveries []ScheduledDelivery) {
+	start := shiftStartTime.UTC()
+	state.AdvanceNotificationScheduledFor = &start
+	state.ScheduledAdvanceNotifications = deliveries
+}
+
+// ClearAdvanceNotificationSchedule forgets the scheduled advance notifications, so that they
+// are scheduled again if still needed, and returns the deliveries that are still ahead
+func (m *Manager) ClearAdvanceNotificationSchedule(state *State) []ScheduledDelivery {
+	var pending []ScheduledDelivery
+	now := time.Now()
+	for _, delivery := range state.ScheduledAdvanceNotifications {
+		if delivery.DeliverAt.After(now) {
+			pending = append(pending, delivery)
+		}
+	}
+	state.AdvanceNotificationScheduledFor = nil
+	state.ScheduledAdvanceNotifications = nil
+	return pending
+}
+
+// RecordAdvanceNotificationSent updates the state to record when an advance notification was
+// sent and the start of the shift it was sent for
+func (m *Manager) RecordAdvanceNotificationSent(state *State, shiftStartTime time.Time) {
 	now := time.Now().UTC()
+	start := shiftStartTime.UTC()
 	state.LastAdvanceNotificationSent = &now
+	state.AdvanceNotificationSentFor = &start
+}
+
+// ShouldSendShiftEndingNotification checks if a reminder should be sent for the shift
+// ending at shiftEndTime: the end is within the reminder window and no reminder has been
+// sent for a shift ending at that time yet
+func (m *Manager) ShouldSendShiftEndingNotification(state *State, shiftEndTime time.Time, reminderTime time.Duration) bool {
+	if reminderTime <= 0 {
+		return false
+	}
+
+	timeUntilEnd := time.Until(shiftEndTime)
+	if timeUntilEnd <= 0 || timeUntilEnd > reminderTime {
+		return false
+	}
+
+	return state.LastShiftEndingNotification == nil || !state.LastShiftEndingNotification.Equal(shiftEndTime)
+}
+
+// RecordShiftEndingNotificationSent updates the state to record the end of the shift a reminder was sent for
+func (m *Manager) RecordShiftEndingNotificationSent(state *State, shiftEndTime time.Time) {
+	end := shiftEndTime.UTC()
+	state.LastShiftEndingNotification = &end
+}
+
+// ShouldSendDailySummary checks if the daily summary due at summaryTime has not been sent yet.
+// A summary more than grace late is skipped, so one is not sent hours after it was due.
+func (m *Manager) ShouldSendDailySummary(state *State, summaryTime time.Time, grace time.Duration) bool {
+	late := time.Since(summaryTime)
+	if late < 0 || late > grace {
+		return false
+	}
+
+	return state.LastDailySummary == nil || state.LastDailySummary.Before(summaryTime)
+}
+
+// RecordDailySummarySent updates the state to record the time the daily summary was due
+func (m *Manager) RecordDailySummarySent(state *State, summaryTime time.Time) {
+	due := summaryTime.UTC()
+	state.LastDailySummary = &due
+}
+
+// Mute suppresses notifications for the given duration
+func (m *Manager) Mute(state *State, duration time.Duration) {
+	until := time.Now().UTC().Add(duration)
+	state.MutedUntil = &until
+}
+
+// Unmute clears any active mute
+func (m *Manager) Unmute(state *State) {
+	state.MutedUntil = nil
+}
+
+// RecordCheck updates the state to record that on-call status was known up to now
+func (m *Manager) RecordCheck(state *State) {
+	now := time.Now().UTC()
+	state.LastCheckAt = &now
+}
+
+// Pause suppresses notifications from from until until, keeping any other pauses that have not
+// ended yet
+func (m *Manager) Pause(state *State, from, until time.Time) {
+	now := time.Now()
+	state.Pauses = slices.DeleteFunc(state.Pauses, func(p PauseWindow) bool {
+		return !p.Until.After(now)
+	})
+	state.Pauses = append(state.Pauses, PauseWindow{From: from.UTC(), Until: until.UTC()})
+	slices.SortFunc(state.Pauses, func(a, b PauseWindow) int {
+		return a.From.Compare(b.From)
+	})
+}
+
+// Resume clears every pause, including those that have not started yet
+func (m *Manager) Resume(state *State) {
+	state.Pauses = nil
+}
+
+// ActivePause returns the pause notifications are currently suppressed by, or nil if none is
+func (m *Manager) ActivePause(state *State) *PauseWindow {
+	now := time.Now()
+	for i, pause := range state.Pauses {
+		if !now.Before(pause.From) && now.Before(pause.Until) {
+			return &state.Pauses[i]
+		}
+	}
+	return nil
+}
+
+// IsMuted checks if notifications are currently muted
+func (m *Manager) IsMuted(state *State) bool {
+	return state.MutedUntil != nil && time.Now().UTC().Before(*state.MutedUntil)
+}
+
+// RecordAcknowledgement updates the state to record when a notification was acknowledged
+func (m *Manager) RecordAcknowledgement(state *State) {
+	now := time.Now().UTC()
+	state.LastAcknowledgedAt = &now
+}
+
+// RecordNotificationSent updates the state to record the most recently sent notification,
+// and appends it to the notification history
+func (m *Manager) RecordNotificationSent(state *State, backend, event string, shiftStartTime time.Time) {
+	record := NotificationRecord{
+		Event:          event,
+		ShiftStartTime: shiftStartTime.UTC(),
+		Backend:        backend,
+		SentAt:         time.Now().UTC(),
+	}
+	state.LastNotification = &record
+	state.NotificationHistory = append(state.NotificationHistory, record)
+	if excess := len(state.NotificationHistory) - MaxNotificationHistory; excess > 0 {
+		state.NotificationHistory = slices.Delete(state.NotificationHistory, 0, excess)
+	}
+}
+
+// RecordShiftStarted records the start of the shift the user is currently on call for, and
+// the schedule it belongs to if known
+func (m *Manager) RecordShiftStarted(state *State, shiftStartTime time.Time, scheduleID string) {
+	start := shiftStartTime.UTC()
+	state.CurrentShiftStart = &start
+	state.CurrentShiftScheduleID = scheduleID
+}
+
+// RecordShiftEnded clears the current shift, returning its recorded start (zero if unknown)
+// and schedule
+func (m *Manager) RecordShiftEnded(state *State) (time.Time, string) {
+	var start time.Time
+	if state.CurrentShiftStart != nil {
+		start = *state.CurrentShiftStart
+	}
+	scheduleID := state.CurrentShiftScheduleID
+	state.CurrentShiftStart = nil
+	state.CurrentShiftScheduleID = ""
+	return start, scheduleID
+}
+
+// RecordCurrentShift records when the user's current shift, as last looked up, ends. A nil
+// shift clears it, e.g. once the user is off call.
+func (m *Manager) RecordCurrentShift(state *State, current *ShiftWindow) {
+	state.CurrentShiftEnd = nil
+	if current != nil {
+		end := current.End
+		state.CurrentShiftEnd = &end
+	}
+}
+
+// RecordSlackStatus records the Slack status text the notifier set and the shift end it shows,
+// so that the status is only updated or cleared while it is still the one the notifier set.
+// An empty text records that the notifier does not own the status; a zero end that it is unknown.
+func (m *Manager) RecordSlackStatus(state *State, text string, shiftEnd time.Time) {
+	state.SlackStatusText = text
+	state.SlackStatusEnd = nil
+	if !shiftEnd.IsZero() {
+		end := shiftEnd.UTC()
+		state.SlackStatusEnd = &end
+	}
+}
+
+// RecordNextShift records the user's next shift as last looked up, or that there is none
+func (m *Manager) RecordNextShift(state *State, next *ShiftWindow) {
+	state.NextShift = next
+}
+
+// TrackUpcomingShift records the next upcoming shift and returns the previously known
+// upcoming shift if it has since been taken away, e.g. by an override. A shift counts as
+// taken away when it has not started yet but is no longer the next shift, or has been cut
+// short. If next starts before the known shift the known shift is kept, since it may
+// still follow the new one.
+func (m *Manager) TrackUpcomingShift(state *State, next *ShiftWindow) *ShiftWindow {
+	known := state.KnownUpcomingShift
+	if known != nil && known.Start.After(time.Now()) {
+		if next != nil && next.Start.Before(known.Start) {
+			return nil
+		}
+		if next == nil || next.Start.After(known.Start) || next.End.Before(known.End) {
+			state.KnownUpcomingShift = next
+			return known
+		}
+	}
+	state.KnownUpcomingShift = next
+	return nil
+}
+
+// ShouldSendCoverageGapAlert checks if a coverage gap has not been alerted about yet. Gaps are
+// clamped to the lookahead window, so an ongoing or trailing gap is reported with new bounds on
+// every check; any overlap with the gap already alerted about counts as the same gap.
+func (m *Manager) ShouldSendCoverageGapAlert(state *State, gap ShiftWindow) bool {
+	last := state.LastCoverageGap
+	return last == nil || !last.Start.Before(gap.End) || !gap.Start.Before(last.End)
+}
+
+// RecordCoverageGapAlert updates the state to record the coverage gap that was alerted about.
+// A gap overlapping the recorded one extends it, so the gap is still recognised as it moves
+// along with the lookahead window.
+func (m *Manager) RecordCoverageGapAlert(state *State, gap ShiftWindow) {
+	if last := state.LastCoverageGap; last != nil && !m.ShouldSendCoverageGapAlert(state, gap) {
+		if last.Start.Before(gap.Start) {
+			gap.Start = last.Start
+		}
+		if last.End.After(gap.End) {
+			gap.End = last.End
+		}
+	}
+	state.LastCoverageGap = &gap
+}
+
+// UnalertedOnCalls returns the on-call assignments in current that have not been alerted
+// about yet. Alerts for assignments that are no longer current are forgotten.
+func (m *Manager) UnalertedOnCalls(state *State, current []OnCallRecord) []OnCallRecord {
+	var alerted, pending []OnCallRecord
+	for _, record := range current {
+		switch {
+		case containsOnCall(state.AlertedOnCalls, record):
+			if !containsOnCall(alerted, record) {
+				alerted = append(alerted, record)
+			}
+		case !containsOnCall(pending, record):
+			pending = append(pending, record)
+		}
+	}
+	state.AlertedOnCalls = alerted
+	return pending
+}
+
+// RecordOnCallAlerted updates the state to record that an on-call assignment was alerted about
+func (m *Manager) RecordOnCallAlerted(state *State, record OnCallRecord) {
+	state.AlertedOnCalls = append(state.AlertedOnCalls, record)
+}
+
+// containsOnCall checks if records contains an assignment for the same user and start time
+func containsOnCall(records []OnCallRecord, record OnCallRecord) bool {
+	for _, r := range records {
+		if r.UserID == record.UserID && r.Start.Equal(record.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShiftChanges compares the user's shifts, as seen now, against the last snapshot and returns
+// the shifts that were added, removed, or moved (an added shift overlapping a removed one).
+// Only the part of each shift between now and the end of the last snapshot is compared, so
+// shifts that have ended and new shifts beyond the previous horizon are not changes. Nothing
+// is reported before a first snapshot has been recorded.
+func (m *Manager) ShiftChanges(state *State, now time.Time, shifts []ShiftWindow) []ShiftChange {
+	snapshot := state.ShiftSnapshot
+	if snapshot == nil {
+		return nil
+	}
+	added := unmatchedWindows(shifts, snapshot.Shifts, now, snapshot.Until)
+	removed := unmatchedWindows(snapshot.Shifts, shifts, now, snapshot.Until)
+
+	var changes []ShiftChange
+	for _, r := range removed {
+		change := ShiftChange{Removed: &r}
+		for i, a := range added {
+			if a.Start.Before(r.End) && r.Start.Before(a.End) {
+				change.Added = &a
+				added = append(added[:i], added[i+1:]...)
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, a := range added {
+		changes = append(changes, ShiftChange{Added: &a})
+	}
+	return changes
+}
+
+// RecordShiftSnapshot updates the state with the user's shifts up to until
+func (m *Manager) RecordShiftSnapshot(state *State, until time.Time, shifts []ShiftWindow) {
+	state.ShiftSnapshot = &ShiftSnapshot{Until: until, Shifts: shifts}
+}
+
+// unmatchedWindows returns the windows whose part between since and until is not in others
+func unmatchedWindows(windows, others []ShiftWindow, since, until time.Time) []ShiftWindow {
+	var unmatched []ShiftWindow
+	for _, w := range windows {
+		clipped, ok := clipWindow(w, since, until)
+		if !ok {
+			continue
+		}
+		matched := false
+		for _, other := range others {
+			if o, ok := clipWindow(other, since, until); ok && o.Start.Equal(clipped.Start) && o.End.Equal(clipped.End) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, w)
+		}
+	}
+	return unmatched
+}
+
+// clipWindow returns the part of the window between since and until, if there is any
+func clipWindow(w ShiftWindow, since, until time.Time) (ShiftWindow, bool) {
+	if w.Start.Before(since) {
+		w.Start = since
+	}
+	if w.End.After(until) {
+		w.End = until
+	}
+	return w, w.Start.Before(w.End)
 }
@@ -1,8 +1,19 @@
 package state
 
 import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -64,6 +75,414 @@ func TestSaveAndLoadRoundTrip(t *testing.T) {
 	}
 }
 
+func TestSaveReplacesFileAtomically(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	manager := NewManager(statePath)
+
+	for _, onCall := range []bool{true, false} {
+		if err := manager.Save(&State{WasOnCall: onCall}); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "state.json" {
+		t.Fatalf("expected only state.json to remain, got %v", entries)
+	}
+	info, err := os.Stat(statePath)
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Fatalf("expected state file mode 0644, got %o", perm)
+	}
+	loaded, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.WasOnCall {
+		t.Fatalf("expected the latest saved state to be loaded")
+	}
+}
+
+func TestLockFileRejectsSecondHolder(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	lock, err := LockFile(statePath)
+	if err != nil {
+		t.Fatalf("LockFile returned error: %v", err)
+	}
+	if _, err := LockFile(statePath); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while the lock is held, got %v", err)
+	}
+
+	if err := lock.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	relocked, err := LockFile(statePath)
+	if err != nil {
+		t.Fatalf("expected the lock to be free after Close, got %v", err)
+	}
+	relocked.Close()
+}
+
+func TestEncryptedStore(t *testing.T) {
+	newCipher := func(fill byte) cipher.AEAD {
+		aead, err := NewStateCipher(bytes.Repeat([]byte{fill}, 32))
+		if err != nil {
+			t.Fatalf("NewStateCipher returned error: %v", err)
+		}
+		return aead
+	}
+	inner := &MemoryStore{}
+
+	// State saved before encryption was enabled is still readable
+	if err := NewManagerWithStore(inner).Save(&State{WasOnCall: true}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	manager := NewManagerWithStore(NewEncryptedStore(inner, newCipher(1)))
+	loaded, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error for plain text state: %v", err)
+	}
+	if !loaded.WasOnCall {
+		t.Fatalf("expected plain text state to be loaded")
+	}
+
+	if err := manager.Save(loaded); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	raw, _ := inner.Load()
+	if bytes.Contains(raw, []byte("was_on_call")) {
+		t.Fatalf("expected saved state to be encrypted, got %s", raw)
+	}
+	loaded, err = manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !loaded.WasOnCall {
+		t.Fatalf("expected WasOnCall to survive encryption")
+	}
+
+	if _, err := NewManagerWithStore(NewEncryptedStore(inner, newCipher(2))).Load(); err == nil {
+		t.Fatalf("expected an error loading state with the wrong key")
+	}
+}
+
+func TestManagerWithStore(t *testing.T) {
+	store := &MemoryStore{}
+	manager := NewManagerWithStore(store)
+
+	loaded, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.WasOnCall {
+		t.Fatalf("expected the default state from an empty store")
+	}
+
+	if err := manager.Update(func(state *State) error {
+		state.WasOnCall = true
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	loaded, err = NewManagerWithStore(store).Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !loaded.WasOnCall {
+		t.Fatalf("expected WasOnCall to persist in the store")
+	}
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+			w.Write(data)
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	client, err := OpenS3(context.Background(), S3Options{Region: "us-east-1", Endpoint: server.URL, PathStyle: true})
+	if err != nil {
+		t.Fatalf("OpenS3 returned error: %v", err)
+	}
+	manager := NewManagerWithStore(NewS3Store(client, "notifier", "state.json"))
+
+	state, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error for a missing object: %v", err)
+	}
+	if state.WasOnCall {
+		t.Fatalf("expected default state to be off-call")
+	}
+
+	state.WasOnCall = true
+	if err := manager.Save(state); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	if _, ok := objects["/notifier/state.json"]; !ok {
+		t.Fatalf("expected state object at /notifier/state.json, got %v", objects)
+	}
+
+	loaded, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !loaded.WasOnCall {
+		t.Fatalf("expected WasOnCall to persist in the bucket")
+	}
+}
+
+func TestS3StoreLock(t *testing.T) {
+	type object struct {
+		data []byte
+		etag string
+	}
+	var mu sync.Mutex
+	objects := map[string]object{}
+	version := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		current, exists := objects[r.URL.Path]
+		if (r.Header.Get("If-None-Match") == "*" && exists) ||
+			(r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != current.etag)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			io.WriteString(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+			w.Header().Set("ETag", current.etag)
+			w.Write(current.data)
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			version++
+			etag := fmt.Sprintf(`"%d"`, version)
+			objects[r.URL.Path] = object{data: data, etag: etag}
+			w.Header().Set("ETag", etag)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	client, err := OpenS3(context.Background(), S3Options{Region: "us-east-1", Endpoint: server.URL, PathStyle: true})
+	if err != nil {
+		t.Fatalf("OpenS3 returned error: %v", err)
+	}
+	store := NewS3Store(client, "notifier", "state.json")
+	locked := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		_, ok := objects["/notifier/state.json.lock"]
+		return ok
+	}
+	lockWait, s3LockLease = 100*time.Millisecond, 150*time.Millisecond
+	t.Cleanup(func() { lockWait, s3LockLease = 5*time.Minute, time.Minute })
+
+	unlock, err := store.Lock()
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+	if _, err := store.Lock(); err == nil {
+		t.Fatalf("expected a second Lock to fail while the state is locked")
+	}
+	// The holder renews its lease, so an update outlasting it keeps the lock
+	time.Sleep(3 * s3LockLease)
+	if _, err := store.Lock(); err == nil {
+		t.Fatalf("expected Lock to fail while the holder renews its lease")
+	}
+	unlock()
+	if locked() {
+		t.Fatalf("expected unlock to delete the lock object")
+	}
+
+	// A lock whose lease expired is taken over
+	mu.Lock()
+	objects["/notifier/state.json.lock"] = object{data: []byte(time.Now().Add(-time.Second).Format(time.RFC3339Nano)), etag: `"stale"`}
+	mu.Unlock()
+	unlock, err = store.Lock()
+	if err != nil {
+		t.Fatalf("Lock returned error for an expired lock: %v", err)
+	}
+	unlock()
+
+	manager := NewManagerWithStore(store)
+	if err := manager.Update(func(state *State) error {
+		state.WasOnCall = true
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if locked() {
+		t.Fatalf("expected Update to release the lock object")
+	}
+}
+
+// closeCountingDriver is a database/sql driver whose connections count how often they are closed
+type closeCountingDriver struct{ closed *int }
+
+func (d closeCountingDriver) Open(string) (driver.Conn, error) { return closeCountingConn(d), nil }
+
+type closeCountingConn closeCountingDriver
+
+func (c closeCountingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (c closeCountingConn) Begin() (driver.Tx, error)           { return nil, errors.ErrUnsupported }
+func (c closeCountingConn) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestDiscardConnClosesSession(t *testing.T) {
+	closed := 0
+	db := sql.OpenDB(driverConnector{closeCountingDriver{&closed}})
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn returned error: %v", err)
+	}
+	conn.Close()
+	if closed != 0 {
+		t.Fatalf("expected Close to return the connection to the pool")
+	}
+
+	if conn, err = db.Conn(context.Background()); err != nil {
+		t.Fatalf("Conn returned error: %v", err)
+	}
+	discardConn(conn)
+	if closed != 1 {
+		t.Fatalf("expected discardConn to close the session, got %d closes", closed)
+	}
+}
+
+// driverConnector opens connections with a driver without registering it
+type driverConnector struct{ driver driver.Driver }
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c driverConnector) Driver() driver.Driver                        { return c.driver }
+
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("NOTIFIER_TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("NOTIFIER_TEST_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+
+	// Opening twice checks that migrations already applied are skipped
+	for range 2 {
+		db, err := OpenPostgres(ctx, dsn)
+		if err != nil {
+			t.Fatalf("OpenPostgres returned error: %v", err)
+		}
+		db.Close()
+	}
+	db, err := OpenPostgres(ctx, dsn)
+	if err != nil {
+		t.Fatalf("OpenPostgres returned error: %v", err)
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM notifier_schema_migrations`).Scan(&version); err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if version != len(postgresMigrations) {
+		t.Fatalf("expected schema version %d, got %d", len(postgresMigrations), version)
+	}
+
+	prefix := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.ExecContext(ctx, `DELETE FROM notifier_state WHERE key LIKE $1`, prefix+"%")
+	})
+
+	first := NewManagerWithStore(NewPostgresStore(db, prefix+"-first"))
+	second := NewManagerWithStore(NewPostgresStore(db, prefix+"-second"))
+	loaded, err := first.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.WasOnCall {
+		t.Fatalf("expected the default state for a missing row")
+	}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		manager := NewManagerWithStore(NewPostgresStore(db, prefix+"-first"))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if err := manager.Update(func(state *State) error {
+					state.WasOnCall = true
+					state.Pauses = append(state.Pauses, PauseWindow{})
+					return nil
+				}); err != nil {
+					t.Errorf("Update returned error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	loaded, err = first.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !loaded.WasOnCall || len(loaded.Pauses) != 20 {
+		t.Fatalf("expected all 20 updates to persist, got %d pauses", len(loaded.Pauses))
+	}
+	if other, err := second.Load(); err != nil || other.WasOnCall {
+		t.Fatalf("expected a different key to keep its own state, got %+v (%v)", other, err)
+	}
+
+	// A schema from a newer notifier must not be used
+	if _, err := db.ExecContext(ctx, `INSERT INTO notifier_schema_migrations (version) VALUES ($1)`, len(postgresMigrations)+1); err != nil {
+		t.Fatalf("failed to insert schema version: %v", err)
+	}
+	defer db.ExecContext(ctx, `DELETE FROM notifier_schema_migrations WHERE version = $1`, len(postgresMigrations)+1)
+	if newer, err := OpenPostgres(ctx, dsn); err == nil {
+		newer.Close()
+		t.Fatalf("expected OpenPostgres to reject a newer schema version")
+	}
+}
+
 func TestTransitionDetectors(t *testing.T) {
 	manager := NewManager("/tmp/unused")
 
@@ -123,8 +542,9 @@ func TestRecordAdvanceNotificationSent(t *testing.T) {
 	manager := NewManager("/tmp/unused")
 	state := &State{}
 
+	shiftStart := time.Now().UTC().Add(30 * time.Minute)
 	before := time.Now().UTC()
-	manager.RecordAdvanceNotificationSent(state)
+	manager.RecordAdvanceNotificationSent(state, shiftStart)
 	after := time.Now().UTC()
 
 	if state.LastAdvanceNotificationSent == nil {
@@ -135,4 +555,410 @@ func TestRecordAdvanceNotificationSent(t *testing.T) {
 	if recorded.Before(before) || recorded.After(after) {
 		t.Fatalf("expected timestamp between %v and %v, got %v", before, after, recorded)
 	}
+	if state.AdvanceNotificationSentFor == nil || !state.AdvanceNotificationSentFor.Equal(shiftStart) {
+		t.Fatalf("expected shift start %v to be recorded, got %v", shiftStart, state.AdvanceNotificationSentFor)
+	}
+}
+
+func TestShouldSendAdvanceNotificationPerShift(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	advance := 2 * time.Hour
+
+	// Back-to-back shifts a few hours apart each get their own notification
+	first := time.Now().UTC().Add(30 * time.Minute)
+	manager.RecordAdvanceNotificationSent(state, first)
+	if manager.ShouldSendAdvanceNotification(state, first, advance) {
+		t.Fatalf("expected advance notification to be skipped for the shift it was sent for")
+	}
+
+	second := time.Now().UTC().Add(90 * time.Minute)
+	if !manager.ShouldSendAdvanceNotification(state, second, advance) {
+		t.Fatalf("expected advance notification for a different shift within 24 hours")
+	}
+}
+
+func TestShouldSendAdvanceNotificationPerAdvanceTime(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	shiftStart := time.Now().UTC().Add(90 * time.Minute)
+
+	// Sent in the 24h window, so the 2h window still gets its own reminder
+	sent := shiftStart.Add(-20 * time.Hour)
+	state.LastAdvanceNotificationSent = &sent
+	state.AdvanceNotificationSentFor = &shiftStart
+	if !manager.ShouldSendAdvanceNotification(state, shiftStart, 24*time.Hour, 2*time.Hour) {
+		t.Fatalf("expected an advance notification for the 2h window")
+	}
+
+	manager.RecordAdvanceNotificationSent(state, shiftStart)
+	if manager.ShouldSendAdvanceNotification(state, shiftStart, 24*time.Hour, 2*time.Hour) {
+		t.Fatalf("expected the 2h window to send a single advance notification")
+	}
+
+	// Sent in the 2h window, so the 15m window still gets its own reminder
+	soon := time.Now().UTC().Add(10 * time.Minute)
+	sent = soon.Add(-time.Hour)
+	state.LastAdvanceNotificationSent = &sent
+	state.AdvanceNotificationSentFor = &soon
+	if !manager.ShouldSendAdvanceNotification(state, soon, 24*time.Hour, 2*time.Hour, 15*time.Minute) {
+		t.Fatalf("expected an advance notification for the 15m window")
+	}
+}
+
+func TestRecordNotificationSentPrunesHistory(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	start := time.Now().UTC()
+
+	for i := range MaxNotificationHistory + 5 {
+		manager.RecordNotificationSent(state, "ntfy", "shift_started", start.Add(time.Duration(i)*time.Hour))
+	}
+
+	if len(state.NotificationHistory) != MaxNotificationHistory {
+		t.Fatalf("expected %d history entries, got %d", MaxNotificationHistory, len(state.NotificationHistory))
+	}
+	oldest := state.NotificationHistory[0]
+	if !oldest.ShiftStartTime.Equal(start.Add(5 * time.Hour)) {
+		t.Fatalf("expected the oldest entries to be pruned, got oldest shift %v", oldest.ShiftStartTime)
+	}
+	newest := state.NotificationHistory[len(state.NotificationHistory)-1]
+	if state.LastNotification == nil || *state.LastNotification != newest || newest.Backend != "ntfy" {
+		t.Fatalf("expected last notification %+v to match the newest history entry %+v", state.LastNotification, newest)
+	}
+}
+
+func TestShouldSendShiftEndingNotificationOncePerShift(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	reminder := time.Hour
+
+	if manager.ShouldSendShiftEndingNotification(state, time.Now().UTC().Add(2*time.Hour), reminder) {
+		t.Fatalf("expected shift ending notification to be skipped outside window")
+	}
+
+	shiftEnd := time.Now().UTC().Add(30 * time.Minute)
+	if !manager.ShouldSendShiftEndingNotification(state, shiftEnd, reminder) {
+		t.Fatalf("expected shift ending notification to be sent within window")
+	}
+	manager.RecordShiftEndingNotificationSent(state, shiftEnd)
+	if manager.ShouldSendShiftEndingNotification(state, shiftEnd, reminder) {
+		t.Fatalf("expected shift ending notification to be skipped once sent for this shift")
+	}
+
+	// An extended shift gets a new reminder once its new end is near
+	if !manager.ShouldSendShiftEndingNotification(state, shiftEnd.Add(15*time.Minute), reminder) {
+		t.Fatalf("expected shift ending notification to be sent for a new shift end")
+	}
+}
+
+func TestShouldSendDailySummaryOncePerDay(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	grace := time.Hour
+
+	if manager.ShouldSendDailySummary(state, time.Now().UTC().Add(time.Minute), grace) {
+		t.Fatalf("expected daily summary to be skipped before it is due")
+	}
+	if manager.ShouldSendDailySummary(state, time.Now().UTC().Add(-2*time.Hour), grace) {
+		t.Fatalf("expected daily summary to be skipped when more than the grace period late")
+	}
+
+	// Yesterday's summary does not stop today's
+	due := time.Now().UTC().Add(-10 * time.Minute)
+	manager.RecordDailySummarySent(state, due.Add(-24*time.Hour))
+	if !manager.ShouldSendDailySummary(state, due, grace) {
+		t.Fatalf("expected daily summary to be sent once due")
+	}
+	manager.RecordDailySummarySent(state, due)
+	if manager.ShouldSendDailySummary(state, due, grace) {
+		t.Fatalf("expected daily summary to be skipped once sent")
+	}
+}
+
+func TestMuteAndUnmute(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+
+	if manager.IsMuted(state) {
+		t.Fatalf("expected default state to be unmuted")
+	}
+
+	manager.Mute(state, time.Hour)
+	if !manager.IsMuted(state) {
+		t.Fatalf("expected state to be muted")
+	}
+
+	manager.Unmute(state)
+	if manager.IsMuted(state) {
+		t.Fatalf("expected state to be unmuted")
+	}
+
+	expired := time.Now().UTC().Add(-time.Minute)
+	state.MutedUntil = &expired
+	if manager.IsMuted(state) {
+		t.Fatalf("expected expired mute to be ignored")
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+	now := time.Now().UTC()
+
+	// A pause that has already ended is dropped when another is added
+	state.Pauses = []PauseWindow{{From: now.Add(-48 * time.Hour), Until: now.Add(-24 * time.Hour)}}
+	manager.Pause(state, now.Add(24*time.Hour), now.Add(72*time.Hour))
+	if len(state.Pauses) != 1 {
+		t.Fatalf("expected the ended pause to be dropped, got %v", state.Pauses)
+	}
+	if manager.ActivePause(state) != nil {
+		t.Fatalf("expected a pause that has not started to be inactive")
+	}
+
+	manager.Pause(state, now.Add(-time.Hour), now.Add(time.Hour))
+	if pause := manager.ActivePause(state); pause == nil || !pause.Until.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected the current pause to be active, got %v", pause)
+	}
+
+	manager.Resume(state)
+	if manager.ActivePause(state) != nil || len(state.Pauses) != 0 {
+		t.Fatalf("expected resume to clear every pause, got %v", state.Pauses)
+	}
+}
+
+func TestUpdatePersistsChanges(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	manager := NewManager(statePath)
+
+	if err := manager.Update(func(state *State) error {
+		manager.Mute(state, time.Hour)
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	loaded, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !manager.IsMuted(loaded) {
+		t.Fatalf("expected mute to persist")
+	}
+}
+
+func TestUpdateIsNotLostAcrossManagers(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	// Each manager stands in for a separate process, such as the notifier and a mute subcommand
+	var wg sync.WaitGroup
+	for range 2 {
+		manager := NewManager(statePath)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := manager.Update(func(state *State) error {
+					state.Pauses = append(state.Pauses, PauseWindow{})
+					return nil
+				}); err != nil {
+					t.Errorf("Update returned error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	loaded, err := NewManager(statePath).Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(loaded.Pauses) != 40 {
+		t.Fatalf("expected all 40 updates to persist, got %d", len(loaded.Pauses))
+	}
+}
+
+func TestShouldScheduleAdvanceNotification(t *testing.T) {
+	manager := NewManager("/tmp/unused")
+	state := &State{}
+
+	shiftStart := time.Now().UTC().Add(5 * time.Hour)
+	advance := 2 * time.Hour
+
+	if !manager.ShouldScheduleAdvanceNotification(state, shiftStart, advance, 72*time.Hour) {
+		t.Fatalf("expected advance notification to be scheduled")
+	}
+	if manager.ShouldScheduleAdvanceNotification(state, shiftStart, advance, time.Hour) {
+		t.Fatalf("expected scheduling to wait until delivery is within the max delay")
+	}
+
+	deliveries := []ScheduledDelivery{{ID: "past", DeliverAt: time.Now().Add(-time.Minute)}, {ID: "ahead", DeliverAt: shiftStart.Add(-advance)}}
+	manager.RecordAdvanceNotificationScheduled(state, shiftStart, deliveries)
+	if manager.ShouldScheduleAdvanceNotification(state, shiftStart, advance, 72*time.Hour) {
+		t.Fatalf("expected advance notification not to be scheduled twice for the same shift")
+	}
+	if !manager.IsAdvanceNotificationScheduled(state, shiftStart) {
+		t.Fatalf("expected shift to be recorded as scheduled")
+	}
+
+	// A moved shift needs a new reminder
+	if !manager.ShouldScheduleAdvanceNotification(state, shiftStart.Add(time.Hour), advance, 72*time.Hour) {
+		t.Fatalf("expected a moved shift to be scheduled again")
+	}
+
+	// Clearing the schedule hands back the deliveries that can still be cancelled
+	pending := manager.ClearAdvanceNotificationSchedule(state)
+	if len(pending) != 1 || pending[0].ID != "ahead" {
+		t.Fatalf("expected only the delivery still ahead, got %+v", pending)
+	}
+	if !manager.ShouldScheduleAdvanceNotification(state, shiftStart, advance, 72*time.Hour) {
+		t.Fatalf("expected the shift to be scheduled again after clearing")
+	}
+}
+
+func TestRecordShiftStartedAndEnded(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{}
+
+	if start, _ := manager.RecordShiftEnded(state); !start.IsZero() {
+		t.Fatalf("expected zero start for unknown shift, got %v", start)
+	}
+
+	shiftStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	manager.RecordShiftStarted(state, shiftStart, "PSCHED1")
+	if state.CurrentShiftStart == nil || !state.CurrentShiftStart.Equal(shiftStart) {
+		t.Fatalf("expected current shift start %v, got %v", shiftStart, state.CurrentShiftStart)
+	}
+
+	if start, scheduleID := manager.RecordShiftEnded(state); !start.Equal(shiftStart) || scheduleID != "PSCHED1" {
+		t.Fatalf("expected recorded start %v on PSCHED1, got %v on %q", shiftStart, start, scheduleID)
+	}
+	if state.CurrentShiftStart != nil || state.CurrentShiftScheduleID != "" {
+		t.Fatalf("expected current shift to be cleared")
+	}
+}
+
+func TestTrackUpcomingShiftDetectsRemovedShift(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{}
+
+	shift := &ShiftWindow{Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(32 * time.Hour)}
+	if removed := manager.TrackUpcomingShift(state, shift); removed != nil {
+		t.Fatalf("expected nothing removed on first sight, got %+v", removed)
+	}
+	if removed := manager.TrackUpcomingShift(state, &ShiftWindow{Start: shift.Start, End: shift.End}); removed != nil {
+		t.Fatalf("expected unchanged shift to be kept, got %+v", removed)
+	}
+
+	// A new earlier shift does not hide the known one
+	earlier := &ShiftWindow{Start: time.Now().Add(time.Hour), End: time.Now().Add(2 * time.Hour)}
+	if removed := manager.TrackUpcomingShift(state, earlier); removed != nil {
+		t.Fatalf("expected earlier shift not to count as removal, got %+v", removed)
+	}
+
+	later := &ShiftWindow{Start: shift.Start.Add(7 * 24 * time.Hour), End: shift.End.Add(7 * 24 * time.Hour)}
+	removed := manager.TrackUpcomingShift(state, later)
+	if removed == nil || !removed.Start.Equal(shift.Start) {
+		t.Fatalf("expected shift starting %v to be reported as removed, got %+v", shift.Start, removed)
+	}
+	if state.KnownUpcomingShift != later {
+		t.Fatalf("expected later shift to be tracked next")
+	}
+}
+
+func TestTrackUpcomingShiftIgnoresStartedShift(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{KnownUpcomingShift: &ShiftWindow{Start: time.Now().Add(-time.Minute), End: time.Now().Add(time.Hour)}}
+
+	if removed := manager.TrackUpcomingShift(state, nil); removed != nil {
+		t.Fatalf("expected started shift not to be reported, got %+v", removed)
+	}
+	if state.KnownUpcomingShift != nil {
+		t.Fatalf("expected known shift to be cleared")
+	}
+}
+
+func TestCoverageGapAlertSentOncePerGap(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{}
+	gap := ShiftWindow{Start: time.Now().Add(time.Hour), End: time.Now().Add(3 * time.Hour)}
+
+	if !manager.ShouldSendCoverageGapAlert(state, gap) {
+		t.Fatalf("expected alert for a new gap")
+	}
+	manager.RecordCoverageGapAlert(state, gap)
+	if manager.ShouldSendCoverageGapAlert(state, gap) {
+		t.Fatalf("expected no repeat alert for the same gap")
+	}
+	// The lookahead window moves on, so the same gap comes back with later bounds
+	moved := ShiftWindow{Start: gap.Start.Add(time.Minute), End: gap.End.Add(time.Hour)}
+	if manager.ShouldSendCoverageGapAlert(state, moved) {
+		t.Fatalf("expected no repeat alert for an overlapping gap")
+	}
+	manager.RecordCoverageGapAlert(state, moved)
+	if !state.LastCoverageGap.Start.Equal(gap.Start) || !state.LastCoverageGap.End.Equal(moved.End) {
+		t.Fatalf("expected the recorded gap to be extended, got %+v", state.LastCoverageGap)
+	}
+	if !manager.ShouldSendCoverageGapAlert(state, ShiftWindow{Start: moved.End.Add(time.Hour), End: moved.End.Add(2 * time.Hour)}) {
+		t.Fatalf("expected alert for a separate gap")
+	}
+}
+
+func TestUnalertedOnCalls(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{}
+	start := time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+	alice := OnCallRecord{UserID: "PALICE", Start: start}
+	bob := OnCallRecord{UserID: "PBOB", Start: start}
+
+	pending := manager.UnalertedOnCalls(state, []OnCallRecord{alice, alice, bob})
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending assignments, got %+v", pending)
+	}
+	manager.RecordOnCallAlerted(state, alice)
+
+	pending = manager.UnalertedOnCalls(state, []OnCallRecord{{UserID: "PALICE", Start: start.In(time.FixedZone("CET", 3600))}, bob})
+	if len(pending) != 1 || pending[0].UserID != "PBOB" {
+		t.Fatalf("expected only PBOB pending, got %+v", pending)
+	}
+
+	// Alerts are forgotten once the assignment is no longer current
+	manager.UnalertedOnCalls(state, nil)
+	if len(state.AlertedOnCalls) != 0 {
+		t.Fatalf("expected alerted assignments to be pruned, got %+v", state.AlertedOnCalls)
+	}
+}
+
+func TestShiftChanges(t *testing.T) {
+	manager := NewManager(filepath.Join(t.TempDir(), "state.json"))
+	state := &State{}
+	now := time.Date(2030, time.March, 4, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return now.Add(time.Duration(hours) * time.Hour) }
+	window := func(from, to int) ShiftWindow { return ShiftWindow{Start: at(from), End: at(to)} }
+
+	shifts := []ShiftWindow{window(-3, 5), window(24, 32), window(48, 56), window(100, 120)}
+	if changes := manager.ShiftChanges(state, now, shifts); changes != nil {
+		t.Fatalf("expected no changes without a snapshot, got %+v", changes)
+	}
+	manager.RecordShiftSnapshot(state, at(110), shifts)
+
+	// An hour later: the shift in progress is unchanged, one shift moved, one was removed,
+	// one was added, and one appeared beyond the previous horizon
+	later := at(1)
+	current := []ShiftWindow{window(-3, 5), window(26, 34), window(72, 80), window(100, 120), window(130, 138)}
+	changes := manager.ShiftChanges(state, later, current)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	moved, removed, added := changes[0], changes[1], changes[2]
+	if moved.Removed == nil || moved.Added == nil || !moved.Removed.Start.Equal(at(24)) || !moved.Added.Start.Equal(at(26)) {
+		t.Fatalf("expected the shift at +24h to move to +26h, got %+v", moved)
+	}
+	if removed.Added != nil || removed.Removed == nil || !removed.Removed.Start.Equal(at(48)) {
+		t.Fatalf("expected the shift at +48h to be removed, got %+v", removed)
+	}
+	if added.Removed != nil || added.Added == nil || !added.Added.Start.Equal(at(72)) {
+		t.Fatalf("expected a shift at +72h to be added, got %+v", added)
+	}
 }
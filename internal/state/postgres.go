@@ -0,0 +1,168 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	// Registers the "postgres" database/sql driver
+	_ "github.com/lib/pq"
+)
+
+// postgresTimeout bounds each state query so a slow database cannot stall a check
+const postgresTimeout = 10 * time.Second
+
+// postgresMigrationLock is the advisory lock key held while migrating, so that several
+// notifiers starting at once do not apply the same migration twice
+const postgresMigrationLock = 7340021
+
+// postgresStateLock is the first key of the advisory lock held on a state key during an
+// update; the second key is derived from the state key
+const postgresStateLock = 7340022
+
+// postgresMigrations are applied in order; each entry is one schema version. Existing
+// entries must never be changed, only new ones appended.
+var postgresMigrations = []string{
+	`CREATE TABLE notifier_state (
+		key        TEXT PRIMARY KEY,
+		state      JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+}
+
+// OpenPostgres connects to the database at dsn and brings the state schema up to date
+func OpenPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	if err := migratePostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migratePostgres applies the migrations that have not been applied yet, recording each
+// applied version in notifier_schema_migrations
+func migratePostgres(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start state schema migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to lock state schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS notifier_schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create state schema migrations table: %w", err)
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM notifier_schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read state schema version: %w", err)
+	}
+	if current > len(postgresMigrations) {
+		return fmt.Errorf("state schema version %d is newer than this notifier supports (%d)", current, len(postgresMigrations))
+	}
+	for version := current + 1; version <= len(postgresMigrations); version++ {
+		if _, err := tx.ExecContext(ctx, postgresMigrations[version-1]); err != nil {
+			return fmt.Errorf("failed to apply state schema migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO notifier_schema_migrations (version) VALUES ($1)`, version); err != nil {
+			return fmt.Errorf("failed to record state schema migration %d: %w", version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit state schema migration: %w", err)
+	}
+	return nil
+}
+
+// PostgresStore keeps one user's state in a row of the notifier_state table
+type PostgresStore struct {
+	db  *sql.DB
+	key string
+}
+
+// NewPostgresStore creates a store for the state saved under key; the schema must already
+// be up to date (see OpenPostgres)
+func NewPostgresStore(db *sql.DB, key string) *PostgresStore {
+	return &PostgresStore{db: db, key: key}
+}
+
+// Load implements Store
+func (s *PostgresStore) Load() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT state FROM notifier_state WHERE key = $1`, s.key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s from database: %w", s.key, err)
+	}
+	return data, nil
+}
+
+// Save implements Store
+func (s *PostgresStore) Save(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO notifier_state (key, state, updated_at) VALUES ($1, $2, now())
+		ON CONFLICT (key) DO UPDATE SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`, s.key, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write state %s to database: %w", s.key, err)
+	}
+	return nil
+}
+
+// Lock implements LockingStore with a session advisory lock on the state key, held on a
+// connection of its own until unlock is called or the connection is lost
+func (s *PostgresStore) Lock() (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockWait)
+	defer cancel()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock state %s in database: %w", s.key, err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1, hashtext($2))`, postgresStateLock, s.key); err != nil {
+		// The lock may have been granted as the wait timed out
+		discardConn(conn)
+		return nil, fmt.Errorf("failed to lock state %s in database: %w", s.key, err)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+		defer cancel()
+		var unlocked bool
+		err := conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1, hashtext($2))`, postgresStateLock, s.key).Scan(&unlocked)
+		if err != nil || !unlocked {
+			slog.Warn("Failed to unlock state in database, closing the connection holding the lock", "key", s.key, "error", err)
+			discardConn(conn)
+			return
+		}
+		conn.Close()
+	}, nil
+}
+
+// discardConn closes conn's session instead of returning it to the pool, which releases the
+// session's advisory locks. (*sql.Conn).Close alone would keep the session open in the pool.
+func discardConn(conn *sql.Conn) {
+	// database/sql closes a connection whose Raw function reports it as bad
+	conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+}
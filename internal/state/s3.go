@@ -0,0 +1,249 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// s3Timeout bounds each state request so a slow object store cannot stall a check
+const s3Timeout = 10 * time.Second
+
+// s3LockLease is how long a lock object is honoured, so that a process that exits during an
+// update does not keep the state locked. The holder renews the lease while it is alive.
+var s3LockLease = time.Minute
+
+// s3LockRetry is how often a held lock object is checked again while waiting for it
+const s3LockRetry = 250 * time.Millisecond
+
+// S3Options selects the bucket state is kept in
+type S3Options struct {
+	Bucket string
+	// Region overrides the region from the AWS environment and shared config
+	Region string
+	// Endpoint points the client at an S3-compatible service such as MinIO or R2
+	Endpoint string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key
+	PathStyle bool
+}
+
+// OpenS3 creates an S3 client for opts. Credentials come from the default AWS chain:
+// environment variables, shared config and credentials files, web identity and the
+// container or instance role.
+func OpenS3(ctx context.Context, opts S3Options) (*s3.Client, error) {
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(opts.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+		o.UsePathStyle = opts.PathStyle
+	}), nil
+}
+
+// S3Store keeps one user's state in an object of an S3 bucket
+type S3Store struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+// NewS3Store creates a store for the state saved in the object key of bucket
+func NewS3Store(client *s3.Client, bucket, key string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, key: key}
+}
+
+// Load implements Store
+func (s *S3Store) Load() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if isS3NotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return data, nil
+}
+
+// Save implements Store
+func (s *S3Store) Save(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write state s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return nil
+}
+
+// Lock implements LockingStore. S3 has no locks, so a lock object is created next to the state
+// with a conditional write that fails if it already exists. The object records when its lease
+// expires, and is rewritten with a later expiry while the lock is held however long the update
+// takes; an expired one is replaced with a write conditional on its ETag.
+func (s *S3Store) Lock() (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockWait+s3Timeout)
+	defer cancel()
+
+	lockKey := s.key + ".lock"
+	deadline := time.Now().Add(lockWait)
+	for {
+		lease := s3Lease()
+		out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(lockKey),
+			Body:        bytes.NewReader(lease),
+			IfNoneMatch: aws.String("*"),
+		})
+		if err == nil {
+			return s.hold(lockKey, out.ETag), nil
+		}
+		if !isS3PreconditionFailed(err) {
+			return nil, fmt.Errorf("failed to lock state s3://%s/%s: %w", s.bucket, s.key, err)
+		}
+
+		// Take over the lock if its holder let the lease expire
+		held, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(lockKey),
+		})
+		if err != nil && !isS3NotFound(err) {
+			return nil, fmt.Errorf("failed to lock state s3://%s/%s: %w", s.bucket, s.key, err)
+		}
+		if err == nil {
+			data, _ := io.ReadAll(held.Body)
+			held.Body.Close()
+			if expires, perr := time.Parse(time.RFC3339Nano, string(data)); perr != nil || time.Now().After(expires) {
+				out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+					Bucket:  aws.String(s.bucket),
+					Key:     aws.String(lockKey),
+					Body:    bytes.NewReader(lease),
+					IfMatch: held.ETag,
+				})
+				if err == nil {
+					return s.hold(lockKey, out.ETag), nil
+				}
+				if !isS3PreconditionFailed(err) {
+					return nil, fmt.Errorf("failed to lock state s3://%s/%s: %w", s.bucket, s.key, err)
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock state s3://%s/%s: locked by another process", s.bucket, s.key)
+		}
+		time.Sleep(s3LockRetry)
+	}
+}
+
+// s3Lease returns the content of a lock object whose lease starts now
+func s3Lease() []byte {
+	return []byte(time.Now().UTC().Add(s3LockLease).Format(time.RFC3339Nano))
+}
+
+// hold renews the lease of the lock object written with etag until the returned function is
+// called, which deletes the object unless another process has taken it over
+func (s *S3Store) hold(lockKey string, etag *string) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s3LockLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+			out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:  aws.String(s.bucket),
+				Key:     aws.String(lockKey),
+				Body:    bytes.NewReader(s3Lease()),
+				IfMatch: etag,
+			})
+			cancel()
+			if isS3PreconditionFailed(err) {
+				slog.Warn("State lock was taken over by another process", "bucket", s.bucket, "key", s.key)
+				return
+			}
+			if err != nil {
+				// Retried on the next tick, which is well within the lease
+				slog.Warn("Failed to renew state lock", "bucket", s.bucket, "key", s.key, "error", err)
+				continue
+			}
+			etag = out.ETag
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+		ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+		defer cancel()
+		s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:  aws.String(s.bucket),
+			Key:     aws.String(lockKey),
+			IfMatch: etag,
+		})
+	}
+}
+
+// isS3PreconditionFailed reports whether err means a conditional write lost to another one
+func isS3PreconditionFailed(err error) bool {
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	status := respErr.HTTPStatusCode()
+	return status == http.StatusPreconditionFailed || status == http.StatusConflict
+}
+
+// isS3NotFound reports whether err means the state object does not exist yet. Some
+// S3-compatible services answer a bare 404 instead of a NoSuchKey error.
+func isS3NotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey" {
+		return true
+	}
+	var respErr *smithyhttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// lockWait bounds how long the S3 and PostgreSQL stores wait for another process to release
+// the state. A
+// running notifier holds the lock for a whole check, including the notifications it sends,
+// which can each take up to a backend's timeout, so commands such as mute wait long enough for
+// a slow check to finish.
+var lockWait = 5 * time.Minute
+
+// Store persists the JSON-encoded state of one monitored user
+type Store interface {
+	// Load returns the saved state, or nil if none has been saved yet
+	Load() ([]byte, error)
+	// Save replaces the saved state
+	Save(data []byte) error
+}
+
+// LockingStore is a Store that can keep other processes from changing the state between the
+// load and the save of an update, such as the mute subcommand racing a running notifier
+type LockingStore interface {
+	Store
+	// Lock waits for exclusive access to the state until unlock is called
+	Lock() (unlock func(), err error)
+}
+
+// MemoryStore keeps the state in memory only, so it is lost when the process exits
+type MemoryStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// Load implements Store
+func (s *MemoryStore) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = slices.Clone(data)
+	return nil
+}
+
+// FileStore keeps the state in a local JSON file
+type FileStore struct {
+	Path string
+}
+
+// Load implements Store
+func (s *FileStore) Load() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return data, nil
+}
+
+// Save implements Store. The state is written to a temporary file in the same directory
+// and renamed over the old one, with both synced to disk, so a crash or power loss leaves
+// either the old or the new state, never a partial file.
+func (s *FileStore) Save(data []byte) error {
+	dir := filepath.Dir(s.Path)
+	// Ensure directory exists
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.Path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	// Clean up the temporary file unless it was renamed into place
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set state file permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.Path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory entry change such as a rename to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open state directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync state directory: %w", err)
+	}
+	return nil
+}
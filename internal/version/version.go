@@ -0,0 +1,60 @@
+// Package version reports which build of the notifier is running
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/a7d-corp/pagerduty-oncall-notifier/internal/version.Version=v1.2.3 \
+//	  -X github.com/a7d-corp/pagerduty-oncall-notifier/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/a7d-corp/pagerduty-oncall-notifier/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+}
+
+// Get returns the build information injected with -ldflags. The commit and date fall back to
+// the VCS details the Go toolchain embeds when building from a git checkout.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+// String formats the build information for --version
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion)
+}
+
+// UserAgent returns the User-Agent sent with outbound HTTP requests
+func UserAgent() string {
+	return "pagerduty-oncall-notifier/" + Version
+}
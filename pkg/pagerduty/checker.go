@@ -0,0 +1,36 @@
+package pagerduty
+
+import (
+	"context"
+	"time"
+)
+
+// OnCallChecker is the part of Client used to poll a user's on-call status. Code that only
+// needs these answers should depend on it, so it can be tested against Mock.
+type OnCallChecker interface {
+	// UserID returns the ID of the monitored user
+	UserID() string
+	// IsOnCall reports whether the user is on call right now
+	IsOnCall(ctx context.Context) (bool, error)
+	// GetCurrentShift returns the shift the user is on call for, or nil if they are not
+	GetCurrentShift(ctx context.Context) (*UpcomingShift, error)
+	// GetUpcomingShift returns the user's next shift within the coming week, or nil if there is none
+	GetUpcomingShift(ctx context.Context) (*UpcomingShift, error)
+	// ListShifts returns the user's shifts that overlap [since, until), earliest first
+	ListShifts(ctx context.Context, since, until time.Time) ([]UpcomingShift, error)
+	// CurrentOnCalls lists everyone on call in the monitored scope right now
+	CurrentOnCalls(ctx context.Context) ([]OnCall, error)
+	// FindCoverageGaps returns the windows within lookahead where nobody is on call
+	FindCoverageGaps(ctx context.Context, lookahead time.Duration) ([]Gap, error)
+	// PreviousResponder returns who was on call for the schedule just before start
+	PreviousResponder(ctx context.Context, scheduleID string, start time.Time) (*Handoff, error)
+	// NextResponder returns who is on call for the schedule once the shift from start to end is over
+	NextResponder(ctx context.Context, scheduleID string, start, end time.Time) (*Handoff, error)
+	// OpenIncidents summarises the open incidents in the monitored scope
+	OpenIncidents(ctx context.Context) (*IncidentSummary, error)
+	// RateLimit returns when PagerDuty last rate limited requests and when it allows them again
+	RateLimit() (limitedAt, retryAt time.Time)
+}
+
+// Client implements OnCallChecker
+var _ OnCallChecker = (*Client)(nil)
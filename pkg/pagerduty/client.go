@@ -0,0 +1,587 @@
+// Package pagerduty answers on-call questions about a single PagerDuty user: whether they
+// are on call, when their shifts start and end, and who hands over to them
+package pagerduty
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// stringPtr returns a pointer to the given string
+func stringPtr(s string) *string {
+	return &s
+}
+
+// RegionAPIURLs maps PagerDuty service regions to their REST API endpoints
+var RegionAPIURLs = map[string]string{
+	"us": "https://api.pagerduty.com",
+	"eu": "https://api.eu.pagerduty.com",
+}
+
+// TeamScheduleRefreshInterval is how often the schedules of a team are re-discovered in team mode
+const TeamScheduleRefreshInterval = time.Hour
+
+// Scope selects which on-call entries count towards the user being on call.
+// Exactly one of ScheduleID, EscalationPolicyID, or TeamID should be set.
+type Scope struct {
+	ScheduleID         string
+	EscalationPolicyID string
+	// TeamID monitors every schedule belonging to the team
+	TeamID string
+}
+
+// Client wraps the PagerDuty API client
+type Client struct {
+	client    *pagerduty.Client
+	transport *rateLimitTransport
+	scope     Scope
+	userID    string
+	maxPages  int
+	// retryMaxElapsed bounds how long a failing call is retried
+	retryMaxElapsed time.Duration
+	// maxEscalationLevel drops on-call entries at deeper escalation levels; zero keeps all
+	maxEscalationLevel uint
+	// scheduleSource and scheduleLayers select which schedule entries count as on call
+	scheduleSource ScheduleSource
+	scheduleLayers []string
+
+	// Team mode caches the discovered schedule IDs between refreshes
+	teamMu          sync.Mutex
+	teamScheduleIDs []string
+	teamRefreshedAt time.Time
+
+	// The next upcoming shift is cached until it starts or shiftCacheTTL passes
+	shiftCacheTTL     time.Duration
+	upcomingMu        sync.Mutex
+	upcomingShift     *UpcomingShift
+	upcomingExpiresAt time.Time
+}
+
+// DefaultMaxPages is the default limit on pages fetched per on-call listing
+const DefaultMaxPages = 10
+
+// DefaultShiftCacheTTL is how long the next upcoming shift is cached by default
+const DefaultShiftCacheTTL = 15 * time.Minute
+
+// onCallPageSize is the number of on-call entries requested per page (the API maximum)
+const onCallPageSize = 100
+
+// ClientOptions holds optional PagerDuty client settings
+type ClientOptions struct {
+	// APIURL overrides the REST API endpoint, e.g. for the EU service region
+	APIURL string
+	// MaxPages limits the pages fetched per on-call listing; defaults to DefaultMaxPages
+	MaxPages int
+	// RetryMaxElapsed bounds how long a failing call is retried; defaults to DefaultRetryMaxElapsed
+	RetryMaxElapsed time.Duration
+	// ShiftCacheTTL caches the next upcoming shift between calls; zero disables caching
+	ShiftCacheTTL time.Duration
+	// MaxEscalationLevel ignores on-call entries deeper than this level (e.g. 1 for primary only); zero keeps all
+	MaxEscalationLevel int
+	// ScheduleSource selects which schedule entries count as on call; defaults to SourceOnCalls
+	ScheduleSource ScheduleSource
+	// ScheduleLayers lists the layer IDs or names used with SourceLayers
+	ScheduleLayers []string
+	// OAuth authenticates with a scoped OAuth app instead of the API token when set
+	OAuth *OAuthCredentials
+	// APITokenFile is read for the API token, and re-read when it changes, instead of using apiToken
+	APITokenFile string
+	// ProxyURL sends requests through an HTTP(S) proxy instead of the one from the environment
+	ProxyURL *url.URL
+	// TLSConfig configures client certificates and trusted CAs, e.g. for a TLS-intercepting proxy
+	TLSConfig *tls.Config
+	// UserAgent replaces the go-pagerduty User-Agent sent with API requests when set
+	UserAgent string
+}
+
+// NewClient creates a new PagerDuty client. apiToken is ignored when opts.OAuth or
+// opts.APITokenFile is set.
+func NewClient(apiToken string, scope Scope, userID string, opts ClientOptions) *Client {
+	var clientOpts []pagerduty.ClientOptions
+	if opts.APIURL != "" {
+		clientOpts = append(clientOpts, pagerduty.WithAPIEndpoint(opts.APIURL))
+	}
+	client := pagerduty.NewClient(apiToken, clientOpts...)
+	var base http.RoundTripper = http.DefaultTransport
+	if opts.ProxyURL != nil || opts.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if opts.ProxyURL != nil {
+			transport.Proxy = http.ProxyURL(opts.ProxyURL)
+		}
+		if opts.TLSConfig != nil {
+			transport.TLSClientConfig = opts.TLSConfig
+		}
+		base = transport
+	}
+	if opts.UserAgent != "" {
+		// Beneath the OAuth transport, so token requests identify the application too
+		base = &userAgentTransport{base: base, userAgent: opts.UserAgent}
+	}
+	switch {
+	case opts.OAuth != nil:
+		base = &oauthTransport{base: base, creds: *opts.OAuth}
+	case opts.APITokenFile != "":
+		base = &tokenFileTransport{base: base, path: opts.APITokenFile}
+	}
+	transport := &rateLimitTransport{base: base}
+	client.HTTPClient = &http.Client{Transport: transport}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+	if opts.RetryMaxElapsed <= 0 {
+		opts.RetryMaxElapsed = DefaultRetryMaxElapsed
+	}
+	return &Client{
+		client:    client,
+		transport: transport,
+		scope:     scope,
+		userID:    userID,
+		maxPages:  opts.MaxPages,
+
+		retryMaxElapsed:    opts.RetryMaxElapsed,
+		shiftCacheTTL:      opts.ShiftCacheTTL,
+		maxEscalationLevel: uint(max(opts.MaxEscalationLevel, 0)),
+		scheduleSource:     opts.ScheduleSource,
+		scheduleLayers:     opts.ScheduleLayers,
+	}
+}
+
+// RateLimit returns when PagerDuty last rate limited this client (zero if never) and when
+// it said requests may resume
+func (c *Client) RateLimit() (limitedAt, retryAt time.Time) {
+	return c.transport.status()
+}
+
+// listOnCalls fetches all pages of on-call entries, up to the configured page limit,
+// dropping entries deeper than the configured escalation level
+func (c *Client) listOnCalls(ctx context.Context, opts pagerduty.ListOnCallOptions) ([]pagerduty.OnCall, error) {
+	var oncalls []pagerduty.OnCall
+	opts.Limit = onCallPageSize
+	for page := 1; ; page++ {
+		response, err := withRetry(ctx, c, func() (*pagerduty.ListOnCallsResponse, error) {
+			return c.client.ListOnCallsWithContext(ctx, opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, oncall := range response.OnCalls {
+			if c.maxEscalationLevel == 0 || oncall.EscalationLevel <= c.maxEscalationLevel {
+				oncalls = append(oncalls, oncall)
+			}
+		}
+		if !response.More {
+			return oncalls, nil
+		}
+		if page >= c.maxPages {
+			slog.Warn("On-call listing truncated; raise PD_MAX_PAGES to fetch more", "pages", page, "entries", len(oncalls))
+			return oncalls, nil
+		}
+		opts.Offset += opts.Limit
+	}
+}
+
+// listOnCallOptions returns on-call listing options restricted to the configured scope
+func (c *Client) listOnCallOptions(ctx context.Context) (pagerduty.ListOnCallOptions, error) {
+	switch {
+	case c.scope.EscalationPolicyID != "":
+		return pagerduty.ListOnCallOptions{EscalationPolicyIDs: []string{c.scope.EscalationPolicyID}}, nil
+	case c.scope.TeamID != "":
+		scheduleIDs, err := c.teamSchedules(ctx)
+		if err != nil {
+			return pagerduty.ListOnCallOptions{}, err
+		}
+		return pagerduty.ListOnCallOptions{ScheduleIDs: scheduleIDs}, nil
+	default:
+		return pagerduty.ListOnCallOptions{ScheduleIDs: []string{c.scope.ScheduleID}}, nil
+	}
+}
+
+// teamSchedules returns the IDs of the team's schedules, re-discovering them once the cache is stale.
+// If a refresh fails, the previously discovered schedules are used.
+func (c *Client) teamSchedules(ctx context.Context) ([]string, error) {
+	c.teamMu.Lock()
+	defer c.teamMu.Unlock()
+
+	if c.teamScheduleIDs != nil && time.Since(c.teamRefreshedAt) < TeamScheduleRefreshInterval {
+		return c.teamScheduleIDs, nil
+	}
+
+	scheduleIDs, err := c.discoverTeamSchedules(ctx)
+	if err != nil {
+		if c.teamScheduleIDs != nil {
+			slog.Warn("Failed to refresh team schedules, using previous list", "team_id", c.scope.TeamID, "error", err)
+			return c.teamScheduleIDs, nil
+		}
+		return nil, err
+	}
+
+	if !slices.Equal(scheduleIDs, c.teamScheduleIDs) {
+		slog.Info("Monitoring team schedules", "team_id", c.scope.TeamID, "schedule_ids", scheduleIDs)
+	}
+	c.teamScheduleIDs = scheduleIDs
+	c.teamRefreshedAt = time.Now()
+	return scheduleIDs, nil
+}
+
+// discoverTeamSchedules lists all schedules and returns the IDs of those belonging to the team
+func (c *Client) discoverTeamSchedules(ctx context.Context) ([]string, error) {
+	var scheduleIDs []string
+	opts := pagerduty.ListSchedulesOptions{Limit: 100}
+	for {
+		response, err := withRetry(ctx, c, func() (*pagerduty.ListSchedulesResponse, error) {
+			return c.client.ListSchedulesWithContext(ctx, opts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list schedules: %w", err)
+		}
+		for _, schedule := range response.Schedules {
+			for _, team := range schedule.Teams {
+				if team.ID == c.scope.TeamID {
+					scheduleIDs = append(scheduleIDs, schedule.ID)
+					break
+				}
+			}
+		}
+		if !response.More {
+			break
+		}
+		opts.Offset += opts.Limit
+	}
+
+	if len(scheduleIDs) == 0 {
+		return nil, fmt.Errorf("no schedules found for team %s", c.scope.TeamID)
+	}
+	slices.Sort(scheduleIDs)
+	return scheduleIDs, nil
+}
+
+// ResolveCurrentUser looks up the user the API token belongs to and monitors them.
+// This only works with user-scoped API tokens.
+func (c *Client) ResolveCurrentUser(ctx context.Context) (*User, error) {
+	user, err := withRetry(ctx, c, func() (*pagerduty.User, error) {
+		return c.client.GetCurrentUserWithContext(ctx, pagerduty.GetCurrentUserOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current user (PD_USER_ID must be set for account-level API tokens): %w", err)
+	}
+	c.userID = user.ID
+	return &User{
+		ID:   user.ID,
+		Name: user.Name,
+	}, nil
+}
+
+// UserID returns the ID of the user this client monitors
+func (c *Client) UserID() string {
+	return c.userID
+}
+
+// IsOnCall checks if the configured user is currently on-call for the configured schedule
+func (c *Client) IsOnCall(ctx context.Context) (bool, error) {
+	oncalls, err := c.CurrentOnCalls(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if any of the on-call entries match our user ID
+	for _, oncall := range oncalls {
+		if oncall.UserID == c.userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// OnCall describes a user currently on call in the monitored scope
+type OnCall struct {
+	UserID   string
+	UserName string
+	Start    time.Time
+	End      time.Time
+}
+
+// CurrentOnCalls returns everyone currently on call in the monitored scope
+func (c *Client) CurrentOnCalls(ctx context.Context) ([]OnCall, error) {
+	opts, err := c.listOnCallOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch on-call status: %w", err)
+	}
+
+	result := make([]OnCall, 0, len(oncalls))
+	for _, oncall := range oncalls {
+		entry := OnCall{UserID: oncall.User.ID, UserName: oncall.User.Summary}
+		// Start and end are empty for permanent on-call assignments
+		entry.Start, _ = time.Parse(time.RFC3339, oncall.Start)
+		entry.End, _ = time.Parse(time.RFC3339, oncall.End)
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// GetScheduleURL returns the web URL of the configured schedule, or of the escalation policy
+// or team in those modes
+func (c *Client) GetScheduleURL(ctx context.Context) (string, error) {
+	if c.scope.TeamID != "" {
+		team, err := withRetry(ctx, c, func() (*pagerduty.Team, error) {
+			return c.client.GetTeamWithContext(ctx, c.scope.TeamID)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch team: %w", err)
+		}
+		return team.HTMLURL, nil
+	}
+	if c.scope.EscalationPolicyID != "" {
+		policy, err := withRetry(ctx, c, func() (*pagerduty.EscalationPolicy, error) {
+			return c.client.GetEscalationPolicyWithContext(ctx, c.scope.EscalationPolicyID, &pagerduty.GetEscalationPolicyOptions{})
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch escalation policy: %w", err)
+		}
+		return policy.HTMLURL, nil
+	}
+
+	schedule, err := c.GetSchedule(ctx)
+	if err != nil {
+		return "", err
+	}
+	return schedule.HTMLURL, nil
+}
+
+// Schedule describes the configured PagerDuty schedule
+type Schedule struct {
+	ID      string
+	Name    string
+	HTMLURL string
+	// TimeZone is the IANA name of the schedule's timezone, e.g. "Europe/Berlin"
+	TimeZone string
+}
+
+// GetSchedule returns details of the configured schedule
+func (c *Client) GetSchedule(ctx context.Context) (*Schedule, error) {
+	schedule, err := withRetry(ctx, c, func() (*pagerduty.Schedule, error) {
+		return c.client.GetScheduleWithContext(ctx, c.scope.ScheduleID, pagerduty.GetScheduleOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule: %w", err)
+	}
+	return &Schedule{
+		ID:       schedule.ID,
+		Name:     schedule.Name,
+		HTMLURL:  schedule.HTMLURL,
+		TimeZone: schedule.TimeZone,
+	}, nil
+}
+
+// User describes the configured PagerDuty user
+type User struct {
+	ID   string
+	Name string
+}
+
+// GetUser returns details of the configured user
+func (c *Client) GetUser(ctx context.Context) (*User, error) {
+	user, err := withRetry(ctx, c, func() (*pagerduty.User, error) {
+		return c.client.GetUserWithContext(ctx, c.userID, pagerduty.GetUserOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	return &User{
+		ID:   user.ID,
+		Name: user.Name,
+	}, nil
+}
+
+// GetCurrentShift returns the shift the configured user is currently on call for
+// Returns nil if the user is not on call. If the user is covering via a schedule override,
+// Override is set and the shift window is that of the override.
+func (c *Client) GetCurrentShift(ctx context.Context) (*UpcomingShift, error) {
+	opts, err := c.listOnCallOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.UserIDs = []string{c.userID}
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current shift: %w", err)
+	}
+
+	for _, oncall := range oncalls {
+		if oncall.User.ID != c.userID {
+			continue
+		}
+		startTime, err := time.Parse(time.RFC3339, oncall.Start)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+		endTime, err := time.Parse(time.RFC3339, oncall.End)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+		shift := &UpcomingShift{
+			StartTime:  startTime,
+			EndTime:    endTime,
+			ScheduleID: oncall.Schedule.ID,
+		}
+		// Overrides are ignored when evaluating raw layers
+		if oncall.Schedule.ID != "" && c.scheduleSource != SourceLayers {
+			override, err := c.currentOverride(ctx, oncall.Schedule.ID)
+			if err != nil {
+				slog.Warn("Failed to look up overrides", "schedule_id", oncall.Schedule.ID, "error", err)
+			} else if override != nil {
+				shift.StartTime = override.StartTime
+				shift.EndTime = override.EndTime
+				shift.Override = true
+			}
+		}
+		return shift, nil
+	}
+
+	return nil, nil
+}
+
+// currentOverride returns the window of the override that currently puts the configured
+// user on call for the schedule, or nil if there is none
+func (c *Client) currentOverride(ctx context.Context, scheduleID string) (*UpcomingShift, error) {
+	now := time.Now().UTC()
+	resp, err := withRetry(ctx, c, func() (*pagerduty.ListOverridesResponse, error) {
+		return c.client.ListOverridesWithContext(ctx, scheduleID, pagerduty.ListOverridesOptions{
+			Since: now.Format(time.RFC3339),
+			Until: now.Add(time.Minute).Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range resp.Overrides {
+		if override.User.ID != c.userID {
+			continue
+		}
+		startTime, err := time.Parse(time.RFC3339, override.Start)
+		if err != nil {
+			continue
+		}
+		endTime, err := time.Parse(time.RFC3339, override.End)
+		if err != nil {
+			continue
+		}
+		if !now.Before(startTime) && now.Before(endTime) {
+			return &UpcomingShift{StartTime: startTime, EndTime: endTime}, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpcomingShift represents the time window of an on-call shift
+type UpcomingShift struct {
+	StartTime time.Time
+	EndTime   time.Time
+	// Override is set when the shift comes from a schedule override rather than the rotation
+	Override bool
+	// ScheduleID is the schedule the shift belongs to; empty if unknown or not from a schedule
+	ScheduleID string
+}
+
+// GetUpcomingShift returns the next upcoming shift for the configured user, served from
+// the cache while it is fresh and the cached shift has not started yet
+// Returns nil if no upcoming shift is found
+func (c *Client) GetUpcomingShift(ctx context.Context) (*UpcomingShift, error) {
+	if c.shiftCacheTTL <= 0 {
+		return c.fetchUpcomingShift(ctx)
+	}
+
+	c.upcomingMu.Lock()
+	defer c.upcomingMu.Unlock()
+	now := time.Now()
+	if now.Before(c.upcomingExpiresAt) {
+		return c.upcomingShift, nil
+	}
+
+	shift, err := c.fetchUpcomingShift(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.upcomingShift = shift
+	c.upcomingExpiresAt = now.Add(c.shiftCacheTTL)
+	if shift != nil && shift.StartTime.Before(c.upcomingExpiresAt) {
+		c.upcomingExpiresAt = shift.StartTime
+	}
+	return shift, nil
+}
+
+// ResetCache discards the cached upcoming shift and team schedules, so the next calls query
+// PagerDuty again. The previous team schedules are still used if re-discovering them fails.
+func (c *Client) ResetCache() {
+	c.upcomingMu.Lock()
+	c.upcomingShift = nil
+	c.upcomingExpiresAt = time.Time{}
+	c.upcomingMu.Unlock()
+
+	c.teamMu.Lock()
+	c.teamRefreshedAt = time.Time{}
+	c.teamMu.Unlock()
+}
+
+// fetchUpcomingShift looks up the next upcoming shift from PagerDuty
+func (c *Client) fetchUpcomingShift(ctx context.Context) (*UpcomingShift, error) {
+	// Get current time and look ahead for upcoming shifts
+	now := time.Now().UTC()
+	future := now.AddDate(0, 0, 7)
+	opts, err := c.listOnCallOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.Since = now.Format(time.RFC3339)
+	opts.Until = future.Format(time.RFC3339)
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch upcoming shifts: %w", err)
+	}
+
+	// Find the next shift for our user
+	var nextShift *UpcomingShift
+	for _, oncall := range oncalls {
+		// Parse the start time from string
+		startTime, err := time.Parse(time.RFC3339, oncall.Start)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+
+		if oncall.User.ID == c.userID && startTime.After(now) {
+			// Parse the end time from string
+			endTime, err := time.Parse(time.RFC3339, oncall.End)
+			if err != nil {
+				continue // Skip this entry if we can't parse the time
+			}
+
+			shift := &UpcomingShift{
+				StartTime: startTime,
+				EndTime:   endTime,
+			}
+			// Keep the earliest upcoming shift
+			if nextShift == nil || shift.StartTime.Before(nextShift.StartTime) {
+				nextShift = shift
+			}
+		}
+	}
+
+	return nextShift, nil
+}
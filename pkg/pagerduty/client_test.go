@@ -0,0 +1,336 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client that talks to the given test server
+func newTestClient(serverURL string, scope Scope, userID string) *Client {
+	return NewClient("test-token", scope, userID, ClientOptions{APIURL: serverURL})
+}
+
+func TestTeamSchedulesDiscoversAcrossPages(t *testing.T) {
+	t.Parallel()
+
+	pages := [][]map[string]interface{}{
+		{
+			{"id": "PSCHED2", "teams": []map[string]string{{"id": "PTEAM1"}}},
+			{"id": "POTHER", "teams": []map[string]string{{"id": "PTEAM2"}}},
+		},
+		{
+			{"id": "PSCHED1", "teams": []map[string]string{{"id": "PTEAM2"}, {"id": "PTEAM1"}}},
+		},
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := offset / 100
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"schedules": pages[page],
+			"more":      page < len(pages)-1,
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{TeamID: "PTEAM1"}, "PUSER1")
+	scheduleIDs, err := client.teamSchedules(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(scheduleIDs, []string{"PSCHED1", "PSCHED2"}) {
+		t.Fatalf("unexpected schedules: %v", scheduleIDs)
+	}
+
+	// A second lookup within the refresh interval is served from the cache
+	if _, err := client.teamSchedules(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestTeamSchedulesErrorsWhenTeamHasNoSchedules(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"schedules": []interface{}{}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{TeamID: "PTEAM1"}, "PUSER1")
+	if _, err := client.teamSchedules(context.Background()); err == nil {
+		t.Fatalf("expected an error for a team without schedules")
+	}
+}
+
+func TestResolveCurrentUserSetsUserID(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/me" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"user": map[string]string{"id": "PUSER1", "name": "Alex Example"},
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "")
+	user, err := client.ResolveCurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.Name != "Alex Example" {
+		t.Fatalf("unexpected user name: %s", user.Name)
+	}
+	if client.UserID() != "PUSER1" {
+		t.Fatalf("expected client to monitor PUSER1, got %s", client.UserID())
+	}
+}
+
+func TestIsOnCallFollowsPagination(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		oncalls := []map[string]interface{}{{"user": map[string]string{"id": "POTHER"}}}
+		more := true
+		if r.URL.Query().Get("offset") == "100" {
+			oncalls = []map[string]interface{}{{"user": map[string]string{"id": "PUSER1"}}}
+			more = false
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"oncalls": oncalls, "more": more})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	onCall, err := client.IsOnCall(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !onCall {
+		t.Fatalf("expected user on the second page to be on call")
+	}
+}
+
+func TestListOnCallsStopsAtPageLimit(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"oncalls": []map[string]interface{}{{"user": map[string]string{"id": "POTHER"}}},
+			"more":    true,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, MaxPages: 3})
+	if _, err := client.IsOnCall(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestIsOnCallIgnoresDeeperEscalationLevels(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"oncalls": []map[string]interface{}{
+				{"user": map[string]string{"id": "POTHER"}, "escalation_level": 1},
+				{"user": map[string]string{"id": "PUSER1"}, "escalation_level": 2},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, MaxEscalationLevel: 1})
+	onCall, err := client.IsOnCall(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if onCall {
+		t.Fatalf("expected a level 2 entry not to count as on call")
+	}
+}
+
+func TestEscalationPolicyScopeListsPolicyOnCalls(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	upcoming := now.Add(24 * time.Hour)
+	var mu sync.Mutex
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query())
+		mu.Unlock()
+		oncalls := []map[string]interface{}{
+			{"user": map[string]string{"id": "PUSER1"}, "start": now.Add(-time.Hour).Format(time.RFC3339), "end": now.Add(time.Hour).Format(time.RFC3339)},
+		}
+		if r.URL.Query().Get("since") != "" {
+			oncalls = append(oncalls, map[string]interface{}{
+				"user": map[string]string{"id": "PUSER1"}, "start": upcoming.Format(time.RFC3339), "end": upcoming.Add(8 * time.Hour).Format(time.RFC3339),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"oncalls": oncalls})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{EscalationPolicyID: "PPOLICY1"}, "PUSER1")
+	onCall, err := client.IsOnCall(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !onCall {
+		t.Fatalf("expected the user on call through the escalation policy")
+	}
+	shift, err := client.GetUpcomingShift(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shift == nil || !shift.StartTime.Equal(upcoming) {
+		t.Fatalf("expected the upcoming shift at %v, got %+v", upcoming, shift)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(queries))
+	}
+	for _, query := range queries {
+		if got := query["escalation_policy_ids[]"]; len(got) != 1 || got[0] != "PPOLICY1" {
+			t.Fatalf("expected the listing to be scoped to the escalation policy, got %v", query)
+		}
+		if _, ok := query["schedule_ids[]"]; ok {
+			t.Fatalf("expected no schedule_ids[] with an escalation policy scope, got %v", query)
+		}
+	}
+}
+
+func TestClientUsesConfiguredProxy(t *testing.T) {
+	t.Parallel()
+
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.Host + r.URL.Path
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"user": map[string]string{"id": "PUSER1"}})
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: "http://api.pagerduty.invalid", ProxyURL: proxyURL})
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requested != "api.pagerduty.invalid/users/PUSER1" {
+		t.Fatalf("expected the request to go through the proxy, got %q", requested)
+	}
+}
+
+func TestGetUpcomingShiftIsCached(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"oncalls": []map[string]interface{}{{
+				"user":  map[string]string{"id": "PUSER1"},
+				"start": start.Format(time.RFC3339),
+				"end":   start.Add(8 * time.Hour).Format(time.RFC3339),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, ShiftCacheTTL: time.Hour})
+	for i := 0; i < 3; i++ {
+		shift, err := client.GetUpcomingShift(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if shift == nil || !shift.StartTime.Equal(start) {
+			t.Fatalf("expected shift starting at %v, got %+v", start, shift)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	// The shift is fetched again once the cache entry expires
+	client.upcomingExpiresAt = time.Now().Add(-time.Second)
+	if _, err := client.GetUpcomingShift(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests after expiry, got %d", requests)
+	}
+
+	// Resetting the cache fetches the shift again straight away
+	client.ResetCache()
+	if _, err := client.GetUpcomingShift(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests after a reset, got %d", requests)
+	}
+}
+
+func TestGetCurrentShiftDetectsOverride(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oncalls":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"oncalls": []map[string]interface{}{{
+					"user":     map[string]string{"id": "PUSER1"},
+					"schedule": map[string]string{"id": "PSCHED1"},
+					"start":    now.Add(-time.Hour).Format(time.RFC3339),
+					"end":      now.Add(24 * time.Hour).Format(time.RFC3339),
+				}},
+			})
+		case "/schedules/PSCHED1/overrides":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"overrides": []map[string]interface{}{{
+					"user":  map[string]string{"id": "PUSER1"},
+					"start": now.Add(-time.Hour).Format(time.RFC3339),
+					"end":   now.Add(3 * time.Hour).Format(time.RFC3339),
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	shift, err := client.GetCurrentShift(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shift == nil || !shift.Override {
+		t.Fatalf("expected override shift, got %+v", shift)
+	}
+	if !shift.EndTime.Equal(now.Add(3 * time.Hour)) {
+		t.Fatalf("expected override end %v, got %v", now.Add(3*time.Hour), shift.EndTime)
+	}
+}
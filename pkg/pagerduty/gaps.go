@@ -0,0 +1,98 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// Gap is a window in which a schedule has nobody on call
+type Gap struct {
+	ScheduleID string
+	Start      time.Time
+	End        time.Time
+}
+
+// FindCoverageGaps returns the windows within the next lookahead in which a monitored
+// schedule's final coverage has nobody on call, earliest first
+func (c *Client) FindCoverageGaps(ctx context.Context, lookahead time.Duration) ([]Gap, error) {
+	var scheduleIDs []string
+	switch {
+	case c.scope.ScheduleID != "":
+		scheduleIDs = []string{c.scope.ScheduleID}
+	case c.scope.TeamID != "":
+		ids, err := c.teamSchedules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		scheduleIDs = ids
+	default:
+		return nil, fmt.Errorf("coverage gap checks require a schedule or team")
+	}
+
+	since := time.Now().UTC()
+	until := since.Add(lookahead)
+	var gaps []Gap
+	for _, scheduleID := range scheduleIDs {
+		schedule, err := withRetry(ctx, c, func() (*pagerduty.Schedule, error) {
+			return c.client.GetScheduleWithContext(ctx, scheduleID, pagerduty.GetScheduleOptions{
+				Since: since.Format(time.RFC3339),
+				Until: until.Format(time.RFC3339),
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch coverage of schedule %s: %w", scheduleID, err)
+		}
+		for _, gap := range coverageGaps(schedule.FinalSchedule.RenderedScheduleEntries, since, until) {
+			gap.ScheduleID = scheduleID
+			gaps = append(gaps, gap)
+		}
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Start.Before(gaps[j].Start) })
+	return gaps, nil
+}
+
+// coverageGaps returns the parts of [since, until) not covered by any of the entries
+func coverageGaps(entries []pagerduty.RenderedScheduleEntry, since, until time.Time) []Gap {
+	type window struct{ start, end time.Time }
+	var covered []window
+	for _, entry := range entries {
+		start, err := time.Parse(time.RFC3339, entry.Start)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+		end, err := time.Parse(time.RFC3339, entry.End)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+		covered = append(covered, window{start, end})
+	}
+	sort.Slice(covered, func(i, j int) bool { return covered[i].start.Before(covered[j].start) })
+
+	var gaps []Gap
+	cursor := since
+	for _, w := range covered {
+		if w.start.After(cursor) {
+			gaps = append(gaps, Gap{Start: cursor, End: minTime(w.start, until)})
+		}
+		if w.end.After(cursor) {
+			cursor = w.end
+		}
+		if !cursor.Before(until) {
+			return gaps
+		}
+	}
+	return append(gaps, Gap{Start: cursor, End: until})
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestCoverageGaps(t *testing.T) {
+	since := time.Date(2030, time.March, 4, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	at := func(hours int) string { return since.Add(time.Duration(hours) * time.Hour).Format(time.RFC3339) }
+
+	cases := []struct {
+		name    string
+		entries []pagerduty.RenderedScheduleEntry
+		want    [][2]int
+	}{
+		{"fully covered", []pagerduty.RenderedScheduleEntry{{Start: at(-2), End: at(12)}, {Start: at(12), End: at(30)}}, nil},
+		{"gap between entries", []pagerduty.RenderedScheduleEntry{{Start: at(0), End: at(8)}, {Start: at(10), End: at(24)}}, [][2]int{{8, 10}}},
+		{"unsorted and overlapping", []pagerduty.RenderedScheduleEntry{{Start: at(4), End: at(20)}, {Start: at(0), End: at(6)}}, [][2]int{{20, 24}}},
+		{"gap at start", []pagerduty.RenderedScheduleEntry{{Start: at(3), End: at(24)}}, [][2]int{{0, 3}}},
+		{"no entries", nil, [][2]int{{0, 24}}},
+	}
+	for _, tc := range cases {
+		gaps := coverageGaps(tc.entries, since, until)
+		if len(gaps) != len(tc.want) {
+			t.Fatalf("%s: expected %d gaps, got %+v", tc.name, len(tc.want), gaps)
+		}
+		for i, want := range tc.want {
+			start := since.Add(time.Duration(want[0]) * time.Hour)
+			end := since.Add(time.Duration(want[1]) * time.Hour)
+			if !gaps[i].Start.Equal(start) || !gaps[i].End.Equal(end) {
+				t.Fatalf("%s: expected gap %v-%v, got %v-%v", tc.name, start, end, gaps[i].Start, gaps[i].End)
+			}
+		}
+	}
+}
+
+func TestFindCoverageGapsUsesFinalSchedule(t *testing.T) {
+	t.Parallel()
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		now := time.Now().UTC()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"schedule": map[string]interface{}{
+				"id": "PSCHED1",
+				"final_schedule": map[string]interface{}{
+					"rendered_schedule_entries": []map[string]interface{}{
+						{"start": now.Add(-time.Hour).Format(time.RFC3339), "end": now.Add(2 * time.Hour).Format(time.RFC3339)},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	gaps, err := client.FindCoverageGaps(context.Background(), 6*time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(gaps) != 1 || gaps[0].ScheduleID != "PSCHED1" {
+		t.Fatalf("expected one gap on PSCHED1, got %+v", gaps)
+	}
+	if gap := gaps[0].End.Sub(gaps[0].Start); gap < 3*time.Hour || gap > 5*time.Hour {
+		t.Fatalf("expected a gap of about 4 hours, got %v", gap)
+	}
+	if query == "" {
+		t.Fatalf("expected since/until to be sent")
+	}
+}
@@ -0,0 +1,88 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// handoffTolerance is how far apart a shift boundary and another responder's shift may be
+// for them to count as a handover
+const handoffTolerance = time.Minute
+
+// Handoff names the responder on the other side of a shift handover
+type Handoff struct {
+	UserID   string
+	UserName string
+	At       time.Time
+}
+
+// PreviousResponder returns who handed over to the configured user at the start of their
+// shift, or nil if nobody is found. When scheduleID is set only that schedule is considered,
+// otherwise the whole monitored scope.
+func (c *Client) PreviousResponder(ctx context.Context, scheduleID string, start time.Time) (*Handoff, error) {
+	opts, err := c.handoffOptions(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	opts.Since = start.Add(-handoffTolerance).UTC().Format(time.RFC3339)
+	opts.Until = start.UTC().Format(time.RFC3339)
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch previous responder: %w", err)
+	}
+
+	// The previous responder's shift ended when ours started
+	for _, oncall := range oncalls {
+		entryEnd, err := time.Parse(time.RFC3339, oncall.End)
+		if err != nil || oncall.User.ID == c.userID || entryEnd.Sub(start).Abs() > handoffTolerance {
+			continue
+		}
+		return &Handoff{UserID: oncall.User.ID, UserName: oncall.User.Summary, At: entryEnd}, nil
+	}
+	return nil, nil
+}
+
+// NextResponder returns who takes over from the configured user when their shift from start
+// to end is over, or nil if nobody is found. end may be a little after the actual handover,
+// e.g. the time the end of the shift was noticed.
+func (c *Client) NextResponder(ctx context.Context, scheduleID string, start, end time.Time) (*Handoff, error) {
+	opts, err := c.handoffOptions(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	opts.Since = end.UTC().Format(time.RFC3339)
+	opts.Until = end.Add(handoffTolerance).UTC().Format(time.RFC3339)
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch next responder: %w", err)
+	}
+
+	// The next responder's shift started after ours did, by the time ours ended. Others
+	// whose shifts began during ours (e.g. on other escalation levels) started earlier,
+	// so the latest start wins.
+	var next *Handoff
+	for _, oncall := range oncalls {
+		entryStart, err := time.Parse(time.RFC3339, oncall.Start)
+		if err != nil || oncall.User.ID == c.userID || entryStart.Before(start) || entryStart.After(end.Add(handoffTolerance)) {
+			continue
+		}
+		if next == nil || entryStart.After(next.At) {
+			next = &Handoff{UserID: oncall.User.ID, UserName: oncall.User.Summary, At: entryStart}
+		}
+	}
+	return next, nil
+}
+
+// handoffOptions returns the on-call listing options for the given schedule, or for the
+// monitored scope if scheduleID is empty
+func (c *Client) handoffOptions(ctx context.Context, scheduleID string) (pagerduty.ListOnCallOptions, error) {
+	if scheduleID != "" {
+		return pagerduty.ListOnCallOptions{ScheduleIDs: []string{scheduleID}}, nil
+	}
+	return c.listOnCallOptions(ctx)
+}
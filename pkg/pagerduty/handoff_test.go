@@ -0,0 +1,63 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreviousAndNextResponder(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2030, time.March, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(8 * time.Hour)
+	entry := func(userID, name string, from, to time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"user":  map[string]string{"id": userID, "summary": name},
+			"start": from.Format(time.RFC3339),
+			"end":   to.Format(time.RFC3339),
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("schedule_ids[]"); got != "PSCHED2" {
+			t.Errorf("expected schedule PSCHED2, got %q", got)
+		}
+		var oncalls []map[string]interface{}
+		if r.URL.Query().Get("until") == start.Format(time.RFC3339) {
+			oncalls = []map[string]interface{}{
+				entry("PLEAD", "Team Lead", start.Add(-72*time.Hour), start.Add(72*time.Hour)),
+				entry("PJANE", "Jane Doe", start.Add(-8*time.Hour), start),
+			}
+		} else {
+			oncalls = []map[string]interface{}{
+				entry("PUSER1", "Me", start, end),
+				entry("PLATE", "Late Starter", start.Add(time.Hour), end.Add(time.Hour)),
+				entry("PBOB", "Bob Smith", end, end.Add(8*time.Hour)),
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"oncalls": oncalls})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	previous, err := client.PreviousResponder(context.Background(), "PSCHED2", start)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if previous == nil || previous.UserName != "Jane Doe" {
+		t.Fatalf("expected Jane Doe as previous responder, got %+v", previous)
+	}
+
+	// The end of the shift may only be noticed on a later poll
+	next, err := client.NextResponder(context.Background(), "PSCHED2", start, end.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if next == nil || next.UserName != "Bob Smith" || !next.At.Equal(end) {
+		t.Fatalf("expected Bob Smith taking over at %v, got %+v", end, next)
+	}
+}
@@ -0,0 +1,78 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// IncidentSummary counts the unresolved incidents the configured user is responsible for
+type IncidentSummary struct {
+	Count int
+	// Oldest is when the oldest open incident was created; zero if there are none
+	Oldest time.Time
+}
+
+// OpenIncidents summarises the triggered and acknowledged incidents on the escalation
+// policies the configured user is currently on call for
+func (c *Client) OpenIncidents(ctx context.Context) (*IncidentSummary, error) {
+	policies := map[string]bool{}
+	if c.scope.EscalationPolicyID != "" {
+		policies[c.scope.EscalationPolicyID] = true
+	} else {
+		opts, err := c.listOnCallOptions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.UserIDs = []string{c.userID}
+		oncalls, err := c.listOnCalls(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch escalation policies: %w", err)
+		}
+		for _, oncall := range oncalls {
+			policies[oncall.EscalationPolicy.ID] = true
+		}
+	}
+
+	summary := &IncidentSummary{}
+	if len(policies) == 0 {
+		return summary, nil
+	}
+
+	opts := pagerduty.ListIncidentsOptions{
+		Limit:    onCallPageSize,
+		Statuses: []string{"triggered", "acknowledged"},
+	}
+	if c.scope.TeamID != "" {
+		opts.TeamIDs = []string{c.scope.TeamID}
+	}
+	for page := 1; ; page++ {
+		response, err := withRetry(ctx, c, func() (*pagerduty.ListIncidentsResponse, error) {
+			return c.client.ListIncidentsWithContext(ctx, opts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list incidents: %w", err)
+		}
+		for _, incident := range response.Incidents {
+			if !policies[incident.EscalationPolicy.ID] {
+				continue
+			}
+			summary.Count++
+			created, err := time.Parse(time.RFC3339, incident.CreatedAt)
+			if err == nil && (summary.Oldest.IsZero() || created.Before(summary.Oldest)) {
+				summary.Oldest = created
+			}
+		}
+		if !response.More {
+			return summary, nil
+		}
+		if page >= c.maxPages {
+			slog.Warn("Incident listing truncated; raise PD_MAX_PAGES to fetch more", "pages", page)
+			return summary, nil
+		}
+		opts.Offset += opts.Limit
+	}
+}
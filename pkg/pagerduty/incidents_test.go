@@ -0,0 +1,50 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenIncidentsCountsOwnEscalationPolicies(t *testing.T) {
+	t.Parallel()
+
+	oldest := time.Date(2030, time.March, 4, 7, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oncalls":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"oncalls": []map[string]interface{}{{
+					"user":              map[string]string{"id": "PUSER1"},
+					"escalation_policy": map[string]string{"id": "PEP1"},
+				}},
+			})
+		case "/incidents":
+			if got := r.URL.Query()["statuses[]"]; len(got) != 2 {
+				t.Errorf("expected triggered and acknowledged statuses, got %v", got)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"incidents": []map[string]interface{}{
+					{"escalation_policy": map[string]string{"id": "PEP1"}, "created_at": oldest.Add(time.Hour).Format(time.RFC3339)},
+					{"escalation_policy": map[string]string{"id": "PEP1"}, "created_at": oldest.Format(time.RFC3339)},
+					{"escalation_policy": map[string]string{"id": "POTHER"}, "created_at": oldest.Add(-time.Hour).Format(time.RFC3339)},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	summary, err := client.OpenIncidents(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if summary.Count != 2 || !summary.Oldest.Equal(oldest) {
+		t.Fatalf("expected 2 incidents, oldest %v, got %+v", oldest, summary)
+	}
+}
@@ -0,0 +1,134 @@
+package pagerduty
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Mock is an OnCallChecker that answers from its fields instead of calling PagerDuty, for
+// testing code that polls on-call status. The fields may be changed between calls.
+type Mock struct {
+	User string
+	// OnCall is reported by IsOnCall, and Current is returned by GetCurrentShift
+	OnCall  bool
+	Current *UpcomingShift
+	// Shifts are the user's shifts, earliest first. GetUpcomingShift returns the first that
+	// has not started yet, and ListShifts those overlapping the requested window.
+	Shifts    []UpcomingShift
+	OnCalls   []OnCall
+	Gaps      []Gap
+	Previous  *Handoff
+	Next      *Handoff
+	Incidents *IncidentSummary
+	// Err, when set, is returned by every call instead of a result
+	Err error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Calls returns how many times the named method has been called
+func (m *Mock) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[method]
+}
+
+// record counts a call of the named method
+func (m *Mock) record(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = make(map[string]int)
+	}
+	m.calls[method]++
+}
+
+// UserID implements OnCallChecker
+func (m *Mock) UserID() string {
+	return m.User
+}
+
+// IsOnCall implements OnCallChecker
+func (m *Mock) IsOnCall(ctx context.Context) (bool, error) {
+	m.record("IsOnCall")
+	return m.OnCall, m.Err
+}
+
+// GetCurrentShift implements OnCallChecker
+func (m *Mock) GetCurrentShift(ctx context.Context) (*UpcomingShift, error) {
+	m.record("GetCurrentShift")
+	if m.Err != nil || !m.OnCall {
+		return nil, m.Err
+	}
+	return m.Current, nil
+}
+
+// GetUpcomingShift implements OnCallChecker
+func (m *Mock) GetUpcomingShift(ctx context.Context) (*UpcomingShift, error) {
+	m.record("GetUpcomingShift")
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	now := time.Now()
+	for _, shift := range m.Shifts {
+		if shift.StartTime.After(now) {
+			return &shift, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListShifts implements OnCallChecker
+func (m *Mock) ListShifts(ctx context.Context, since, until time.Time) ([]UpcomingShift, error) {
+	m.record("ListShifts")
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var shifts []UpcomingShift
+	for _, shift := range m.Shifts {
+		if shift.StartTime.Before(until) && shift.EndTime.After(since) {
+			shifts = append(shifts, shift)
+		}
+	}
+	return shifts, nil
+}
+
+// CurrentOnCalls implements OnCallChecker
+func (m *Mock) CurrentOnCalls(ctx context.Context) ([]OnCall, error) {
+	m.record("CurrentOnCalls")
+	return m.OnCalls, m.Err
+}
+
+// FindCoverageGaps implements OnCallChecker
+func (m *Mock) FindCoverageGaps(ctx context.Context, lookahead time.Duration) ([]Gap, error) {
+	m.record("FindCoverageGaps")
+	return m.Gaps, m.Err
+}
+
+// PreviousResponder implements OnCallChecker
+func (m *Mock) PreviousResponder(ctx context.Context, scheduleID string, start time.Time) (*Handoff, error) {
+	m.record("PreviousResponder")
+	return m.Previous, m.Err
+}
+
+// NextResponder implements OnCallChecker
+func (m *Mock) NextResponder(ctx context.Context, scheduleID string, start, end time.Time) (*Handoff, error) {
+	m.record("NextResponder")
+	return m.Next, m.Err
+}
+
+// OpenIncidents implements OnCallChecker
+func (m *Mock) OpenIncidents(ctx context.Context) (*IncidentSummary, error) {
+	m.record("OpenIncidents")
+	return m.Incidents, m.Err
+}
+
+// RateLimit implements OnCallChecker; the mock is never rate limited
+func (m *Mock) RateLimit() (limitedAt, retryAt time.Time) {
+	return time.Time{}, time.Time{}
+}
+
+// Mock implements OnCallChecker
+var _ OnCallChecker = (*Mock)(nil)
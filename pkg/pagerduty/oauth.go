@@ -0,0 +1,118 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuthTokenURLs maps PagerDuty service regions to their OAuth token endpoints
+var OAuthTokenURLs = map[string]string{
+	"us": "https://identity.pagerduty.com/oauth/token",
+	"eu": "https://identity.eu.pagerduty.com/oauth/token",
+}
+
+// DefaultOAuthScopes are the read-only scopes the notifier needs from a scoped OAuth app
+var DefaultOAuthScopes = []string{
+	"schedules.read",
+	"users.read",
+	"oncalls.read",
+	"escalation_policies.read",
+	"teams.read",
+	"incidents.read",
+}
+
+// oauthRefreshMargin is how long before expiry an access token is replaced
+const oauthRefreshMargin = time.Minute
+
+// OAuthCredentials configures the client credentials flow of a PagerDuty scoped OAuth app
+type OAuthCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested as is; the account scope (as_account-<region>.<subdomain>) must be included
+	Scopes []string
+}
+
+// oauthTransport authenticates requests with an access token from the client credentials
+// flow, fetching a new one shortly before the current one expires or after it is rejected
+type oauthTransport struct {
+	base  http.RoundTripper
+	creds OAuthCredentials
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.accessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// The token may have been revoked; the next request fetches a new one
+		t.mu.Lock()
+		if t.token == token {
+			t.token = ""
+		}
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+// accessToken returns the cached access token, requesting a new one if it is missing or about to expire
+func (t *oauthTransport) accessToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Now().Before(t.expiresAt.Add(-oauthRefreshMargin)) {
+		return t.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {t.creds.ClientID},
+		"client_secret": {t.creds.ClientSecret},
+		"scope":         {strings.Join(t.creds.Scopes, " ")},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.creds.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create OAuth token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request OAuth token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("OAuth token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode OAuth token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("OAuth token response did not include an access token")
+	}
+	t.token = result.AccessToken
+	t.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return t.token, nil
+}
@@ -0,0 +1,58 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOAuthTokenIsCachedAndReplacedWhenRejected(t *testing.T) {
+	t.Parallel()
+
+	tokens := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "client" {
+			t.Errorf("unexpected token request: %v", r.Form)
+		}
+		if got := r.Form.Get("scope"); !strings.HasPrefix(got, "as_account-us.acme ") {
+			t.Errorf("expected the account scope first, got %q", got)
+		}
+		tokens++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token-%d", tokens), "expires_in": 3600})
+	}))
+	defer tokenServer.Close()
+
+	var seen []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if len(seen) == 2 {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"message": "Unauthorized", "code": 2006}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"user": map[string]string{"id": "PUSER1"}})
+	}))
+	defer apiServer.Close()
+
+	client := NewClient("", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{
+		APIURL: apiServer.URL,
+		OAuth: &OAuthCredentials{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       []string{"as_account-us.acme", "users.read"},
+		},
+	})
+	for range 3 {
+		_, _ = client.GetUser(context.Background())
+	}
+
+	want := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected authorization headers %v, got %v", want, seen)
+	}
+}
@@ -0,0 +1,86 @@
+package pagerduty
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRetryAfter is assumed when a rate-limited response doesn't say when to retry
+	defaultRetryAfter = time.Minute
+	// maxRateLimitWait is the longest a request waits to be retried after a 429
+	maxRateLimitWait = 2 * time.Minute
+	// maxRateLimitRetries is how many times a rate-limited request is retried
+	maxRateLimitRetries = 2
+)
+
+// rateLimitTransport retries requests rejected with 429 Too Many Requests once the delay
+// PagerDuty asked for has passed, and records the limit so callers can slow down
+type rateLimitTransport struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	limitedAt time.Time
+	retryAt   time.Time
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		now := time.Now()
+		wait := retryAfter(resp.Header, now)
+		t.mu.Lock()
+		t.limitedAt = now
+		t.retryAt = now.Add(wait)
+		t.mu.Unlock()
+
+		// Only bodiless requests can be replayed safely
+		if attempt >= maxRateLimitRetries || wait > maxRateLimitWait || req.Body != nil {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// status returns when the last 429 was received and when PagerDuty said to retry
+func (t *rateLimitTransport) status() (limitedAt, retryAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limitedAt, t.retryAt
+}
+
+// retryAfter returns the delay requested by a rate-limited response, from the Retry-After
+// header (seconds or an HTTP date) or PagerDuty's ratelimit-reset header
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0)
+		}
+	}
+	if value := header.Get("Ratelimit-Reset"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,86 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedRequestIsRetried(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"oncalls": []map[string]interface{}{{"user": map[string]string{"id": "PUSER1"}}},
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	started := time.Now()
+	onCall, err := client.IsOnCall(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !onCall {
+		t.Fatalf("expected user to be on call")
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+	if limitedAt, _ := client.RateLimit(); limitedAt.Before(started) {
+		t.Fatalf("expected rate limit to be recorded, got %v", limitedAt)
+	}
+}
+
+func TestRateLimitGivesUpWhenRetryIsTooFarAway(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	if _, err := client.IsOnCall(context.Background()); err == nil {
+		t.Fatalf("expected an error while rate limited")
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+	if _, retryAt := client.RateLimit(); time.Until(retryAt) < 59*time.Minute {
+		t.Fatalf("expected retry time about an hour away, got %v", retryAt)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"seconds", http.Header{"Retry-After": {"42"}}, 42 * time.Second},
+		{"http date", http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second},
+		{"ratelimit reset", http.Header{"Ratelimit-Reset": {"15"}}, 15 * time.Second},
+		{"missing", http.Header{}, defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
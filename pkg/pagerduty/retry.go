@@ -0,0 +1,67 @@
+package pagerduty
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+const (
+	// DefaultRetryMaxElapsed is the default time budget for retrying a failed API call
+	DefaultRetryMaxElapsed = time.Minute
+	// retryInitialDelay is the delay before the first retry; it doubles on each attempt
+	retryInitialDelay = time.Second
+	// retryMaxDelay caps the delay between retries
+	retryMaxDelay = 15 * time.Second
+)
+
+// withRetry calls fn, retrying transient failures with jittered exponential backoff until
+// the client's retry budget is spent
+func withRetry[T any](ctx context.Context, c *Client, fn func() (T, error)) (T, error) {
+	started := time.Now()
+	delay := retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || !isRetryable(err) {
+			return result, err
+		}
+
+		// Full jitter between half and all of the current delay
+		wait := delay/2 + rand.N(delay/2+1)
+		if time.Since(started)+wait > c.retryMaxElapsed {
+			return result, err
+		}
+		slog.Warn("PagerDuty request failed, retrying", "attempt", attempt, "retry_in", wait.Round(time.Millisecond), "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// isRetryable reports whether an API error is likely to succeed on retry: server errors and
+// network failures are; other API errors and cancellations are not. Rate limits are already
+// waited out by rateLimitTransport, so a 429 reaching here means the wait was too long.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr pagerduty.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary() && !apiErr.RateLimited()
+	}
+	var apiErrPtr *pagerduty.APIError
+	if errors.As(err, &apiErrPtr) {
+		return apiErrPtr.Temporary() && !apiErrPtr.RateLimited()
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerErrorsAreRetried(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"oncalls": []map[string]interface{}{{"user": map[string]string{"id": "PUSER1"}}},
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	onCall, err := client.IsOnCall(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !onCall || requests != 3 {
+		t.Fatalf("expected success on the third attempt, got onCall=%v after %d requests", onCall, requests)
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	if _, err := client.IsOnCall(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestRetriesStopAtMaxElapsed(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, RetryMaxElapsed: 2 * time.Second})
+	started := time.Now()
+	if _, err := client.IsOnCall(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Fatalf("expected retries to stop within the budget, took %v", elapsed)
+	}
+}
@@ -0,0 +1,55 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxShiftListingDays is the widest window PagerDuty accepts for an on-call listing
+const MaxShiftListingDays = 90
+
+// ListShifts returns the configured user's shifts that overlap [since, until), earliest first.
+// A shift in progress at since is included with its real start time.
+func (c *Client) ListShifts(ctx context.Context, since, until time.Time) ([]UpcomingShift, error) {
+	opts, err := c.listOnCallOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.UserIDs = []string{c.userID}
+	opts.Since = since.UTC().Format(time.RFC3339)
+	opts.Until = until.UTC().Format(time.RFC3339)
+
+	oncalls, err := c.scopeOnCalls(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shifts: %w", err)
+	}
+
+	var shifts []UpcomingShift
+	seen := make(map[string]bool)
+	for _, oncall := range oncalls {
+		if oncall.User.ID != c.userID {
+			continue
+		}
+		startTime, err := time.Parse(time.RFC3339, oncall.Start)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+		endTime, err := time.Parse(time.RFC3339, oncall.End)
+		if err != nil {
+			continue // Skip this entry if we can't parse the time
+		}
+
+		// The same shift is listed once per escalation level it covers
+		key := oncall.Schedule.ID + "|" + oncall.Start + "|" + oncall.End
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		shifts = append(shifts, UpcomingShift{StartTime: startTime, EndTime: endTime, ScheduleID: oncall.Schedule.ID})
+	}
+
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].StartTime.Before(shifts[j].StartTime) })
+	return shifts, nil
+}
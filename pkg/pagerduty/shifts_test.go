@@ -0,0 +1,53 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListShiftsSortsAndDeduplicates(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2030, time.March, 4, 0, 0, 0, 0, time.UTC)
+	entry := func(userID string, level int, from, to time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"user":             map[string]string{"id": userID},
+			"schedule":         map[string]string{"id": "PSCHED1"},
+			"escalation_level": level,
+			"start":            from.Format(time.RFC3339),
+			"end":              to.Format(time.RFC3339),
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("user_ids[]"); got != "PUSER1" {
+			t.Errorf("expected user PUSER1, got %q", got)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"oncalls": []map[string]interface{}{
+			entry("PUSER1", 1, since.Add(48*time.Hour), since.Add(56*time.Hour)),
+			entry("PUSER1", 2, since.Add(48*time.Hour), since.Add(56*time.Hour)),
+			entry("POTHER", 1, since.Add(24*time.Hour), since.Add(32*time.Hour)),
+			entry("PUSER1", 1, since.Add(-2*time.Hour), since.Add(6*time.Hour)),
+		}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Scope{ScheduleID: "PSCHED1"}, "PUSER1")
+	shifts, err := client.ListShifts(context.Background(), since, since.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(shifts) != 2 {
+		t.Fatalf("expected 2 shifts, got %+v", shifts)
+	}
+	if !shifts[0].StartTime.Equal(since.Add(-2*time.Hour)) || !shifts[1].StartTime.Equal(since.Add(48*time.Hour)) {
+		t.Fatalf("expected shifts in start order, got %+v", shifts)
+	}
+	if shifts[1].ScheduleID != "PSCHED1" {
+		t.Fatalf("expected schedule PSCHED1, got %q", shifts[1].ScheduleID)
+	}
+}
@@ -0,0 +1,90 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// ScheduleSource selects which entries of a schedule count towards a user being on call
+type ScheduleSource string
+
+const (
+	// SourceOnCalls uses the PagerDuty on-call listing (the default)
+	SourceOnCalls ScheduleSource = "oncalls"
+	// SourceFinal uses the schedule's rendered final layer, including overrides
+	SourceFinal ScheduleSource = "final"
+	// SourceLayers uses the rendered entries of selected schedule layers, ignoring overrides
+	SourceLayers ScheduleSource = "layers"
+)
+
+// ParseScheduleSource converts a name into a ScheduleSource
+func ParseScheduleSource(name string) (ScheduleSource, error) {
+	switch source := ScheduleSource(name); source {
+	case SourceOnCalls, SourceFinal, SourceLayers:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown schedule source %q (valid sources: oncalls, final, layers)", name)
+	}
+}
+
+// scopeOnCalls lists on-call entries from the configured schedule source. Listings that are
+// not restricted to schedules (escalation policy mode) always use the on-call listing.
+func (c *Client) scopeOnCalls(ctx context.Context, opts pagerduty.ListOnCallOptions) ([]pagerduty.OnCall, error) {
+	if c.scheduleSource == "" || c.scheduleSource == SourceOnCalls || len(opts.ScheduleIDs) == 0 {
+		return c.listOnCalls(ctx, opts)
+	}
+
+	// Without a window the on-call listing reports who is on call right now
+	since := time.Now().UTC()
+	until := since.Add(time.Second)
+	if opts.Since != "" {
+		since, _ = time.Parse(time.RFC3339, opts.Since)
+	}
+	if opts.Until != "" {
+		until, _ = time.Parse(time.RFC3339, opts.Until)
+	}
+
+	var oncalls []pagerduty.OnCall
+	for _, scheduleID := range opts.ScheduleIDs {
+		schedule, err := withRetry(ctx, c, func() (*pagerduty.Schedule, error) {
+			return c.client.GetScheduleWithContext(ctx, scheduleID, pagerduty.GetScheduleOptions{
+				Since: since.Format(time.RFC3339),
+				Until: until.Format(time.RFC3339),
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render schedule %s: %w", scheduleID, err)
+		}
+		for _, entry := range c.renderedEntries(schedule) {
+			if len(opts.UserIDs) > 0 && !slices.Contains(opts.UserIDs, entry.User.ID) {
+				continue
+			}
+			oncalls = append(oncalls, pagerduty.OnCall{
+				User:     pagerduty.User{APIObject: entry.User},
+				Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: scheduleID}},
+				Start:    entry.Start,
+				End:      entry.End,
+			})
+		}
+	}
+	return oncalls, nil
+}
+
+// renderedEntries returns the schedule's entries for the configured source: the final layer,
+// or every layer whose ID or name is in the configured layer list
+func (c *Client) renderedEntries(schedule *pagerduty.Schedule) []pagerduty.RenderedScheduleEntry {
+	if c.scheduleSource == SourceFinal {
+		return schedule.FinalSchedule.RenderedScheduleEntries
+	}
+	var entries []pagerduty.RenderedScheduleEntry
+	for _, layer := range schedule.ScheduleLayers {
+		if slices.Contains(c.scheduleLayers, layer.ID) || slices.Contains(c.scheduleLayers, layer.Name) {
+			entries = append(entries, layer.RenderedScheduleEntries...)
+		}
+	}
+	return entries
+}
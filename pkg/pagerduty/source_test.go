@@ -0,0 +1,64 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsOnCallUsesScheduleSource(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	entry := func(userID string) map[string]interface{} {
+		return map[string]interface{}{
+			"user":  map[string]string{"id": userID},
+			"start": now.Add(-time.Hour).Format(time.RFC3339),
+			"end":   now.Add(time.Hour).Format(time.RFC3339),
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schedules/PSCHED1" {
+			t.Errorf("expected the schedule to be rendered, got %s", r.URL.Path)
+		}
+		// PUSER1's layer entry is overridden by POTHER in the final schedule
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"schedule": map[string]interface{}{
+				"id":             "PSCHED1",
+				"final_schedule": map[string]interface{}{"rendered_schedule_entries": []map[string]interface{}{entry("POTHER")}},
+				"schedule_layers": []map[string]interface{}{
+					{"id": "PLAYER1", "name": "Primary", "rendered_schedule_entries": []map[string]interface{}{entry("PUSER1")}},
+					{"id": "PLAYER2", "name": "Weekend", "rendered_schedule_entries": []map[string]interface{}{entry("PTHIRD")}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		source ScheduleSource
+		layers []string
+		want   bool
+	}{
+		{SourceFinal, nil, false},
+		{SourceLayers, []string{"Primary"}, true},
+		{SourceLayers, []string{"PLAYER2"}, false},
+	}
+	for _, tc := range cases {
+		client := NewClient("test-token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{
+			APIURL:         server.URL,
+			ScheduleSource: tc.source,
+			ScheduleLayers: tc.layers,
+		})
+		onCall, err := client.IsOnCall(context.Background())
+		if err != nil {
+			t.Fatalf("%s %v: expected no error, got %v", tc.source, tc.layers, err)
+		}
+		if onCall != tc.want {
+			t.Fatalf("%s %v: expected on call %v, got %v", tc.source, tc.layers, tc.want, onCall)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package pagerduty
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenFileTransport authenticates requests with an API token read from a file, re-reading
+// the file whenever it changes so rotated tokens are used without a restart
+type tokenFileTransport struct {
+	base http.RoundTripper
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenFileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Token token="+token)
+	return t.base.RoundTrip(req)
+}
+
+// currentToken returns the token in the file, reloading it if the file changed since it was
+// last read. If a changed file cannot be read, the previous token keeps being used.
+func (t *tokenFileTransport) currentToken() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Stat follows symlinks, so the atomic swaps of Kubernetes secret volumes are noticed
+	info, err := os.Stat(t.path)
+	if err == nil && t.token != "" && info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return t.token, nil
+	}
+	var token string
+	if err == nil {
+		token, err = readTokenFile(t.path)
+	}
+	if err != nil {
+		if t.token == "" {
+			return "", err
+		}
+		slog.Warn("Failed to reload PagerDuty API token, using the previous one", "error", err)
+		return t.token, nil
+	}
+
+	if t.token != "" && token != t.token {
+		slog.Info("Reloaded PagerDuty API token", "path", t.path)
+	}
+	t.token = token
+	t.modTime = info.ModTime()
+	t.size = info.Size()
+	return t.token, nil
+}
+
+// readTokenFile reads an API token from a file, ignoring surrounding whitespace
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read API token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("API token file %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,59 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPITokenFileIsReloadedWhenChanged(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"user": map[string]string{"id": "PUSER1"}})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	client := NewClient("", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, APITokenFile: path})
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("second\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	// Make sure the change is visible on filesystems with coarse timestamps
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("failed to touch token: %v", err)
+	}
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// A removed file keeps the last token in use
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove token: %v", err)
+	}
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{"Token token=first", "Token token=second", "Token token=second"}
+	for i, header := range want {
+		if i >= len(seen) || seen[i] != header {
+			t.Fatalf("expected authorization headers %v, got %v", want, seen)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package pagerduty
+
+import "net/http"
+
+// userAgentTransport sets the User-Agent of every API request, identifying the application
+// using the client to PagerDuty
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,28 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserAgentOption(t *testing.T) {
+	t.Parallel()
+
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"user": map[string]string{"id": "PUSER1"}})
+	}))
+	defer server.Close()
+
+	client := NewClient("token", Scope{ScheduleID: "PSCHED1"}, "PUSER1", ClientOptions{APIURL: server.URL, UserAgent: "notifier/v1.2.3"})
+	if _, err := client.GetUser(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if userAgent != "notifier/v1.2.3" {
+		t.Errorf("expected User-Agent notifier/v1.2.3, got %q", userAgent)
+	}
+}
@@ -0,0 +1,69 @@
+package pagerduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// Validate checks that the API token is accepted and that the monitored schedule, escalation
+// policy, or team and the configured user exist, returning an error that names the first
+// setting that is wrong
+func (c *Client) Validate(ctx context.Context) error {
+	var err error
+	switch {
+	case c.scope.EscalationPolicyID != "":
+		_, err = withRetry(ctx, c, func() (*pagerduty.EscalationPolicy, error) {
+			return c.client.GetEscalationPolicyWithContext(ctx, c.scope.EscalationPolicyID, &pagerduty.GetEscalationPolicyOptions{})
+		})
+		err = describeLookupError(err, "escalation policy", "PD_ESCALATION_POLICY_ID", c.scope.EscalationPolicyID)
+	case c.scope.TeamID != "":
+		_, err = withRetry(ctx, c, func() (*pagerduty.Team, error) {
+			return c.client.GetTeamWithContext(ctx, c.scope.TeamID)
+		})
+		err = describeLookupError(err, "team", "PD_TEAM_ID", c.scope.TeamID)
+	default:
+		_, err = withRetry(ctx, c, func() (*pagerduty.Schedule, error) {
+			return c.client.GetScheduleWithContext(ctx, c.scope.ScheduleID, pagerduty.GetScheduleOptions{})
+		})
+		err = describeLookupError(err, "schedule", "PD_SCHEDULE_ID", c.scope.ScheduleID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = withRetry(ctx, c, func() (*pagerduty.User, error) {
+		return c.client.GetUserWithContext(ctx, c.userID, pagerduty.GetUserOptions{})
+	})
+	return describeLookupError(err, "user", "PD_USER_ID or PD_USERS", c.userID)
+}
+
+// describeLookupError turns a failed lookup of a configured object into an error that says
+// which setting to fix. Errors other than a rejected token or missing object are wrapped as is.
+func describeLookupError(err error, kind, setting, id string) error {
+	if err == nil {
+		return nil
+	}
+	statusCode := 0
+	var apiErr pagerduty.APIError
+	var apiErrPtr *pagerduty.APIError
+	if errors.As(err, &apiErr) {
+		statusCode = apiErr.StatusCode
+	} else if errors.As(err, &apiErrPtr) {
+		statusCode = apiErrPtr.StatusCode
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("PagerDuty rejected the API token; check PD_API_TOKEN (and PD_REGION for EU accounts)")
+	case http.StatusForbidden:
+		return fmt.Errorf("the API token is not allowed to read %s %s; check its permissions", kind, id)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s %s not found; check %s", kind, id, setting)
+	default:
+		return fmt.Errorf("failed to look up %s %s: %w", kind, id, err)
+	}
+}
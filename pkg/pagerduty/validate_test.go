@@ -0,0 +1,54 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateNamesTheWrongSetting(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schedules/PSCHED1":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"schedule": map[string]string{"id": "PSCHED1"}})
+		case "/users/PUSER1":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"user": map[string]string{"id": "PUSER1"}})
+		case "/schedules/PDENIED":
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"message": "Unauthorized", "code": 2006}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"message": "Not Found", "code": 2100}})
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		scope  Scope
+		userID string
+		want   string
+	}{
+		{Scope{ScheduleID: "PSCHED1"}, "PUSER1", ""},
+		{Scope{ScheduleID: "PTYPO"}, "PUSER1", "schedule PTYPO not found; check PD_SCHEDULE_ID"},
+		{Scope{ScheduleID: "PSCHED1"}, "PNOBODY", "user PNOBODY not found; check PD_USER_ID"},
+		{Scope{EscalationPolicyID: "PPOLICY"}, "PUSER1", "escalation policy PPOLICY not found; check PD_ESCALATION_POLICY_ID"},
+		{Scope{ScheduleID: "PDENIED"}, "PUSER1", "check PD_API_TOKEN"},
+	}
+	for _, tc := range cases {
+		err := newTestClient(server.URL, tc.scope, tc.userID).Validate(context.Background())
+		if tc.want == "" {
+			if err != nil {
+				t.Fatalf("%+v: expected no error, got %v", tc.scope, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: expected error containing %q, got %v", tc.scope, tc.want, err)
+		}
+	}
+}